@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// bannerRecords caps the records the startup banner names; the rest are
+// counted.
+const bannerRecords = 5
+
+// logStartup logs, once and on one line, what the run command is about to
+// do.
+func logStartup(cfg ddns.Config, mode string, interval time.Duration) {
+	slog.Info("Starting ddns-updater", startupAttrs(cfg, mode, interval)...)
+}
+
+// startupAttrs summarizes cfg, the updater's effective configuration, for
+// the startup banner. Tokens and webhook URLs are left out; notifiers are
+// only named.
+func startupAttrs(cfg ddns.Config, mode string, interval time.Duration) []any {
+	provider := "cloudflare"
+	if cfg.Provider != nil {
+		provider = cfg.Provider.Name()
+	}
+	attrs := []any{"mode", mode}
+	if mode == "loop" {
+		attrs = append(attrs, "interval", interval)
+	}
+	attrs = append(attrs, "provider", provider)
+	if cfg.FallbackProvider != nil {
+		attrs = append(attrs, "fallback_provider", cfg.FallbackProvider.Name())
+	}
+
+	records := make([]string, 0, min(len(cfg.Records), bannerRecords))
+	for _, r := range cfg.Records[:min(len(cfg.Records), bannerRecords)] {
+		records = append(records, describeRecord(r))
+	}
+	if more := len(cfg.Records) - bannerRecords; more > 0 {
+		records = append(records, fmt.Sprintf("%d more", more))
+	}
+	attrs = append(attrs, "records", strings.Join(records, ", "))
+	if cfg.Content != "" {
+		attrs = append(attrs, "content", cfg.Content)
+	}
+	if len(cfg.OverrideIPs) > 0 {
+		attrs = append(attrs, "override_ips", strings.Join(cfg.OverrideIPs, ","))
+	}
+
+	notifiers := make([]string, 0, len(cfg.Notifiers))
+	for _, n := range cfg.Notifiers {
+		notifiers = append(notifiers, n.Name())
+	}
+	if len(notifiers) == 0 {
+		notifiers = append(notifiers, "none")
+	}
+	return append(attrs, "notifiers", strings.Join(notifiers, ","))
+}
+
+// describeRecord formats a record for the banner, e.g.
+// "home.example.com A proxied ttl=auto".
+func describeRecord(r ddns.Record) string {
+	name := r.Name
+	if name == "" {
+		name = "(selected)"
+	}
+	proxied := "dns-only"
+	if r.Proxied {
+		proxied = "proxied"
+	}
+	ttl := fmt.Sprint(r.TTL)
+	if r.TTL == ddns.AutoTTL {
+		ttl = "auto"
+	}
+	return fmt.Sprintf("%s %s %s ttl=%s", name, r.Type, proxied, ttl)
+}
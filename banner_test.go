@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+func TestStartupBannerOmitsSecrets(t *testing.T) {
+	secrets := []string{
+		"cf-token-secret",
+		"org-token-secret",
+		"https://discord.com/api/webhooks/123/discord-secret",
+		"https://hooks.slack.com/services/T0/B0/slack-secret",
+		"https://example.com/hook?key=webhook-secret",
+		"status-token-secret",
+		"update-token-secret",
+	}
+	creds := writeFile(t, "credentials.json", `{"org": {"api_token": "org-token-secret", "account_id": "acc-org"}}`)
+	records := writeFile(t, "records.json", `{"records": [
+		{"name": "home.example.com", "proxied": true},
+		{"name": "vpn.example.org", "zone": "example.org", "credential": "org", "ttl": 300}
+	]}`)
+	setenv(t,
+		"ZONE_NAME", "example.com",
+		"API_TOKEN", secrets[0],
+		"CREDENTIALS_FILE", creds,
+		"RECORD_CONTENT", "198.51.100.1",
+		"DISCORD_WEBHOOK_URL", secrets[2],
+		"SLACK_WEBHOOK_URL", secrets[3],
+		"WEBHOOK_URL", secrets[4],
+		"STATUS_TOKEN", secrets[5],
+		"UPDATE_TOKEN", secrets[6],
+		"HEALTH_ADDR", "127.0.0.1:0",
+	)
+	cfg, err := getEnvVars(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Logger = slog.New(slog.DiscardHandler)
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logs := captureLogs(t)
+	logStartup(updater.Config(), "loop", 5*time.Minute)
+	banner := logs.String()
+
+	if strings.Count(banner, "\n") != 1 {
+		t.Errorf("banner is not one line:\n%s", banner)
+	}
+	for _, secret := range secrets {
+		if strings.Contains(banner, secret) {
+			t.Errorf("banner leaks %q:\n%s", secret, banner)
+		}
+	}
+	if strings.Contains(banner, "secret") {
+		t.Errorf("banner holds part of a secret:\n%s", banner)
+	}
+	for _, want := range []string{
+		"mode=loop", "interval=5m0s", "provider=cloudflare", "content=198.51.100.1",
+		`records="home.example.com A proxied ttl=auto, vpn.example.org A dns-only ttl=300"`,
+		"notifiers=discord,slack,webhook",
+	} {
+		if !strings.Contains(banner, want) {
+			t.Errorf("banner misses %q:\n%s", want, banner)
+		}
+	}
+}
+
+func TestStartupBannerCapsRecords(t *testing.T) {
+	cfg := ddns.Config{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		cfg.Records = append(cfg.Records, ddns.Record{Name: name + ".example.com", Type: "A", TTL: ddns.AutoTTL})
+	}
+	logs := captureLogs(t)
+	logStartup(cfg, "once", 0)
+	banner := logs.String()
+	if !strings.Contains(banner, "e.example.com A dns-only ttl=auto, 2 more") || strings.Contains(banner, "f.example.com") {
+		t.Errorf("banner does not cap the records at %d:\n%s", bannerRecords, banner)
+	}
+	if strings.Contains(banner, "interval=") || !strings.Contains(banner, "notifiers=none") {
+		t.Errorf("one-shot banner without notifiers:\n%s", banner)
+	}
+}
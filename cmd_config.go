@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+const redacted = "[redacted]"
+
+type effectiveConfig struct {
+	Provider         string            `json:"provider"`
+	FallbackProvider string            `json:"fallback_provider,omitempty"`
+	ZoneName         string            `json:"zone_name,omitempty"`
+	NamePrefix       string            `json:"record_name_prefix,omitempty"`
+	NameSuffix       string            `json:"record_name_suffix,omitempty"`
+	Stack            string            `json:"stack,omitempty"`
+	APIToken         string            `json:"api_token,omitempty"`
+	AccountID        string            `json:"account_id,omitempty"`
+	ZoneSelect       string            `json:"zone_select"`
+	Credentials      map[string]string `json:"credentials,omitempty"`
+	Content          string            `json:"content,omitempty"`
+	OverrideIPs      []string          `json:"override_ips,omitempty"`
+	IPProviders      []string          `json:"ip_providers"`
+	IPv4Providers    []string          `json:"ipv4_providers,omitempty"`
+	IPv6Providers    []string          `json:"ipv6_providers,omitempty"`
+	ShuffleProviders bool              `json:"ip_provider_shuffle"`
+	IPv6Select       string            `json:"ipv6_select"`
+	IPProviderRegex  string            `json:"ip_provider_regex,omitempty"`
+	IPCommand        string            `json:"ip_command,omitempty"`
+	IPCommandTimeout string            `json:"ip_command_timeout,omitempty"`
+	IPProviderPath   string            `json:"ip_provider_jsonpath,omitempty"`
+	IPProviderCType  string            `json:"ip_provider_content_type"`
+	CGNATCheck       bool              `json:"cgnat_check"`
+	EdgeCheck        bool              `json:"cf_edge_check"`
+	EdgeRangesURL    string            `json:"cf_edge_ranges_url,omitempty"`
+	ConfirmStable    string            `json:"confirm_stable,omitempty"`
+	AllowedIPRanges  []string          `json:"allowed_ip_cidrs,omitempty"`
+	IPHTTPTimeout    string            `json:"ip_http_timeout"`
+	CFHTTPTimeout    string            `json:"cf_http_timeout"`
+	RequestTimeout   string            `json:"request_timeout,omitempty"`
+	CFRateLimit      float64           `json:"cf_rate_limit,omitempty"`
+	Notifiers        []string          `json:"notifiers"`
+	NotifyLimit      int               `json:"notify_concurrency,omitempty"`
+	NotifyTimeout    string            `json:"notify_timeout"`
+	NotifyRepeat     string            `json:"notify_repeat_interval,omitempty"`
+	MaxRecords       int               `json:"max_records"`
+	ConfirmApex      bool              `json:"confirm_apex_proxy,omitempty"`
+	Retry            retry             `json:"retry"`
+	UpdateFields     []string          `json:"update_fields"`
+	UpdateWindow     string            `json:"update_window,omitempty"`
+	RefreshAfter     string            `json:"refresh_after,omitempty"`
+	CommentStamp     bool              `json:"comment_timestamp"`
+	OnLocked         string            `json:"on_locked"`
+	OnMissing        string            `json:"on_missing"`
+	OnPlaceholder    string            `json:"on_placeholder"`
+	TXTStrategy      string            `json:"txt_strategy"`
+	MultiRecord      string            `json:"multi_record_strategy"`
+	SelectBy         string            `json:"record_select_by"`
+	SaaS             bool              `json:"cf_saas"`
+	Propagation      *propagation      `json:"propagation_check,omitempty"`
+	Failover         *failover         `json:"failover,omitempty"`
+	Interval         string            `json:"interval,omitempty"`
+	Preflight        bool              `json:"preflight"`
+	RunTimeout       string            `json:"run_timeout,omitempty"`
+	WaitForNetwork   string            `json:"wait_for_network,omitempty"`
+	ClockSkewMax     string            `json:"clock_skew_max,omitempty"`
+	MetricsTextfile  string            `json:"metrics_textfile,omitempty"`
+	StatsdAddr       string            `json:"statsd_addr,omitempty"`
+	IPOutputFile     string            `json:"ip_output_file,omitempty"`
+	StatusFile       string            `json:"status_file,omitempty"`
+	HostsFile        string            `json:"hosts_file,omitempty"`
+	HistoryFile      string            `json:"history_file,omitempty"`
+	StateFile        string            `json:"state_file,omitempty"`
+	AuditLog         string            `json:"audit_log,omitempty"`
+	LockFile         string            `json:"lock_file,omitempty"`
+	HistoryMax       int               `json:"history_max,omitempty"`
+	HealthAddr       string            `json:"health_addr,omitempty"`
+	UpdateToken      string            `json:"update_token,omitempty"`
+	StatusToken      string            `json:"status_token,omitempty"`
+	TriggerDebounce  string            `json:"trigger_debounce,omitempty"`
+	Records          []effectiveRecord `json:"records"`
+}
+
+type retry struct {
+	Attempts  int    `json:"attempts"`
+	BaseDelay string `json:"base_delay"`
+	MaxDelay  string `json:"max_delay"`
+	Budget    int    `json:"budget,omitempty"`
+}
+
+type propagation struct {
+	Resolvers []string `json:"resolvers"`
+	Timeout   string   `json:"timeout"`
+}
+
+type failover struct {
+	HealthURL   string   `json:"health_url"`
+	FailoverIPs []string `json:"failover_ips"`
+}
+
+type effectiveRecord struct {
+	Name       string   `json:"name"`
+	Zone       string   `json:"zone"`
+	Credential string   `json:"credential,omitempty"`
+	Type       string   `json:"type"`
+	Proxied    bool     `json:"proxied"`
+	TTL        int      `json:"ttl"`
+	Priority   int      `json:"priority,omitempty"`
+	Weight     int      `json:"weight,omitempty"`
+	Port       int      `json:"port,omitempty"`
+	Comment    string   `json:"comment,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// configCommand prints the configuration the run command would use, after
+// merging flags, environment and config file and applying defaults.
+func configCommand(args []string) {
+	fs, opts := newFlagSet("config")
+	output := outputFlag(fs, "text", "json")
+	fs.Parse(args)
+	checkOutput(*output, "text", "json")
+
+	cfg, err := getEnvVars(opts.configFile)
+	if err != nil {
+		fatal(err)
+	}
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		fatal(err)
+	}
+	ec := newEffectiveConfig(updater.Config())
+	if cfg.Interval > 0 {
+		ec.Interval = cfg.Interval.String()
+	}
+	ec.Preflight = cfg.Preflight
+	if cfg.RefreshAfter > 0 {
+		ec.RefreshAfter = cfg.RefreshAfter.String()
+	}
+	if cfg.ConfirmStable > 0 {
+		ec.ConfirmStable = cfg.ConfirmStable.String()
+	}
+	if cfg.RunTimeout > 0 {
+		ec.RunTimeout = cfg.RunTimeout.String()
+	}
+	if cfg.WaitForNetwork > 0 {
+		ec.WaitForNetwork = cfg.WaitForNetwork.String()
+	}
+	if cfg.ClockSkewMax > 0 {
+		ec.ClockSkewMax = cfg.ClockSkewMax.String()
+	}
+	if cfg.RequestTimeout > 0 {
+		ec.RequestTimeout = cfg.RequestTimeout.String()
+	}
+	if cfg.NotifyRepeatInterval > 0 {
+		ec.NotifyRepeat = cfg.NotifyRepeatInterval.String()
+	}
+	ec.MetricsTextfile = cfg.MetricsTextfile
+	ec.StatsdAddr = cfg.StatsdAddr
+	ec.IPOutputFile = cfg.IPOutputFile
+	ec.StatusFile = cfg.StatusFile
+	ec.HostsFile = cfg.HostsFile
+	ec.StateFile = cfg.StateFile
+	if cfg.HistoryFile != "" {
+		ec.HistoryFile = cfg.HistoryFile
+		ec.HistoryMax = cfg.HistoryMax
+	}
+	ec.AuditLog = cfg.AuditLogFile
+	ec.LockFile = cfg.LockFile
+	ec.HealthAddr = cfg.HealthAddr
+	ec.UpdateToken = redact(cfg.UpdateToken)
+	ec.StatusToken = redact(cfg.StatusToken)
+	if cfg.UpdateToken != "" {
+		ec.TriggerDebounce = cfg.TriggerDebounce.String()
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(ec); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	printEffectiveConfig(ec)
+}
+
+func newEffectiveConfig(cfg ddns.Config) effectiveConfig {
+	ec := effectiveConfig{
+		Provider:         "cloudflare",
+		ZoneName:         cfg.ZoneName,
+		NamePrefix:       cfg.RecordNamePrefix,
+		NameSuffix:       cfg.RecordNameSuffix,
+		APIToken:         redact(cfg.APIToken),
+		AccountID:        cfg.AccountID,
+		ZoneSelect:       string(cfg.ZoneSelect),
+		Content:          cfg.Content,
+		OverrideIPs:      cfg.OverrideIPs,
+		IPProviders:      cfg.IPProviders,
+		IPv4Providers:    cfg.IPv4Providers,
+		IPv6Providers:    cfg.IPv6Providers,
+		ShuffleProviders: cfg.ShuffleProviders,
+		CGNATCheck:       cfg.CGNATCheck,
+		EdgeCheck:        cfg.EdgeCheck,
+		EdgeRangesURL:    cfg.EdgeRangesURL,
+		IPHTTPTimeout:    cfg.IPHTTPTimeout.String(),
+		CFHTTPTimeout:    cfg.CFHTTPTimeout.String(),
+		CFRateLimit:      cfg.CFRateLimit,
+		MaxRecords:       cfg.MaxRecords,
+		CommentStamp:     cfg.CommentTimestamp,
+		ConfirmApex:      cfg.ConfirmApexProxy,
+		NotifyLimit:      cfg.NotifyConcurrency,
+		NotifyTimeout:    cfg.NotifyTimeout.String(),
+		OnLocked:         string(cfg.OnLocked),
+		OnMissing:        string(cfg.OnMissing),
+		OnPlaceholder:    string(cfg.OnPlaceholder),
+		TXTStrategy:      string(cfg.TXTStrategy),
+		MultiRecord:      string(cfg.MultiRecordStrategy),
+		SelectBy:         string(cfg.SelectBy),
+		IPv6Select:       string(cfg.IPv6Select),
+		Stack:            string(cfg.Stack),
+		UpdateFields:     cfg.UpdateFields,
+		SaaS:             cfg.SaaS,
+		Retry: retry{
+			Attempts:  cfg.Retry.Attempts,
+			BaseDelay: cfg.Retry.BaseDelay.String(),
+			MaxDelay:  cfg.Retry.MaxDelay.String(),
+			Budget:    cfg.Retry.Budget,
+		},
+	}
+	if cfg.Provider != nil {
+		ec.Provider = cfg.Provider.Name()
+	}
+	if cfg.FallbackProvider != nil {
+		ec.FallbackProvider = cfg.FallbackProvider.Name()
+	}
+	if cfg.UpdateWindow != nil {
+		ec.UpdateWindow = cfg.UpdateWindow.String()
+	}
+	if cfg.Content == "" && len(ec.IPProviders) == 0 {
+		ec.IPProviders = []string{"ipify"}
+	}
+	if p := cfg.Propagation; p != nil {
+		ec.Propagation = &propagation{Resolvers: p.Resolvers, Timeout: p.Timeout.String()}
+	}
+	if f := cfg.Failover; f != nil {
+		ec.Failover = &failover{HealthURL: f.HealthURL, FailoverIPs: f.FailoverIPs}
+	}
+	if cfg.IPProviderRegex != nil {
+		ec.IPProviderRegex = cfg.IPProviderRegex.String()
+	}
+	ec.IPProviderPath = cfg.IPProviderJSONPath
+	ec.IPProviderCType = string(cfg.IPProviderContentType)
+	if cfg.IPCommand != "" {
+		ec.IPCommand = cfg.IPCommand
+		ec.IPCommandTimeout = cfg.IPCommandTimeout.String()
+	}
+	for _, n := range cfg.AllowedIPRanges {
+		ec.AllowedIPRanges = append(ec.AllowedIPRanges, n.String())
+	}
+	for name, cred := range cfg.Credentials {
+		if ec.Credentials == nil {
+			ec.Credentials = make(map[string]string)
+		}
+		ec.Credentials[name] = redact(cred.APIToken)
+		if cred.Provider != nil {
+			ec.Credentials[name] += " (" + cred.Provider.Name() + ")"
+		}
+	}
+	for _, n := range cfg.Notifiers {
+		ec.Notifiers = append(ec.Notifiers, n.Name())
+	}
+	for _, r := range cfg.Records {
+		ec.Records = append(ec.Records, effectiveRecord{
+			Name:       r.Name,
+			Zone:       r.Zone,
+			Credential: r.Credential,
+			Type:       r.Type,
+			Proxied:    r.Proxied,
+			TTL:        r.TTL,
+			Priority:   r.Priority,
+			Weight:     r.Weight,
+			Port:       r.Port,
+			Comment:    r.Comment,
+			Tags:       r.Tags,
+		})
+	}
+	return ec
+}
+
+func printEffectiveConfig(ec effectiveConfig) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Provider\t%s\n", ec.Provider)
+	if ec.FallbackProvider != "" {
+		fmt.Fprintf(w, "Fallback provider\t%s\n", ec.FallbackProvider)
+	}
+	fmt.Fprintf(w, "Zone name\t%s\n", ec.ZoneName)
+	if ec.NamePrefix != "" {
+		fmt.Fprintf(w, "Record name prefix\t%s\n", ec.NamePrefix)
+	}
+	if ec.NameSuffix != "" {
+		fmt.Fprintf(w, "Record name suffix\t%s\n", ec.NameSuffix)
+	}
+	if ec.Stack != "" {
+		fmt.Fprintf(w, "Stack\t%s\n", ec.Stack)
+	}
+	fmt.Fprintf(w, "API token\t%s\n", ec.APIToken)
+	if ec.AccountID != "" {
+		fmt.Fprintf(w, "Account ID\t%s\n", ec.AccountID)
+	}
+	fmt.Fprintf(w, "Zone selection\t%s\n", ec.ZoneSelect)
+	for _, name := range slices.Sorted(maps.Keys(ec.Credentials)) {
+		fmt.Fprintf(w, "Credential %s\t%s\n", name, ec.Credentials[name])
+	}
+	if ec.Content != "" {
+		fmt.Fprintf(w, "Content\t%s\n", ec.Content)
+	} else if len(ec.OverrideIPs) > 0 {
+		fmt.Fprintf(w, "Override IPs\t%s (detection skipped)\n", strings.Join(ec.OverrideIPs, ", "))
+	} else {
+		fmt.Fprintf(w, "IP providers\t%s\n", strings.Join(ec.IPProviders, ", "))
+		if len(ec.IPv4Providers) > 0 {
+			fmt.Fprintf(w, "IPv4 providers\t%s\n", strings.Join(ec.IPv4Providers, ", "))
+		}
+		if len(ec.IPv6Providers) > 0 {
+			fmt.Fprintf(w, "IPv6 providers\t%s\n", strings.Join(ec.IPv6Providers, ", "))
+		}
+		fmt.Fprintf(w, "Shuffle providers\t%t\n", ec.ShuffleProviders)
+		fmt.Fprintf(w, "IPv6 selection\t%s\n", ec.IPv6Select)
+		if ec.IPProviderRegex != "" {
+			fmt.Fprintf(w, "IP provider regex\t%s\n", ec.IPProviderRegex)
+		}
+		if ec.IPProviderPath != "" {
+			fmt.Fprintf(w, "IP provider JSON path\t%s\n", ec.IPProviderPath)
+		}
+		fmt.Fprintf(w, "IP provider content type\t%s\n", ec.IPProviderCType)
+		if ec.IPCommand != "" {
+			fmt.Fprintf(w, "IP command\t%s (timeout %s)\n", ec.IPCommand, ec.IPCommandTimeout)
+		}
+		fmt.Fprintf(w, "CGNAT check\t%t\n", ec.CGNATCheck)
+		if ec.EdgeCheck && ec.EdgeRangesURL != "" {
+			fmt.Fprintf(w, "Cloudflare edge check\ttrue (ranges from %s)\n", ec.EdgeRangesURL)
+		} else {
+			fmt.Fprintf(w, "Cloudflare edge check\t%t\n", ec.EdgeCheck)
+		}
+		if ec.ConfirmStable != "" {
+			fmt.Fprintf(w, "Confirm stable\t%s\n", ec.ConfirmStable)
+		}
+		if len(ec.AllowedIPRanges) > 0 {
+			fmt.Fprintf(w, "Allowed IP ranges\t%s\n", strings.Join(ec.AllowedIPRanges, ", "))
+		}
+	}
+	fmt.Fprintf(w, "IP HTTP timeout\t%s\n", ec.IPHTTPTimeout)
+	fmt.Fprintf(w, "Cloudflare HTTP timeout\t%s\n", ec.CFHTTPTimeout)
+	if ec.RequestTimeout != "" {
+		fmt.Fprintf(w, "Request timeout\t%s per attempt\n", ec.RequestTimeout)
+	}
+	if ec.CFRateLimit > 0 {
+		fmt.Fprintf(w, "Cloudflare rate limit\t%g req/s\n", ec.CFRateLimit)
+	}
+	fmt.Fprintf(w, "Notifiers\t%s\n", strings.Join(ec.Notifiers, ", "))
+	if len(ec.Notifiers) > 0 {
+		limit := "unlimited"
+		if ec.NotifyLimit > 0 {
+			limit = fmt.Sprint(ec.NotifyLimit)
+		}
+		fmt.Fprintf(w, "Notify concurrency\t%s, %s timeout each\n", limit, ec.NotifyTimeout)
+		repeat := "when cleared"
+		if ec.NotifyRepeat != "" {
+			repeat = "every " + ec.NotifyRepeat
+		}
+		fmt.Fprintf(w, "Repeat failures\t%s\n", repeat)
+	}
+	fmt.Fprintf(w, "Max records\t%d\n", ec.MaxRecords)
+	if ec.ConfirmApex {
+		fmt.Fprintln(w, "Apex proxy\tconfirmed")
+	}
+	fmt.Fprintf(w, "Retry\t%d attempts, %s base delay, %s max delay\n", ec.Retry.Attempts, ec.Retry.BaseDelay, ec.Retry.MaxDelay)
+	if ec.Retry.Budget > 0 {
+		fmt.Fprintf(w, "Retry budget\t%d retries per run\n", ec.Retry.Budget)
+	}
+	fmt.Fprintf(w, "Update fields\t%s\n", strings.Join(ec.UpdateFields, ", "))
+	if ec.UpdateWindow != "" {
+		fmt.Fprintf(w, "Update window\t%s\n", ec.UpdateWindow)
+	}
+	if ec.RefreshAfter != "" {
+		fmt.Fprintf(w, "Refresh after\t%s\n", ec.RefreshAfter)
+	}
+	fmt.Fprintf(w, "Comment timestamp\t%t\n", ec.CommentStamp)
+	fmt.Fprintf(w, "On locked record\t%s\n", ec.OnLocked)
+	fmt.Fprintf(w, "On missing record\t%s\n", ec.OnMissing)
+	fmt.Fprintf(w, "On placeholder content\t%s\n", ec.OnPlaceholder)
+	fmt.Fprintf(w, "TXT strategy\t%s\n", ec.TXTStrategy)
+	fmt.Fprintf(w, "Multi-record strategy\t%s\n", ec.MultiRecord)
+	fmt.Fprintf(w, "Select records by\t%s\n", ec.SelectBy)
+	if ec.SaaS {
+		fmt.Fprintf(w, "Cloudflare for SaaS\t%t\n", ec.SaaS)
+	}
+	if p := ec.Propagation; p != nil {
+		fmt.Fprintf(w, "Propagation check\t%s (timeout %s)\n", strings.Join(p.Resolvers, ", "), p.Timeout)
+	}
+	if f := ec.Failover; f != nil {
+		fmt.Fprintf(w, "Failover\t%s when %s is unhealthy\n", strings.Join(f.FailoverIPs, ", "), f.HealthURL)
+	}
+	if ec.Interval != "" {
+		fmt.Fprintf(w, "Interval\t%s\n", ec.Interval)
+	}
+	fmt.Fprintf(w, "Preflight\t%t\n", ec.Preflight)
+	if ec.RunTimeout != "" {
+		fmt.Fprintf(w, "Run timeout\t%s\n", ec.RunTimeout)
+	}
+	if ec.WaitForNetwork != "" {
+		fmt.Fprintf(w, "Wait for network\t%s\n", ec.WaitForNetwork)
+	}
+	if ec.ClockSkewMax != "" {
+		fmt.Fprintf(w, "Max clock skew\t%s\n", ec.ClockSkewMax)
+	}
+	if ec.MetricsTextfile != "" {
+		fmt.Fprintf(w, "Metrics textfile\t%s\n", ec.MetricsTextfile)
+	}
+	if ec.StatsdAddr != "" {
+		fmt.Fprintf(w, "Statsd address\t%s\n", ec.StatsdAddr)
+	}
+	if ec.IPOutputFile != "" {
+		fmt.Fprintf(w, "IP output file\t%s\n", ec.IPOutputFile)
+	}
+	if ec.StatusFile != "" {
+		fmt.Fprintf(w, "Status file\t%s\n", ec.StatusFile)
+	}
+	if ec.HostsFile != "" {
+		fmt.Fprintf(w, "Hosts file\t%s\n", ec.HostsFile)
+	}
+	if ec.StateFile != "" {
+		fmt.Fprintf(w, "State file\t%s\n", ec.StateFile)
+	}
+	if ec.HistoryFile != "" {
+		fmt.Fprintf(w, "History file\t%s (max %d entries)\n", ec.HistoryFile, ec.HistoryMax)
+	}
+	if ec.LockFile != "" {
+		fmt.Fprintf(w, "Lock file\t%s\n", ec.LockFile)
+	}
+	if ec.AuditLog != "" {
+		fmt.Fprintf(w, "Audit log\t%s\n", ec.AuditLog)
+	}
+	if ec.HealthAddr != "" {
+		fmt.Fprintf(w, "Health server\t%s\n", ec.HealthAddr)
+		fmt.Fprintf(w, "Update token\t%s\n", ec.UpdateToken)
+		fmt.Fprintf(w, "Status token\t%s\n", ec.StatusToken)
+		if ec.TriggerDebounce != "" {
+			fmt.Fprintf(w, "Trigger debounce\t%s\n", ec.TriggerDebounce)
+		}
+	}
+	w.Flush()
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tZONE\tCREDENTIAL\tTYPE\tPROXIED\tTTL\tPRIORITY\tTAGS\tCOMMENT")
+	for _, r := range ec.Records {
+		ttl := fmt.Sprint(r.TTL)
+		if r.TTL == ddns.AutoTTL {
+			ttl = "auto"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%d\t%s\t%s\n", r.Name, r.Zone, r.Credential, r.Type, r.Proxied, ttl, r.Priority, strings.Join(r.Tags, ","), r.Comment)
+	}
+	w.Flush()
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// diagnoseCommand prints the public address as seen by the IP providers, a
+// STUN server, the outbound interface and the records' DNS, followed by
+// hints about NAT and stale records. It makes no changes.
+func diagnoseCommand(args []string) {
+	fs, opts := newFlagSet("diagnose")
+	stun := fs.String("stun", getenv("STUN_SERVER"), "STUN server as host:port (overrides STUN_SERVER, default "+ddns.DefaultSTUNServer+")")
+	fs.Parse(args)
+
+	cfg, err := getEnvVars(opts.configFile)
+	if err != nil {
+		fatal(err)
+	}
+	cfg.Logger = quietLogger()
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		fatal(err)
+	}
+
+	d := updater.Diagnose(context.Background(), *stun)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tFAMILY\tDETAIL\tADDRESS")
+	for _, o := range d.Observations {
+		address := o.Address
+		if o.Err != nil {
+			address = "error: " + o.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.Source, o.Family, o.Detail, address)
+	}
+	w.Flush()
+
+	if len(d.Hints) > 0 {
+		fmt.Println()
+		for _, hint := range d.Hints {
+			fmt.Println("- " + hint)
+		}
+	}
+}
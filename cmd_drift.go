@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// exitDrift is the drift command's status when a record is out of sync,
+// kept apart from the status 1 used for errors.
+const exitDrift = 2
+
+// driftEntry is one record's line in the drift report.
+type driftEntry struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Status  string   `json:"status"`
+	Current string   `json:"current,omitempty"`
+	Desired string   `json:"desired,omitempty"`
+	Changes []string `json:"changes,omitempty"`
+	Reason  string   `json:"reason,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// driftCommand reads every configured record and reports which differ from
+// the desired address and settings. It runs the updater as a dry run, so
+// nothing is changed and no notification is sent, and exits exitDrift when
+// a record is out of sync or missing, or 1 when one could not be read.
+func driftCommand(args []string) {
+	fs, opts := newFlagSet("drift")
+	output := outputFlag(fs, "text", "json")
+	fs.Parse(args)
+	checkOutput(*output, "text", "json")
+
+	cfg, err := getEnvVars(opts.configFile)
+	if err != nil {
+		fatal(err)
+	}
+	cfg.Logger = quietLogger()
+	cfg.DryRun = true
+	// Report missing records as such whatever ON_MISSING says.
+	cfg.OnMissing = ddns.MissingCreate
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		fatal(err)
+	}
+
+	result, runErr := updater.Run(context.Background())
+	if runErr != nil && len(result.Records) == 0 {
+		fatal(runErr)
+	}
+
+	entries := make([]driftEntry, 0, len(result.Records))
+	for _, rr := range result.Records {
+		entries = append(entries, newDriftEntry(rr))
+	}
+
+	if *output == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(entries); err != nil {
+			fatal(err)
+		}
+	} else {
+		printDrift(entries)
+	}
+	// A record that could not be read is an error, not drift.
+	if runErr != nil {
+		os.Exit(1)
+	}
+	if hasDrift(entries) {
+		os.Exit(exitDrift)
+	}
+}
+
+// newDriftEntry maps a dry-run result onto a drift status: in sync,
+// drift, missing, skipped or failed. A record skipped with pending changes,
+// such as a locked one, still counts as drift.
+func newDriftEntry(rr ddns.RecordResult) driftEntry {
+	e := driftEntry{
+		Name:    rr.Name,
+		Type:    rr.Type,
+		Current: rr.Previous,
+		Desired: rr.Content,
+		Changes: rr.Changes,
+		Reason:  rr.Reason,
+	}
+	switch {
+	case rr.Action == ddns.ActionUnchanged:
+		e.Status = "in sync"
+	case rr.Action == ddns.ActionCreated:
+		e.Status = "missing"
+	case rr.Action == ddns.ActionUpdated:
+		e.Status = "drift"
+	case rr.Action == ddns.ActionSkipped && len(rr.Changes) > 0:
+		e.Status = "drift"
+	case rr.Action == ddns.ActionFailed:
+		e.Status = "failed"
+		if rr.Err != nil {
+			e.Error = rr.Err.Error()
+		}
+	default:
+		e.Status = string(rr.Action)
+	}
+	return e
+}
+
+// hasDrift reports whether a record of entries is out of sync or missing.
+func hasDrift(entries []driftEntry) bool {
+	return slices.ContainsFunc(entries, func(e driftEntry) bool {
+		return e.Status == "drift" || e.Status == "missing"
+	})
+}
+
+func printDrift(entries []driftEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tSTATUS\tCURRENT\tDESIRED\tDETAIL")
+	for _, e := range entries {
+		detail := strings.Join(e.Changes, ", ")
+		switch {
+		case e.Error != "":
+			detail = "error: " + e.Error
+		case e.Reason != "":
+			detail = e.Reason
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", e.Name, e.Type, e.Status, dash(e.Current), dash(e.Desired), detail)
+	}
+	w.Flush()
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
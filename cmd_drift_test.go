@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+func TestNewDriftEntry(t *testing.T) {
+	// A dry run over records in every state.
+	records := []ddns.RecordResult{
+		{Name: "home.example.com", Type: "A", Action: ddns.ActionUnchanged, Previous: "192.0.2.1", Content: "192.0.2.1"},
+		{Name: "vpn.example.com", Type: "A", Action: ddns.ActionUpdated, Previous: "192.0.2.1", Content: "198.51.100.1", Changes: []string{"content 192.0.2.1 -> 198.51.100.1"}},
+		{Name: "new.example.com", Type: "AAAA", Action: ddns.ActionCreated, Content: "2001:db8::1"},
+		{Name: "locked.example.com", Type: "A", Action: ddns.ActionSkipped, Previous: "192.0.2.1", Content: "198.51.100.1", Changes: []string{"content 192.0.2.1 -> 198.51.100.1"}, Reason: "record is locked"},
+		{Name: "elsewhere.example.com", Type: "A", Action: ddns.ActionSkipped, Reason: "record does not exist"},
+		{Name: "broken.example.com", Type: "A", Action: ddns.ActionFailed, Err: errors.New("permission denied")},
+	}
+	want := []driftEntry{
+		{Name: "home.example.com", Type: "A", Status: "in sync", Current: "192.0.2.1", Desired: "192.0.2.1"},
+		{Name: "vpn.example.com", Type: "A", Status: "drift", Current: "192.0.2.1", Desired: "198.51.100.1", Changes: []string{"content 192.0.2.1 -> 198.51.100.1"}},
+		{Name: "new.example.com", Type: "AAAA", Status: "missing", Desired: "2001:db8::1"},
+		{Name: "locked.example.com", Type: "A", Status: "drift", Current: "192.0.2.1", Desired: "198.51.100.1", Changes: []string{"content 192.0.2.1 -> 198.51.100.1"}, Reason: "record is locked"},
+		{Name: "elsewhere.example.com", Type: "A", Status: "skipped", Reason: "record does not exist"},
+		{Name: "broken.example.com", Type: "A", Status: "failed", Error: "permission denied"},
+	}
+
+	for i, rr := range records {
+		got := newDriftEntry(rr)
+		w := want[i]
+		if got.Name != w.Name || got.Type != w.Type || got.Status != w.Status || got.Current != w.Current ||
+			got.Desired != w.Desired || !slices.Equal(got.Changes, w.Changes) || got.Reason != w.Reason || got.Error != w.Error {
+			t.Errorf("%s: got %+v, want %+v", rr.Name, got, w)
+		}
+	}
+}
+
+func TestHasDrift(t *testing.T) {
+	inSync := driftEntry{Name: "home.example.com", Status: "in sync"}
+	for _, c := range []struct {
+		name    string
+		entries []driftEntry
+		want    bool
+	}{
+		{"all in sync", []driftEntry{inSync, inSync}, false},
+		{"skipped and failed only", []driftEntry{inSync, {Status: "skipped"}, {Status: "failed"}}, false},
+		{"one drifted", []driftEntry{inSync, {Status: "drift"}, inSync}, true},
+		{"one missing", []driftEntry{{Status: "missing"}, inSync}, true},
+	} {
+		if got := hasDrift(c.entries); got != c.want {
+			t.Errorf("%s: hasDrift = %t, want %t", c.name, got, c.want)
+		}
+	}
+}
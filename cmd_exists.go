@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// exitNotFound is the exists command's status when a record is missing,
+// kept apart from the status 1 used for errors.
+const exitNotFound = 2
+
+// existsCommand prints the current content of every configured record and
+// exits 0 when all of them exist, or exitNotFound when one does not.
+func existsCommand(args []string) {
+	fs, opts := newFlagSet("exists")
+	fs.Parse(args)
+
+	cfg, err := getEnvVars(opts.configFile)
+	if err != nil {
+		fatal(err)
+	}
+	cfg.Logger = quietLogger()
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		fatal(err)
+	}
+
+	states, err := updater.Lookup(context.Background())
+	if err != nil {
+		fatal(err)
+	}
+
+	missing := false
+	for _, s := range states {
+		if !s.Exists {
+			missing = true
+			fmt.Fprintf(os.Stderr, "%s %s not found\n", s.Name, s.Type)
+			continue
+		}
+		if len(states) == 1 {
+			fmt.Println(s.Content)
+		} else {
+			fmt.Printf("%s %s %s\n", s.Name, s.Type, s.Content)
+		}
+	}
+	if missing {
+		os.Exit(exitNotFound)
+	}
+}
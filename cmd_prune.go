@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// pruneCommand deletes zone records matching -match that are no longer
+// configured. Without -confirm it only lists what would be deleted.
+func pruneCommand(args []string) {
+	fs, opts := newFlagSet("prune")
+	match := fs.String("match", getenv("PRUNE_MATCH"), "glob selecting the records to prune, e.g. *.dyn.example.com (overrides PRUNE_MATCH)")
+	var confirm bool
+	if err := boolEnv("PRUNE_CONFIRM", &confirm); err != nil {
+		fatal(err)
+	}
+	fs.BoolVar(&confirm, "confirm", confirm, "delete the records instead of listing them (overrides PRUNE_CONFIRM)")
+	fs.Parse(args)
+
+	if *match == "" {
+		fatal(fmt.Errorf("prune requires -match or %s", envName("PRUNE_MATCH")))
+	}
+
+	cfg, err := getEnvVars(opts.configFile)
+	if err != nil {
+		fatal(err)
+	}
+	acquireLock(cfg)
+	openAuditLog(cfg)
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		fatal(err)
+	}
+
+	pruned, err := updater.Prune(context.Background(), *match, confirm)
+	printPruned(pruned, confirm)
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func printPruned(pruned []ddns.PrunedRecord, confirm bool) {
+	if len(pruned) == 0 {
+		fmt.Println("No records to prune")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTYPE\tCONTENT\tSTATUS")
+	for _, pr := range pruned {
+		status := "would delete"
+		switch {
+		case pr.Err != nil:
+			status = "failed"
+		case pr.Deleted:
+			status = "deleted"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", pr.Name, pr.Type, pr.Content, status)
+	}
+	w.Flush()
+
+	if !confirm {
+		fmt.Println("\nDry run: pass -confirm or set PRUNE_CONFIRM=true to delete these records")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// setCommand writes the address given with -ip to every configured record,
+// skipping public IP detection, for manual failover.
+func setCommand(args []string) {
+	fs, opts := newFlagSet("set")
+	ip := fs.String("ip", "", "address to write to the records instead of the detected one")
+	fs.Parse(args)
+
+	if *ip == "" {
+		fatal(fmt.Errorf("set requires -ip"))
+	}
+	addr := net.ParseIP(*ip)
+	if addr == nil {
+		fatal(fmt.Errorf("invalid -ip value %q: not an IP address", *ip))
+	}
+
+	cfg, err := getEnvVars(opts.configFile)
+	if err != nil {
+		fatal(err)
+	}
+	for _, record := range cfg.Records {
+		if err := checkFamily(record, addr); err != nil {
+			fatal(err)
+		}
+	}
+	cfg.Content = addr.String()
+	cfg.IPProviders = nil
+	cfg.IPv4Providers = nil
+	cfg.IPv6Providers = nil
+	acquireLock(cfg)
+	openAuditLog(cfg)
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		fatal(err)
+	}
+	d := &daemon{updater: updater, cfg: cfg}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if _, err := d.cycle(ctx); err != nil {
+		fatal(err)
+	}
+}
+
+// checkFamily reports whether addr can be written to record, which must be
+// an A record for IPv4 addresses and an AAAA record for IPv6 ones.
+func checkFamily(record ddns.Record, addr net.IP) error {
+	recordType := strings.ToUpper(record.Type)
+	if recordType == "" {
+		recordType = "A"
+	}
+	switch {
+	case recordType == "A" && addr.To4() == nil:
+		return fmt.Errorf("record %s is of type A but %s is not an IPv4 address", record.Name, addr)
+	case recordType == "AAAA" && addr.To4() != nil:
+		return fmt.Errorf("record %s is of type AAAA but %s is not an IPv6 address", record.Name, addr)
+	case recordType != "A" && recordType != "AAAA":
+		return fmt.Errorf("record %s is of type %s: set only supports A and AAAA records", record.Name, recordType)
+	}
+	return nil
+}
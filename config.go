@@ -0,0 +1,642 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+	"github.com/casantosmu/ddns-updater/internal/toml"
+)
+
+type fileConfig struct {
+	Credentials map[string]fileCredential `json:"credentials"`
+	Records     []fileRecord              `json:"records"`
+}
+
+type fileCredential struct {
+	// Provider defaults to cloudflare.
+	Provider  string `json:"provider"`
+	APIToken  string `json:"api_token"`
+	AccountID string `json:"account_id"`
+}
+
+type fileRecord struct {
+	Name       string   `json:"name"`
+	Zone       string   `json:"zone"`
+	Credential string   `json:"credential"`
+	Proxied    *bool    `json:"proxied"`
+	TTL        *int     `json:"ttl"`
+	Comment    string   `json:"comment"`
+	Tags       []string `json:"tags"`
+}
+
+const (
+	defaultMXPriority  = 10
+	defaultSRVPriority = 10
+
+	defaultTriggerDebounce = 5 * time.Second
+)
+
+// cliConfig is the updater configuration plus settings that only concern
+// the command.
+type cliConfig struct {
+	ddns.Config
+
+	// Quiet hides the updater's routine logs; changes are logged by the
+	// command instead.
+	Quiet bool
+	// Preflight checks connectivity to the APIs before the first cycle.
+	Preflight bool
+	// RunTimeout bounds each cycle, retries included.
+	RunTimeout time.Duration
+	// WaitForNetwork, when set, waits up to this long for the DNS API to be
+	// reachable before the first cycle.
+	WaitForNetwork time.Duration
+	// ClockSkewMax, when set, compares the local clock with the DNS API's
+	// at startup and warns when they differ by more than this.
+	ClockSkewMax time.Duration
+
+	// Interval, when set, keeps the run command updating on this period
+	// instead of exiting after one cycle.
+	Interval        time.Duration
+	MetricsTextfile string
+	// StatsdAddr, when set, is a host:port receiving run metrics over UDP
+	// after every cycle.
+	StatsdAddr string
+	// IPOutputFile, when set, receives the current address after every
+	// successful cycle, for scripts that need it without asking a provider.
+	IPOutputFile string
+	// HostsFile, when set, is a hosts-style file whose entries for the A
+	// and AAAA records are kept pointing at their addresses.
+	HostsFile string
+	// StatusFile, when set, is replaced after every cycle with the time,
+	// action, address and error of that cycle.
+	StatusFile string
+
+	// HistoryFile, when set, records every content change as a JSON line,
+	// keeping the newest HistoryMax entries.
+	HistoryFile string
+	HistoryMax  int
+	// LockFile, when set, is locked for the lifetime of the commands that
+	// change records, so overlapping cron runs cannot race.
+	LockFile string
+	// AuditLogFile, when set, is opened for appending by the commands that
+	// change records and receives their audit entries.
+	AuditLogFile string
+
+	// HealthAddr is where the health server listens while looping.
+	// UpdateToken enables its POST /update trigger. StatusToken, when set,
+	// is required by its status endpoint and page.
+	HealthAddr  string
+	UpdateToken string
+	StatusToken string
+	// TriggerDebounce coalesces POST /update requests arriving within this
+	// window of the last triggered cycle.
+	TriggerDebounce time.Duration
+}
+
+// getEnvVars builds the configuration from the environment. Records come
+// from configFile when it is set, otherwise from RECORD_NAME.
+func getEnvVars(configFile string) (*cliConfig, error) {
+	cfg := &cliConfig{
+		Config: ddns.Config{
+			// Cloudflare stores names in lowercase.
+			ZoneName:  strings.ToLower(getenv("ZONE_NAME")),
+			APIToken:  getenv("API_TOKEN"),
+			AccountID: getenv("ACCOUNT_ID"),
+			Content:   getenv("RECORD_CONTENT"),
+		},
+		MetricsTextfile: getenv("METRICS_TEXTFILE"),
+		StatsdAddr:      getenv("STATSD_ADDR"),
+		IPOutputFile:    getenv("IP_OUTPUT_FILE"),
+		StatusFile:      getenv("STATUS_FILE"),
+		HostsFile:       getenv("HOSTS_FILE"),
+		HistoryFile:     getenv("HISTORY_FILE"),
+		HistoryMax:      defaultHistoryMax,
+		AuditLogFile:    getenv("AUDIT_LOG"),
+		LockFile:        getenv("LOCK_FILE"),
+		HealthAddr:      getenv("HEALTH_ADDR"),
+		UpdateToken:     getenv("UPDATE_TOKEN"),
+		StatusToken:     getenv("STATUS_TOKEN"),
+		TriggerDebounce: defaultTriggerDebounce,
+	}
+	defaults := ddns.Record{
+		Type: strings.ToUpper(getenv("RECORD_TYPE")),
+		TTL:  ddns.AutoTTL,
+	}
+	recordName := strings.ToLower(getenv("RECORD_NAME"))
+	cfg.SelectBy = ddns.SelectBy(strings.ToLower(getenv("RECORD_SELECT_BY")))
+	// Records selected by comment or tag do not need a name.
+	byName := cfg.SelectBy == "" || cfg.SelectBy == ddns.SelectByName || cfg.SelectBy == ddns.SelectByGlob
+
+	provider, err := providerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Provider = provider
+	if cfg.FallbackProvider, err = fallbackProviderFromEnv(); err != nil {
+		return nil, err
+	}
+
+	// A config file can name a zone and credential per record, so the
+	// global ones are only required without it.
+	var missingVars []string
+	if configFile == "" {
+		if cfg.ZoneName == "" {
+			missingVars = append(missingVars, envName("ZONE_NAME"))
+		}
+		if recordName == "" && byName {
+			missingVars = append(missingVars, envName("RECORD_NAME"))
+		}
+		if cfg.APIToken == "" && provider == nil {
+			missingVars = append(missingVars, envName("API_TOKEN"))
+		}
+	}
+
+	if len(missingVars) > 0 {
+		return nil, fmt.Errorf("missing environment variables: %s", strings.Join(missingVars, ", "))
+	}
+
+	for _, name := range []string{"IP_PROVIDERS", "IPV4_PROVIDERS", "IPV6_PROVIDERS", "IP_COMMAND", "OVERRIDE_IP"} {
+		if cfg.Content != "" && getenv(name) != "" {
+			return nil, fmt.Errorf("%s and %s are mutually exclusive", envName("RECORD_CONTENT"), envName(name))
+		}
+	}
+	switch defaults.Type {
+	case "MX":
+		defaults.Priority = defaultMXPriority
+		if err := intEnv("MX_PRIORITY", 0, 65535, &defaults.Priority); err != nil {
+			return nil, err
+		}
+	case "SRV":
+		defaults.Priority = defaultSRVPriority
+		if err := intEnv("SRV_PRIORITY", 0, 65535, &defaults.Priority); err != nil {
+			return nil, err
+		}
+		if err := intEnv("SRV_WEIGHT", 0, 65535, &defaults.Weight); err != nil {
+			return nil, err
+		}
+		if getenv("SRV_PORT") == "" {
+			return nil, fmt.Errorf("%s is required for %s SRV", envName("SRV_PORT"), envName("RECORD_TYPE"))
+		}
+		if err := intEnv("SRV_PORT", 1, 65535, &defaults.Port); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := boolEnv("PROXIED", &defaults.Proxied); err != nil {
+		return nil, err
+	}
+	if err := boolEnv("CGNAT_CHECK", &cfg.CGNATCheck); err != nil {
+		return nil, err
+	}
+	if err := boolEnv("CONFIRM_APEX_PROXY", &cfg.ConfirmApexProxy); err != nil {
+		return nil, err
+	}
+	if err := boolEnv("CF_EDGE_CHECK", &cfg.EdgeCheck); err != nil {
+		return nil, err
+	}
+	cfg.EdgeRangesURL = getenv("CF_EDGE_RANGES_URL")
+	if err := intEnv("TTL", ddns.AutoTTL, 86400, &defaults.TTL); err != nil {
+		return nil, err
+	}
+	defaults.Comment = getenv("RECORD_COMMENT")
+	if v := getenv("RECORD_TAGS"); v != "" {
+		defaults.Tags = splitList(v)
+	}
+	if v := getenv("UPDATE_FIELDS"); v != "" {
+		cfg.UpdateFields = splitList(strings.ToLower(v))
+	}
+	for _, env := range []struct {
+		name string
+		dst  *[]string
+	}{
+		{"IP_PROVIDERS", &cfg.IPProviders},
+		{"IPV4_PROVIDERS", &cfg.IPv4Providers},
+		{"IPV6_PROVIDERS", &cfg.IPv6Providers},
+	} {
+		if v := getenv(env.name); v != "" {
+			*env.dst = dedupe(splitList(v))
+			if len(*env.dst) == 0 {
+				return nil, fmt.Errorf("%s does not contain any providers", envName(env.name))
+			}
+		}
+	}
+	if err := boolEnv("DEBUG_DUMP", &cfg.DebugDump); err != nil {
+		return nil, err
+	}
+	if err := boolEnv("PREFLIGHT", &cfg.Preflight); err != nil {
+		return nil, err
+	}
+	if err := boolEnv("CF_SAAS", &cfg.SaaS); err != nil {
+		return nil, err
+	}
+	if v := getenv("UPDATE_WINDOW"); v != "" {
+		window, err := ddns.ParseTimeWindow(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", envName("UPDATE_WINDOW"), err)
+		}
+		cfg.UpdateWindow = window
+	}
+	if err := durationEnv("REFRESH_AFTER", &cfg.RefreshAfter); err != nil {
+		return nil, err
+	}
+	if err := boolEnv("COMMENT_TIMESTAMP", &cfg.CommentTimestamp); err != nil {
+		return nil, err
+	}
+	if err := nonNegativeDurationEnv("CONFIRM_STABLE", &cfg.ConfirmStable); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("RUN_TIMEOUT", &cfg.RunTimeout); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("WAIT_FOR_NETWORK", &cfg.WaitForNetwork); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("CLOCK_SKEW_MAX", &cfg.ClockSkewMax); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("INTERVAL", &cfg.Interval); err != nil {
+		return nil, err
+	}
+	if err := intEnv("HISTORY_MAX", 1, 1000000, &cfg.HistoryMax); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("TRIGGER_DEBOUNCE", &cfg.TriggerDebounce); err != nil {
+		return nil, err
+	}
+	if cfg.UpdateToken != "" && cfg.HealthAddr == "" {
+		return nil, fmt.Errorf("%s requires %s", envName("UPDATE_TOKEN"), envName("HEALTH_ADDR"))
+	}
+	if cfg.StatusToken != "" && cfg.HealthAddr == "" {
+		return nil, fmt.Errorf("%s requires %s", envName("STATUS_TOKEN"), envName("HEALTH_ADDR"))
+	}
+	cfg.OnLocked = ddns.LockedPolicy(strings.ToLower(getenv("ON_LOCKED")))
+	cfg.OnMissing = ddns.MissingPolicy(strings.ToLower(getenv("ON_MISSING")))
+	cfg.OnPlaceholder = ddns.PlaceholderPolicy(strings.ToLower(getenv("ON_PLACEHOLDER")))
+	cfg.TXTStrategy = ddns.TXTStrategy(strings.ToLower(getenv("TXT_STRATEGY")))
+	cfg.ZoneSelect = ddns.ZoneSelect(strings.ToLower(getenv("ZONE_SELECT")))
+	if cfg.ZoneSelect == ddns.ZoneSelectAccount && cfg.APIToken != "" && cfg.AccountID == "" {
+		return nil, fmt.Errorf("%s=%s requires %s", envName("ZONE_SELECT"), ddns.ZoneSelectAccount, envName("ACCOUNT_ID"))
+	}
+	cfg.MultiRecordStrategy = ddns.MultiRecordStrategy(strings.ToLower(getenv("MULTI_RECORD_STRATEGY")))
+	cfg.IPv6Select = ddns.IPv6Select(strings.ToLower(getenv("IPV6_SELECT")))
+	cfg.Stack = ddns.Stack(strings.ToLower(getenv("STACK")))
+	cfg.RecordNamePrefix = strings.ToLower(getenv("RECORD_NAME_PREFIX"))
+	cfg.RecordNameSuffix = strings.ToLower(getenv("RECORD_NAME_SUFFIX"))
+	cfg.StateFile = getenv("STATE_FILE")
+	cfg.IPCommand = getenv("IP_COMMAND")
+	if err := intEnv("RETRY_ATTEMPTS", 1, 100, &cfg.Retry.Attempts); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("RETRY_BASE_DELAY", &cfg.Retry.BaseDelay); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("RETRY_MAX_DELAY", &cfg.Retry.MaxDelay); err != nil {
+		return nil, err
+	}
+	if err := intEnv("RETRY_BUDGET", 0, math.MaxInt32, &cfg.Retry.Budget); err != nil {
+		return nil, err
+	}
+	if err := intEnv("MAX_RECORDS", 1, math.MaxInt, &cfg.MaxRecords); err != nil {
+		return nil, err
+	}
+	for _, cidr := range splitList(getenv("ALLOWED_IP_CIDRS")) {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", envName("ALLOWED_IP_CIDRS"), cidr, err)
+		}
+		cfg.AllowedIPRanges = append(cfg.AllowedIPRanges, network)
+	}
+	if err := boolEnv("IP_PROVIDER_SHUFFLE", &cfg.ShuffleProviders); err != nil {
+		return nil, err
+	}
+	if v := getenv("IP_PROVIDER_REGEX"); v != "" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value: %w", envName("IP_PROVIDER_REGEX"), err)
+		}
+		cfg.IPProviderRegex = re
+	}
+	cfg.IPProviderJSONPath = getenv("IP_PROVIDER_JSONPATH")
+	cfg.IPProviderContentType = ddns.ProviderContentType(strings.ToLower(getenv("IP_PROVIDER_CONTENT_TYPE")))
+	if err := durationEnv("IP_HTTP_TIMEOUT", &cfg.IPHTTPTimeout); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("CF_HTTP_TIMEOUT", &cfg.CFHTTPTimeout); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("REQUEST_TIMEOUT", &cfg.RequestTimeout); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("IP_COMMAND_TIMEOUT", &cfg.IPCommandTimeout); err != nil {
+		return nil, err
+	}
+	if v := getenv("CF_RATE_LIMIT"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || !(rate > 0) || math.IsInf(rate, 0) {
+			return nil, fmt.Errorf("invalid %s value %q: must be a positive number of requests per second", envName("CF_RATE_LIMIT"), v)
+		}
+		cfg.CFRateLimit = rate
+	}
+
+	var checkPropagation bool
+	if err := boolEnv("CHECK_PROPAGATION", &checkPropagation); err != nil {
+		return nil, err
+	}
+	if checkPropagation {
+		cfg.Propagation = &ddns.PropagationCheck{}
+		if v := getenv("PROPAGATION_RESOLVERS"); v != "" {
+			cfg.Propagation.Resolvers = splitList(v)
+		}
+		if err := durationEnv("PROPAGATION_TIMEOUT", &cfg.Propagation.Timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	healthURL, failoverIP := getenv("PRIMARY_HEALTH_URL"), getenv("FAILOVER_IP")
+	if (healthURL == "") != (failoverIP == "") {
+		return nil, fmt.Errorf("%s and %s must be set together", envName("PRIMARY_HEALTH_URL"), envName("FAILOVER_IP"))
+	}
+	if healthURL != "" {
+		cfg.Failover = &ddns.FailoverCheck{HealthURL: healthURL, FailoverIPs: splitList(failoverIP)}
+	}
+	cfg.OverrideIPs = splitList(getenv("OVERRIDE_IP"))
+
+	if cfg.StatsdAddr != "" {
+		if _, _, err := net.SplitHostPort(cfg.StatsdAddr); err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", envName("STATSD_ADDR"), cfg.StatsdAddr, err)
+		}
+	}
+
+	notifiers, err := notifiersFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Notifiers = notifiers
+	if err := intEnv("NOTIFY_CONCURRENCY", 0, 100, &cfg.NotifyConcurrency); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("NOTIFY_TIMEOUT", &cfg.NotifyTimeout); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("NOTIFY_REPEAT_INTERVAL", &cfg.NotifyRepeatInterval); err != nil {
+		return nil, err
+	}
+
+	if path := getenv("CREDENTIALS_FILE"); path != "" {
+		if err := loadCredentialsFile(path, &cfg.Config); err != nil {
+			return nil, err
+		}
+	}
+
+	if configFile != "" {
+		format := strings.ToLower(getenv("CONFIG_FORMAT"))
+		if format != "" && format != "json" && format != "toml" {
+			return nil, fmt.Errorf("invalid %s value %q: must be json or toml", envName("CONFIG_FORMAT"), format)
+		}
+		if err := loadConfigFile(configFile, format, &cfg.Config, defaults); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+
+	for _, name := range splitList(recordName) {
+		record := defaults
+		record.Name = name
+		cfg.Records = append(cfg.Records, record)
+	}
+	if len(cfg.Records) == 0 && !byName {
+		cfg.Records = append(cfg.Records, defaults)
+	}
+	if len(cfg.Records) == 0 {
+		return nil, fmt.Errorf("%s does not contain any record names", envName("RECORD_NAME"))
+	}
+
+	return cfg, nil
+}
+
+// loadConfigFile reads credentials and records from a JSON or TOML config
+// file into cfg; see decodeFile for format. Entries that omit proxied or
+// ttl inherit the global PROXIED and TTL values, and entries without a zone
+// or credential use ZONE_NAME and API_TOKEN.
+func loadConfigFile(path, format string, cfg *ddns.Config, defaults ddns.Record) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var fc fileConfig
+	if err := decodeFile(path, format, data, &fc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if len(fc.Records) == 0 {
+		return fmt.Errorf("config file %s does not define any records", path)
+	}
+
+	if err := addCredentials(cfg, path, fc.Credentials); err != nil {
+		return err
+	}
+
+	records := make([]ddns.Record, 0, len(fc.Records))
+	for i, fr := range fc.Records {
+		if fr.Name == "" && (cfg.SelectBy == "" || cfg.SelectBy == ddns.SelectByName || cfg.SelectBy == ddns.SelectByGlob) {
+			return fmt.Errorf("config file %s: record %d is missing a name", path, i)
+		}
+
+		record := defaults
+		record.Name = strings.ToLower(fr.Name)
+		record.Zone = strings.ToLower(fr.Zone)
+		record.Credential = fr.Credential
+		if fr.Proxied != nil {
+			record.Proxied = *fr.Proxied
+		}
+		if fr.TTL != nil {
+			record.TTL = *fr.TTL
+		}
+		if fr.Comment != "" {
+			record.Comment = fr.Comment
+		}
+		if fr.Tags != nil {
+			record.Tags = fr.Tags
+		}
+		records = append(records, record)
+	}
+	cfg.Records = records
+
+	return nil
+}
+
+// loadCredentialsFile reads named credentials from a JSON file mapping each
+// name to a provider and its token, so records of one config file can span
+// several accounts and DNS hosts without tokens in the environment.
+func loadCredentialsFile(path string, cfg *ddns.Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var creds map[string]fileCredential
+	if err := decodeFile(path, "", data, &creds); err != nil {
+		return fmt.Errorf("failed to parse credentials file %s: %w", path, err)
+	}
+	return addCredentials(cfg, path, creds)
+}
+
+// decodeFile unmarshals the contents of the file at path into v. TOML is
+// used when format is "toml", or when it is empty and path ends in .toml;
+// otherwise JSON. TOML goes through JSON so both formats share the json
+// struct tags.
+func decodeFile(path, format string, data []byte, v any) error {
+	if format == "" && strings.EqualFold(filepath.Ext(path), ".toml") {
+		format = "toml"
+	}
+	if format != "toml" {
+		return json.Unmarshal(data, v)
+	}
+	m, err := toml.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	data, err = json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// addCredentials adds the credentials read from the file at path to cfg,
+// refusing names another file already defined.
+func addCredentials(cfg *ddns.Config, path string, creds map[string]fileCredential) error {
+	for name, c := range creds {
+		if _, ok := cfg.Credentials[name]; ok {
+			return fmt.Errorf("%s: credential %s is already defined", path, name)
+		}
+		if c.APIToken == "" {
+			return fmt.Errorf("%s: credential %s has no API token", path, name)
+		}
+		provider, err := newProvider(c.Provider, c.APIToken)
+		if err != nil {
+			return fmt.Errorf("%s: credential %s: %w", path, name, err)
+		}
+		if cfg.Credentials == nil {
+			cfg.Credentials = make(map[string]ddns.Credential)
+		}
+		cfg.Credentials[name] = ddns.Credential{APIToken: c.APIToken, AccountID: c.AccountID, Provider: provider}
+	}
+	return nil
+}
+
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envPrefix, when set from ENV_PREFIX, makes every variable NAME first be
+// looked up as PREFIX_NAME, so several instances can share one environment.
+// The unprefixed variable is the fallback.
+var envPrefix string
+
+// envName returns the variable that supplies name: the prefixed one when it
+// is set or when neither is, otherwise name itself.
+func envName(name string) string {
+	if envPrefix == "" {
+		return name
+	}
+	prefixed := envPrefix + "_" + name
+	if _, ok := os.LookupEnv(prefixed); ok {
+		return prefixed
+	}
+	if _, ok := os.LookupEnv(name); ok {
+		return name
+	}
+	return prefixed
+}
+
+func getenv(name string) string {
+	return os.Getenv(envName(name))
+}
+
+func durationEnv(name string, dst *time.Duration) error {
+	name = envName(name)
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid %s value %q: must be a positive duration such as 10s", name, v)
+	}
+	*dst = d
+	return nil
+}
+
+// nonNegativeDurationEnv is durationEnv for settings that 0 disables.
+func nonNegativeDurationEnv(name string, dst *time.Duration) error {
+	name = envName(name)
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return fmt.Errorf("invalid %s value %q: must be a duration such as 10s, or 0 to disable", name, v)
+	}
+	*dst = d
+	return nil
+}
+
+func intEnv(name string, min, max int, dst *int) error {
+	name = envName(name)
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min || n > max {
+		return fmt.Errorf("invalid %s value %q: must be between %d and %d", name, v, min, max)
+	}
+	*dst = n
+	return nil
+}
+
+func boolEnv(name string, dst *bool) error {
+	name = envName(name)
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q: %w", name, v, err)
+	}
+	*dst = b
+	return nil
+}
+
+func dedupe(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	var unique []string
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			unique = append(unique, item)
+		}
+	}
+	return unique
+}
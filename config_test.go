@@ -0,0 +1,308 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// setenv sets each name=value pair of kv for the duration of the test.
+func setenv(t *testing.T, kv ...string) {
+	t.Helper()
+	for i := 0; i+1 < len(kv); i += 2 {
+		t.Setenv(kv[i], kv[i+1])
+	}
+}
+
+// writeFile writes data to name in a temporary directory and returns its
+// path.
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFilePerRecordProxiedAndTTL(t *testing.T) {
+	setenv(t, "ZONE_NAME", "example.com", "API_TOKEN", "token", "PROXIED", "true", "TTL", "120")
+	path := writeFile(t, "records.json", `{"records": [
+		{"name": "home.example.com"},
+		{"name": "vpn.example.com", "proxied": false, "ttl": 300},
+		{"name": "mail.example.com", "proxied": false},
+		{"name": "www.example.com", "ttl": 600}
+	]}`)
+
+	cfg, err := getEnvVars(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ddns.Record{
+		{Name: "home.example.com", Proxied: true, TTL: 120},
+		{Name: "vpn.example.com", Proxied: false, TTL: 300},
+		{Name: "mail.example.com", Proxied: false, TTL: 120},
+		{Name: "www.example.com", Proxied: true, TTL: 600},
+	}
+	if len(cfg.Records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(cfg.Records), len(want))
+	}
+	for i, w := range want {
+		got := cfg.Records[i]
+		if got.Name != w.Name || got.Proxied != w.Proxied || got.TTL != w.TTL {
+			t.Errorf("record %d: got %s proxied=%t ttl=%d, want %s proxied=%t ttl=%d", i, got.Name, got.Proxied, got.TTL, w.Name, w.Proxied, w.TTL)
+		}
+	}
+
+	// In one updater, proxied records get automatic TTL and the others
+	// keep their own.
+	cfg.Logger = slog.New(slog.DiscardHandler)
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantTTL := []int{ddns.AutoTTL, 300, 120, ddns.AutoTTL}
+	for i, record := range updater.Config().Records {
+		if record.TTL != wantTTL[i] || record.Proxied != want[i].Proxied {
+			t.Errorf("%s: effective proxied=%t ttl=%d, want proxied=%t ttl=%d", record.Name, record.Proxied, record.TTL, want[i].Proxied, wantTTL[i])
+		}
+	}
+}
+
+func TestMixedCaseNamesAreLowercased(t *testing.T) {
+	setenv(t, "ZONE_NAME", "Example.COM", "API_TOKEN", "token", "RECORD_NAME", "Home.Example.COM")
+	cfg, err := getEnvVars("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ZoneName != "example.com" {
+		t.Errorf("zone = %q, want example.com", cfg.ZoneName)
+	}
+	if len(cfg.Records) != 1 || cfg.Records[0].Name != "home.example.com" {
+		t.Errorf("records = %+v, want home.example.com", cfg.Records)
+	}
+
+	path := writeFile(t, "records.json", `{"records": [{"name": "VPN.Example.ORG", "zone": "Example.ORG"}]}`)
+	cfg, err = getEnvVars(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Records) != 1 || cfg.Records[0].Name != "vpn.example.org" || cfg.Records[0].Zone != "example.org" {
+		t.Errorf("file records = %+v, want vpn.example.org in example.org", cfg.Records)
+	}
+}
+
+func TestEnvPrefixPrecedence(t *testing.T) {
+	old := envPrefix
+	envPrefix = "HOME"
+	t.Cleanup(func() { envPrefix = old })
+
+	for _, c := range []struct {
+		name     string
+		env      []string
+		wantName string
+		want     string
+	}{
+		{"prefixed wins", []string{"HOME_ZONE_NAME", "home.example", "ZONE_NAME", "other.example"}, "HOME_ZONE_NAME", "home.example"},
+		{"unprefixed fallback", []string{"ZONE_NAME", "other.example"}, "ZONE_NAME", "other.example"},
+		{"empty prefixed still wins", []string{"HOME_ZONE_NAME", "", "ZONE_NAME", "other.example"}, "HOME_ZONE_NAME", ""},
+		{"neither set", nil, "HOME_ZONE_NAME", ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			for _, name := range []string{"HOME_ZONE_NAME", "ZONE_NAME"} {
+				t.Setenv(name, "")
+				os.Unsetenv(name)
+			}
+			setenv(t, c.env...)
+			if got := envName("ZONE_NAME"); got != c.wantName {
+				t.Errorf("envName = %q, want %q", got, c.wantName)
+			}
+			if got := getenv("ZONE_NAME"); got != c.want {
+				t.Errorf("getenv = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestEnvPrefixInErrors(t *testing.T) {
+	old := envPrefix
+	envPrefix = "HOME"
+	t.Cleanup(func() { envPrefix = old })
+
+	for _, c := range []struct {
+		env  []string
+		want string
+	}{
+		{[]string{"HOME_UPDATE_TOKEN", "secret"}, "HOME_UPDATE_TOKEN requires HOME_HEALTH_ADDR"},
+		{[]string{"HOME_IP_PROVIDERS", ","}, "HOME_IP_PROVIDERS does not contain any providers"},
+		{[]string{"HOME_RECORD_CONTENT", "192.0.2.1", "OVERRIDE_IP", "192.0.2.2"}, "HOME_RECORD_CONTENT and OVERRIDE_IP are mutually exclusive"},
+		{[]string{"HOME_UPDATE_WINDOW", "later"}, "invalid HOME_UPDATE_WINDOW value"},
+	} {
+		t.Run(c.want, func(t *testing.T) {
+			setenv(t, "HOME_ZONE_NAME", "example.com", "HOME_RECORD_NAME", "home.example.com", "HOME_API_TOKEN", "token")
+			setenv(t, c.env...)
+			_, err := getEnvVars("")
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("getEnvVars error %v, want it to contain %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestCredentialsFileNamedCredentials(t *testing.T) {
+	creds := writeFile(t, "credentials.json", `{
+		"org": {"api_token": "token-org", "account_id": "acc-org"},
+		"gandi": {"provider": "gandi", "api_token": "pat"}
+	}`)
+	records := writeFile(t, "records.json", `{"records": [
+		{"name": "home.example.com"},
+		{"name": "home.example.org", "zone": "example.org", "credential": "org"},
+		{"name": "home.example.net", "zone": "example.net", "credential": "gandi"}
+	]}`)
+	setenv(t, "ZONE_NAME", "example.com", "API_TOKEN", "token", "CREDENTIALS_FILE", creds)
+
+	cfg, err := getEnvVars(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	org, ok := cfg.Credentials["org"]
+	if !ok || org.APIToken != "token-org" || org.AccountID != "acc-org" || org.Provider != nil {
+		t.Errorf("credential org = %+v, want a Cloudflare token with its account", org)
+	}
+	if gandi, ok := cfg.Credentials["gandi"]; !ok || gandi.Provider == nil || gandi.Provider.Name() != "gandi" {
+		t.Errorf("credential gandi = %+v, want the gandi provider", gandi)
+	}
+	wantCredential := []string{"", "org", "gandi"}
+	for i, record := range cfg.Records {
+		if record.Credential != wantCredential[i] {
+			t.Errorf("%s: credential %q, want %q", record.Name, record.Credential, wantCredential[i])
+		}
+	}
+	cfg.Logger = slog.New(slog.DiscardHandler)
+	if _, err := ddns.New(cfg.Config); err != nil {
+		t.Errorf("New with named credentials: %v", err)
+	}
+}
+
+func TestCredentialsFileErrors(t *testing.T) {
+	for _, c := range []struct {
+		name, creds, records string
+	}{
+		{"missing token", `{"org": {"account_id": "acc"}}`, `{"records": [{"name": "home.example.com"}]}`},
+		{"unknown provider", `{"org": {"provider": "nowhere", "api_token": "t"}}`, `{"records": [{"name": "home.example.com"}]}`},
+		{"defined twice", `{"org": {"api_token": "t"}}`, `{"credentials": {"org": {"api_token": "u"}}, "records": [{"name": "home.example.com"}]}`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			setenv(t, "ZONE_NAME", "example.com", "API_TOKEN", "token", "CREDENTIALS_FILE", writeFile(t, "credentials.json", c.creds))
+			if _, err := getEnvVars(writeFile(t, "records.json", c.records)); err == nil {
+				t.Error("getEnvVars succeeded, want an error")
+			}
+		})
+	}
+
+	setenv(t, "ZONE_NAME", "example.com", "API_TOKEN", "token", "CREDENTIALS_FILE", writeFile(t, "credentials.json", `{"org": {"api_token": "t"}}`))
+	cfg, err := getEnvVars(writeFile(t, "records.json", `{"records": [{"name": "home.example.org", "zone": "example.org", "credential": "other"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Logger = slog.New(slog.DiscardHandler)
+	if _, err := ddns.New(cfg.Config); err == nil {
+		t.Error("New with a record naming an unknown credential succeeded, want an error")
+	}
+}
+
+func TestConfigFileJSONAndTOMLAgree(t *testing.T) {
+	setenv(t, "ZONE_NAME", "example.com", "API_TOKEN", "token", "PROXIED", "true", "TTL", "120")
+	jsonPath := writeFile(t, "records.json", `{
+		"credentials": {"org": {"api_token": "token-org", "account_id": "acc-org"}},
+		"records": [
+			{"name": "home.example.com", "comment": "home router", "tags": ["env:prod", "site:home"]},
+			{"name": "vpn.example.com", "proxied": false, "ttl": 300},
+			{"name": "home.example.org", "zone": "example.org", "credential": "org", "ttl": 600}
+		]
+	}`)
+	tomlPath := writeFile(t, "records.toml", `
+[credentials.org]
+api_token = "token-org"
+account_id = "acc-org"
+
+[[records]]
+name = "home.example.com"
+comment = "home router"
+tags = ["env:prod", "site:home"]
+
+[[records]]
+name = "vpn.example.com"
+proxied = false
+ttl = 300
+
+[[records]]
+name = "home.example.org"
+zone = "example.org"
+credential = "org"
+ttl = 600
+`)
+
+	fromJSON, err := getEnvVars(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromTOML, err := getEnvVars(tomlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromTOML.Config, fromJSON.Config) {
+		t.Errorf("TOML config\n%+v\ndiffers from the JSON one\n%+v", fromTOML.Config, fromJSON.Config)
+	}
+	if len(fromJSON.Records) != 3 || fromJSON.Records[1].Proxied || fromJSON.Records[1].TTL != 300 {
+		t.Errorf("JSON records = %+v, want the file's three records", fromJSON.Records)
+	}
+}
+
+func TestConfigFormatOverridesExtension(t *testing.T) {
+	setenv(t, "ZONE_NAME", "example.com", "API_TOKEN", "token", "CONFIG_FORMAT", "toml")
+	cfg, err := getEnvVars(writeFile(t, "records.conf", "[[records]]\nname = \"home.example.com\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Records) != 1 || cfg.Records[0].Name != "home.example.com" {
+		t.Errorf("records = %+v, want home.example.com", cfg.Records)
+	}
+}
+
+func TestConfirmStableEnv(t *testing.T) {
+	for _, c := range []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"30s", 30 * time.Second, false},
+		{"-1s", 0, true},
+		{"soon", 0, true},
+	} {
+		t.Run(c.value, func(t *testing.T) {
+			setenv(t, "ZONE_NAME", "example.com", "RECORD_NAME", "home.example.com", "API_TOKEN", "token", "CONFIRM_STABLE", c.value)
+			cfg, err := getEnvVars("")
+			if (err != nil) != c.wantErr {
+				t.Fatalf("getEnvVars error %v, want error %t", err, c.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.ConfirmStable != c.want {
+				t.Errorf("ConfirmStable = %s, want %s", cfg.ConfirmStable, c.want)
+			}
+			if _, err := ddns.New(cfg.Config); err != nil {
+				t.Errorf("ddns.New: %v", err)
+			}
+		})
+	}
+}
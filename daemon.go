@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+	"github.com/casantosmu/ddns-updater/internal/atomicfile"
+)
+
+// daemon runs update cycles for the run command, either once or every
+// cfg.Interval.
+type daemon struct {
+	updater *ddns.Updater
+	cfg     *cliConfig
+
+	// mu serializes cycles, so an HTTP trigger never overlaps a tick.
+	mu sync.Mutex
+
+	lastMu sync.Mutex
+	last   *cycleReport
+
+	triggerMu      sync.Mutex
+	lastTrigger    time.Time
+	triggerPending bool
+}
+
+// errRunTimeout marks a cycle cut short by RUN_TIMEOUT.
+var errRunTimeout = errors.New("run timed out")
+
+// cycle performs one update and records its outcome. Every update, whether
+// one-shot, scheduled or triggered, goes through here. With RunTimeout set,
+// in-flight work is cancelled once it elapses and errRunTimeout is returned.
+func (d *daemon) cycle(ctx context.Context) (*ddns.Result, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	runCtx := ctx
+	if d.cfg.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, d.cfg.RunTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	result, err := d.updater.Run(runCtx)
+	if ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		if err != nil {
+			err = fmt.Errorf("%w after %s: %w", errRunTimeout, d.cfg.RunTimeout, err)
+		} else {
+			err = fmt.Errorf("%w after %s", errRunTimeout, d.cfg.RunTimeout)
+		}
+	}
+	now := time.Now()
+	if d.cfg.DryRun {
+		return result, err
+	}
+	if d.cfg.Quiet {
+		logChanges(result)
+	}
+	if d.cfg.MetricsTextfile != "" {
+		if err := writeMetricsTextfile(d.cfg.MetricsTextfile, result, err, now); err != nil {
+			slog.Warn("Failed to write metrics textfile", "path", d.cfg.MetricsTextfile, "error", err)
+		}
+	}
+
+	if d.cfg.StatsdAddr != "" {
+		if err := sendStatsd(d.cfg.StatsdAddr, result, err, now.Sub(start)); err != nil {
+			slog.Warn("Failed to send statsd metrics", "addr", d.cfg.StatsdAddr, "error", err)
+		}
+	}
+
+	if d.cfg.HistoryFile != "" {
+		if err := appendHistory(d.cfg.HistoryFile, historyEntries(result, now), d.cfg.HistoryMax); err != nil {
+			slog.Warn("Failed to write history file", "path", d.cfg.HistoryFile, "error", err)
+		}
+	}
+
+	if d.cfg.HostsFile != "" {
+		if err := updateHostsFile(d.cfg.HostsFile, result); err != nil {
+			slog.Warn("Failed to update hosts file", "path", d.cfg.HostsFile, "error", err)
+		}
+	}
+
+	report := newCycleReport(result, err, now)
+	if d.cfg.IPOutputFile != "" && err == nil {
+		if ip := report.address(); ip != "" {
+			if err := atomicfile.Write(d.cfg.IPOutputFile, []byte(ip+"\n"), 0o644); err != nil {
+				slog.Warn("Failed to write IP output file", "path", d.cfg.IPOutputFile, "error", err)
+			}
+		}
+	}
+
+	if d.cfg.StatusFile != "" {
+		if err := writeStatusFile(d.cfg.StatusFile, report); err != nil {
+			slog.Warn("Failed to write status file", "path", d.cfg.StatusFile, "error", err)
+		}
+	}
+
+	d.lastMu.Lock()
+	d.last = &report
+	d.lastMu.Unlock()
+	return result, err
+}
+
+func (d *daemon) lastReport() (cycleReport, bool) {
+	d.lastMu.Lock()
+	defer d.lastMu.Unlock()
+	if d.last == nil {
+		return cycleReport{}, false
+	}
+	return *d.last, true
+}
+
+// acceptTrigger reports whether an HTTP trigger should run a cycle now. A
+// trigger within the debounce window of the previous one is coalesced: it
+// returns false and at most one follow-up cycle runs when the window ends,
+// so a change seen by the last trigger of a burst is not lost.
+func (d *daemon) acceptTrigger(ctx context.Context) bool {
+	d.triggerMu.Lock()
+	defer d.triggerMu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(d.lastTrigger)
+	if d.lastTrigger.IsZero() || elapsed >= d.cfg.TriggerDebounce {
+		d.lastTrigger = now
+		return true
+	}
+	if !d.triggerPending {
+		d.triggerPending = true
+		time.AfterFunc(d.cfg.TriggerDebounce-elapsed, func() { d.runPendingTrigger(ctx) })
+	}
+	return false
+}
+
+func (d *daemon) runPendingTrigger(ctx context.Context) {
+	d.triggerMu.Lock()
+	d.triggerPending = false
+	d.lastTrigger = time.Now()
+	d.triggerMu.Unlock()
+
+	if ctx.Err() != nil {
+		return
+	}
+	slog.Info("Running coalesced HTTP trigger")
+	if _, err := d.cycle(ctx); err != nil && ctx.Err() == nil {
+		slog.Error("Update cycle failed", "error", err)
+	}
+}
+
+// loop runs a cycle immediately and then on every tick until ctx is done.
+// Failed cycles are logged and retried on the next tick. Changes deferred
+// by the update window get an extra cycle when the window opens, in case
+// no tick falls inside it.
+func (d *daemon) loop(ctx context.Context) {
+	if d.cfg.HealthAddr != "" {
+		go d.serve(ctx)
+	}
+
+	slog.Info("Starting update loop", "interval", d.cfg.Interval)
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := d.cycle(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Error("Update cycle failed", "error", err)
+		}
+
+		var windowOpen *time.Timer
+		if result != nil && !result.DeferredUntil.IsZero() {
+			slog.Info("Scheduling a cycle for when the update window opens", "at", result.DeferredUntil)
+			windowOpen = time.NewTimer(time.Until(result.DeferredUntil))
+		}
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping update loop")
+			return
+		case <-ticker.C:
+		case <-timerC(windowOpen):
+		}
+		if windowOpen != nil {
+			windowOpen.Stop()
+		}
+	}
+}
+
+// timerC returns t's channel, or nil, which blocks forever, when t is nil.
+func timerC(t *time.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// newTestDaemon returns a daemon whose updater detects its address from
+// provider and does not retry.
+func newTestDaemon(t *testing.T, provider string, runTimeout time.Duration) *daemon {
+	t.Helper()
+	cfg := &cliConfig{
+		Config: ddns.Config{
+			ZoneName:    "example.com",
+			APIToken:    "token",
+			Records:     []ddns.Record{{Name: "home.example.com", Type: "A", TTL: ddns.AutoTTL}},
+			IPProviders: []string{provider},
+			Retry:       ddns.RetryPolicy{Attempts: 1},
+			Logger:      slog.New(slog.DiscardHandler),
+		},
+		RunTimeout: runTimeout,
+	}
+	updater, err := ddns.New(cfg.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &daemon{updater: updater, cfg: cfg}
+}
+
+func TestCycleRunTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	d := newTestDaemon(t, slow.URL, 50*time.Millisecond)
+	start := time.Now()
+	_, err := d.cycle(context.Background())
+	if !errors.Is(err, errRunTimeout) {
+		t.Fatalf("cycle error %v, want errRunTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("cycle took %s, want it cut short near the 50ms timeout", elapsed)
+	}
+	if report, ok := d.lastReport(); !ok || report.Error == "" {
+		t.Errorf("last report = %+v, want the timeout recorded", report)
+	}
+}
+
+func TestCycleFailureWithinRunTimeout(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	d := newTestDaemon(t, failing.URL, time.Minute)
+	_, err := d.cycle(context.Background())
+	if err == nil || errors.Is(err, errRunTimeout) {
+		t.Errorf("cycle error %v, want a provider failure that is not a timeout", err)
+	}
+}
@@ -0,0 +1,56 @@
+package ddns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records one create, update or delete sent to a DNS API.
+type AuditEntry struct {
+	Time time.Time `json:"time"`
+	// Actor identifies the credential used: "token:" and a truncated
+	// SHA-256 of the Cloudflare token, or "provider:" and the provider name.
+	Actor  string `json:"actor"`
+	Op     string `json:"op"`
+	Record string `json:"record"`
+	Type   string `json:"type"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+	// Result is "ok" or "error".
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// tokenActor returns the audit actor of a Cloudflare token. The hash lets
+// entries be matched to a token without the log revealing it.
+func tokenActor(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return "token:" + hex.EncodeToString(sum[:8])
+}
+
+// audit writes entry to Config.AuditLog, filling in its time and the result
+// of err. Read-only calls are never audited.
+func (u *Updater) audit(entry AuditEntry, err error) {
+	if u.cfg.AuditLog == nil {
+		return
+	}
+	entry.Time = time.Now().UTC()
+	entry.Result = "ok"
+	if err != nil {
+		entry.Result = "error"
+		entry.Error = err.Error()
+	}
+	line, jsonErr := json.Marshal(entry)
+	if jsonErr != nil {
+		u.log.Warn("Failed to encode audit entry", "error", jsonErr)
+		return
+	}
+
+	u.auditMu.Lock()
+	defer u.auditMu.Unlock()
+	if _, err := u.cfg.AuditLog.Write(append(line, '\n')); err != nil {
+		u.log.Warn("Failed to write audit entry", "record", entry.Record, "error", err)
+	}
+}
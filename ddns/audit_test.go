@@ -0,0 +1,78 @@
+package ddns
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditEntries(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "vpn.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+	f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		if r.Method == "POST" && strings.Contains(string(body), "mail.example.com") {
+			writeError(w, http.StatusBadRequest, 9005, "Content for A record is invalid")
+			return true
+		}
+		return false
+	}
+	var log bytes.Buffer
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "secret-token",
+		AuditLog: &log,
+		Records:  []Record{{Name: "home.example.com"}, {Name: "vpn.example.com"}, {Name: "mail.example.com"}},
+	}, f)
+	before := time.Now().UTC()
+	u.Run(t.Context())
+
+	if strings.Contains(log.String(), "secret-token") {
+		t.Fatalf("audit log reveals the API token:\n%s", log.String())
+	}
+	lines := strings.Split(strings.TrimSuffix(log.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d audit lines, want 3:\n%s", len(lines), log.String())
+	}
+	entries := make(map[string]AuditEntry)
+	for _, line := range lines {
+		var raw map[string]any
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			t.Fatalf("invalid audit line %q: %v", line, err)
+		}
+		for _, key := range []string{"time", "actor", "op", "record", "type", "new", "result"} {
+			if _, ok := raw[key]; !ok {
+				t.Errorf("audit line %s lacks %q", line, key)
+			}
+		}
+		var entry AuditEntry
+		json.Unmarshal([]byte(line), &entry)
+		if entry.Time.Before(before.Add(-time.Second)) || entry.Time.Location() != time.UTC {
+			t.Errorf("%s: time %s, want the UTC time of the call", entry.Record, entry.Time)
+		}
+		entries[entry.Record] = entry
+	}
+
+	actor := tokenActor("secret-token")
+	for _, want := range []AuditEntry{
+		{Actor: actor, Op: "create", Record: "home.example.com", Type: "A", New: testIP, Result: "ok"},
+		{Actor: actor, Op: "update", Record: "vpn.example.com", Type: "A", Old: "192.0.2.1", New: testIP, Result: "ok"},
+		{Actor: actor, Op: "create", Record: "mail.example.com", Type: "A", New: testIP, Result: "error"},
+	} {
+		got := entries[want.Record]
+		got.Time = time.Time{}
+		errText := got.Error
+		got.Error = ""
+		if got != want {
+			t.Errorf("entry %+v, want %+v", got, want)
+		}
+		if (want.Result == "error") != (errText != "") {
+			t.Errorf("%s: error %q with result %s", want.Record, errText, want.Result)
+		}
+	}
+	if !strings.HasPrefix(actor, "token:") || len(actor) != len("token:")+16 {
+		t.Errorf("actor %q, want token: and 16 hex digits", actor)
+	}
+}
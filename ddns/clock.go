@@ -0,0 +1,42 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ClockSkew compares the local clock with the Date header returned by the
+// DNS API and reports how far ahead of it the local clock is, negative when
+// behind. The header has a one-second resolution, so smaller skews read as
+// zero. Token and signature checks can fail on a badly skewed clock.
+func (u *Updater) ClockSkew(ctx context.Context) (time.Duration, error) {
+	api := u.apiEndpoint()
+	if api == "" {
+		return 0, errors.New("provider does not report an API endpoint")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "HEAD", api, nil)
+	if err != nil {
+		return 0, err
+	}
+	client := &http.Client{Timeout: u.cfg.CFHTTPTimeout}
+	sent := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach %s: %w", api, err)
+	}
+	resp.Body.Close()
+	received := time.Now()
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("%s returned no usable Date header", api)
+	}
+	// The server stamped the response somewhere between sending and
+	// receiving; the midpoint halves the error the round trip adds.
+	local := sent.Add(received.Sub(sent) / 2)
+	return local.Sub(date).Truncate(time.Second), nil
+}
@@ -0,0 +1,629 @@
+package ddns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CloudflareResponse is the envelope returned by the Cloudflare v4 API.
+type CloudflareResponse[T any] struct {
+	Result     []T         `json:"result"`
+	ResultInfo *ResultInfo `json:"result_info"`
+	Success    bool        `json:"success"`
+	Errors     []any       `json:"errors"`
+}
+
+// ResultInfo describes the page a list response holds.
+type ResultInfo struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	TotalPages int `json:"total_pages"`
+	Count      int `json:"count"`
+	TotalCount int `json:"total_count"`
+}
+
+// MorePages reports whether pages after this one exist. It is false when
+// the response carried no result_info.
+func (ri *ResultInfo) MorePages() bool {
+	return ri != nil && ri.Page < ri.TotalPages
+}
+
+// Zone is a Cloudflare zone as returned by the zones endpoint.
+type Zone struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Account struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"account"`
+}
+
+// DNSRecord is a DNS record as returned by the dns_records endpoint.
+type DNSRecord struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Content    string    `json:"content"`
+	Type       string    `json:"type"`
+	Priority   int       `json:"priority"`
+	Data       *SRVData  `json:"data"`
+	Proxied    bool      `json:"proxied"`
+	TTL        int       `json:"ttl"`
+	Comment    string    `json:"comment"`
+	Tags       []string  `json:"tags"`
+	Locked     bool      `json:"locked"`
+	ModifiedOn time.Time `json:"modified_on"`
+
+	// etag is the ETag of the lookup that returned only this record, sent
+	// back as If-Match on update. Empty when Cloudflare sent none.
+	etag string
+}
+
+// DNSRecordPayload is the request body for creating or updating records
+// that carry a flat content value.
+type DNSRecordPayload struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name"`
+	Content  string   `json:"content"`
+	Proxied  bool     `json:"proxied"`
+	TTL      int      `json:"ttl"`
+	Priority *int     `json:"priority,omitempty"`
+	Comment  string   `json:"comment,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// SRVData is the structured data of an SRV record.
+type SRVData struct {
+	Service  string `json:"service"`
+	Proto    string `json:"proto"`
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	Weight   int    `json:"weight"`
+	Port     int    `json:"port"`
+	Target   string `json:"target"`
+}
+
+// SRVRecordPayload is the request body for creating or updating SRV records.
+type SRVRecordPayload struct {
+	Type    string   `json:"type"`
+	Name    string   `json:"name"`
+	Data    SRVData  `json:"data"`
+	TTL     int      `json:"ttl"`
+	Comment string   `json:"comment,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// APIError is a non-2xx response from the Cloudflare API.
+type APIError struct {
+	StatusCode int
+	Errors     []APIErrorDetail
+	Body       string
+}
+
+// APIErrorDetail is one entry of the errors array of a Cloudflare response.
+type APIErrorDetail struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("cloudflare API error (status %d): %s", e.StatusCode, e.Body)
+}
+
+// ServerError is a 5xx response from the Cloudflare API. Such calls are
+// retried; the APIError it wraps still matches errors.As.
+type ServerError struct {
+	*APIError
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("cloudflare server error (status %d): %s", e.StatusCode, e.Body)
+}
+
+func (e *ServerError) Unwrap() error {
+	return e.APIError
+}
+
+// maxErrorBody caps how much of an error response is kept, so an HTML
+// error page does not flood the logs.
+const maxErrorBody = 4 << 10
+
+// readErrorBody reads at most maxErrorBody bytes of an error response,
+// marking the body when it was cut short.
+func readErrorBody(r io.Reader) []byte {
+	body, _ := io.ReadAll(io.LimitReader(r, maxErrorBody+1))
+	if len(body) > maxErrorBody {
+		body = append(body[:maxErrorBody], "... (truncated)"...)
+	}
+	return body
+}
+
+// responseError returns the error for a non-2xx response: a ServerError
+// for 5xx statuses, an APIError otherwise.
+func responseError(status int, body []byte) error {
+	apiErr := newAPIError(status, body)
+	if status >= 500 {
+		return &ServerError{apiErr}
+	}
+	return apiErr
+}
+
+func newAPIError(status int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: status, Body: string(body)}
+	var envelope struct {
+		Errors []APIErrorDetail `json:"errors"`
+	}
+	if json.Unmarshal(body, &envelope) == nil {
+		apiErr.Errors = envelope.Errors
+	}
+	return apiErr
+}
+
+// Cloudflare answers a token without the needed permission with 403 and one
+// of these codes.
+const (
+	cfCodeAuthentication = 10000
+	cfCodeUnauthorized   = 9109
+)
+
+// isPermissionDenied reports whether err is Cloudflare refusing the call for
+// lack of permission, as opposed to a bad request.
+func isPermissionDenied(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		return false
+	}
+	for _, e := range apiErr.Errors {
+		if e.Code == cfCodeAuthentication || e.Code == cfCodeUnauthorized {
+			return true
+		}
+	}
+	return false
+}
+
+// isConcurrentUpdate reports whether err is Cloudflare refusing a
+// conditional update because the record no longer matches its If-Match
+// ETag.
+func isConcurrentUpdate(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// isOutage reports whether err is a DNS host being unreachable or failing
+// on its side, as opposed to refusing the request.
+func isOutage(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var serverErr *ServerError
+	var urlErr *url.Error
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &serverErr), errors.As(err, &urlErr):
+		return true
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// cfCodeInvalidZone is returned, alongside a 404, for a zone ID that does
+// not exist (any more).
+const cfCodeInvalidZone = 7003
+
+// isStaleZone reports whether err is Cloudflare rejecting the zone ID of
+// the request, as happens after the zone is deleted and re-added.
+func isStaleZone(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.StatusCode == http.StatusNotFound {
+		return true
+	}
+	for _, e := range apiErr.Errors {
+		if e.Code == cfCodeInvalidZone {
+			return true
+		}
+	}
+	return false
+}
+
+const cloudflareBaseURL = "https://api.cloudflare.com/client/v4"
+
+type cloudflare struct {
+	client     *http.Client
+	log        *slog.Logger
+	baseURL    string
+	token      string
+	accountID  string
+	zoneSelect ZoneSelect
+	actor      string
+	debugDump  bool
+	retry      RetryPolicy
+	timeout    time.Duration
+	limiter    *rateLimiter
+	budget     *retryBudget
+}
+
+// cfRequest calls the Cloudflare API, retrying network errors, rate limits
+// and server errors according to the retry policy.
+func (cf *cloudflare) cfRequest(ctx context.Context, method, endpoint string, bodyData any) (*http.Response, error) {
+	return cf.cfRequestHeader(ctx, method, endpoint, bodyData, nil)
+}
+
+// cfRequestHeader is cfRequest with extra request headers.
+func (cf *cloudflare) cfRequestHeader(ctx context.Context, method, endpoint string, bodyData any, header http.Header) (*http.Response, error) {
+	var jsonData []byte
+	if bodyData != nil {
+		var err error
+		jsonData, err = json.Marshal(bodyData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, retryable, err := cf.attempt(ctx, method, endpoint, jsonData, header)
+		if err == nil || !retryable || attempt >= cf.retry.Attempts {
+			return resp, err
+		}
+		if !cf.budget.take() {
+			cf.log.Warn("Retry budget spent, not retrying", "method", method, "endpoint", endpoint, "budget", cf.retry.Budget)
+			return resp, err
+		}
+
+		delay := cf.retry.delay(attempt)
+		cf.log.Warn("Cloudflare request failed, retrying", "method", method, "endpoint", endpoint, "attempt", attempt, "delay", delay, "error", err)
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (cf *cloudflare) attempt(ctx context.Context, method, endpoint string, jsonData []byte, header http.Header) (resp *http.Response, retryable bool, err error) {
+	if err := cf.limiter.wait(ctx); err != nil {
+		return nil, false, err
+	}
+	defer observeAttempt(ctx, time.Now())
+
+	var bodyReader io.Reader
+	if jsonData != nil {
+		bodyReader = bytes.NewReader(jsonData)
+	}
+
+	attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+	if cf.timeout > 0 {
+		attemptCtx, cancel = context.WithTimeout(ctx, cf.timeout)
+	}
+	defer cancel()
+	req, err := http.NewRequestWithContext(attemptCtx, method, cf.baseURL+endpoint, bodyReader)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+cf.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err = cf.client.Do(req)
+	if err != nil {
+		cf.dump(req, jsonData, nil, nil, err)
+		return nil, ctx.Err() == nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody := readErrorBody(resp.Body)
+		resp.Body.Close()
+		cf.dump(req, jsonData, resp, respBody, nil)
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retryable, responseError(resp.StatusCode, respBody)
+	}
+
+	if cf.timeout > 0 {
+		// Read the body under the attempt's deadline, so a response that
+		// stalls midway is retried like one that never arrives.
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, ctx.Err() == nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return resp, false, nil
+}
+
+// dump logs a failed exchange in full when debug dumps are enabled. The
+// Authorization header is redacted.
+func (cf *cloudflare) dump(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, err error) {
+	if !cf.debugDump {
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", req.Method, req.URL)
+	for _, name := range slices.Sorted(maps.Keys(req.Header)) {
+		value := strings.Join(req.Header[name], ", ")
+		if name == "Authorization" {
+			value = "Bearer [redacted]"
+		}
+		fmt.Fprintf(&b, "%s: %s\n", name, value)
+	}
+	if len(reqBody) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", reqBody)
+	}
+
+	if resp != nil {
+		fmt.Fprintf(&b, "\n%s %s\n", resp.Proto, resp.Status)
+		for _, name := range slices.Sorted(maps.Keys(resp.Header)) {
+			fmt.Fprintf(&b, "%s: %s\n", name, strings.Join(resp.Header[name], ", "))
+		}
+		fmt.Fprintf(&b, "\n%s\n", respBody)
+	} else {
+		fmt.Fprintf(&b, "\nno response: %v\n", err)
+	}
+
+	cf.log.Warn("Cloudflare API exchange failed", "dump", b.String())
+}
+
+// verifyToken checks that the API token is valid and active.
+func (cf *cloudflare) verifyToken(ctx context.Context) error {
+	resp, err := cf.cfRequest(ctx, "GET", "/user/tokens/verify", nil)
+	if err != nil {
+		return fmt.Errorf("failed to verify API token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var cfResp struct {
+		Result struct {
+			Status string `json:"status"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
+		return fmt.Errorf("failed to decode token verification response: %w", err)
+	}
+	if cfResp.Result.Status != "active" {
+		return fmt.Errorf("API token is %s", cfResp.Result.Status)
+	}
+	return nil
+}
+
+// getZoneID looks zoneName up, within cf.accountID when it is set. A name
+// found in several accounts is resolved by cf.zoneSelect, an error unless
+// it says otherwise.
+func (cf *cloudflare) getZoneID(ctx context.Context, zoneName string) (string, error) {
+	endpoint := "/zones?name=" + strings.ToLower(zoneName)
+	if cf.accountID != "" {
+		endpoint += "&account.id=" + url.QueryEscape(cf.accountID)
+	}
+	resp, err := cf.cfRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch zone ID: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var cfResp CloudflareResponse[Zone]
+	if err := json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
+		return "", fmt.Errorf("failed to decode zone response: %w", err)
+	}
+
+	if cfResp.ResultInfo.MorePages() {
+		cf.log.Warn("Zone lookup returned more than one page, only the first is checked", "zone", zoneName, "total_count", cfResp.ResultInfo.TotalCount)
+	}
+	var matches []Zone
+	for _, zone := range cfResp.Result {
+		if !sameName(zone.Name, zoneName) {
+			continue
+		}
+		// Under ZoneSelectAccount a zone without account details is not
+		// known to be the configured account's.
+		if cf.accountID == "" || zone.Account.ID == cf.accountID || zone.Account.ID == "" && cf.zoneSelect != ZoneSelectAccount {
+			matches = append(matches, zone)
+		}
+	}
+	if len(matches) == 0 {
+		if cf.zoneSelect == ZoneSelectAccount {
+			return "", fmt.Errorf("zone not found in account %s", cf.accountID)
+		}
+		return "", fmt.Errorf("zone not found")
+	}
+	zone := matches[0]
+	if len(matches) > 1 {
+		accounts := make([]string, 0, len(matches))
+		for _, m := range matches {
+			accounts = append(accounts, fmt.Sprintf("%s (%s)", m.Account.ID, m.Account.Name))
+		}
+		if cf.zoneSelect != ZoneSelectFirst {
+			return "", fmt.Errorf("zone name is ambiguous, the token sees it in %d accounts: %s; set an account ID", len(matches), strings.Join(accounts, ", "))
+		}
+		cf.log.Warn("Zone name is ambiguous, using the first match", "zone", zoneName, "accounts", strings.Join(accounts, ", "))
+	}
+	cf.log.Info("Zone found", "zone", zoneName, "zone_id", zone.ID, "account_id", zone.Account.ID, "account", zone.Account.Name)
+	return zone.ID, nil
+}
+
+func (cf *cloudflare) getRecordData(ctx context.Context, zoneID, recordName, recordType string) (*DNSRecord, error) {
+	records, err := cf.getRecordSet(ctx, zoneID, recordName, recordType)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return &records[0], nil
+}
+
+// getRecordSet returns every record with the given name and type, up to
+// the 100 a page holds.
+func (cf *cloudflare) getRecordSet(ctx context.Context, zoneID, recordName, recordType string) ([]DNSRecord, error) {
+	endpoint := fmt.Sprintf("/zones/%s/dns_records?name=%s&type=%s&per_page=100", zoneID, strings.ToLower(recordName), recordType)
+	resp, err := cf.cfRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch record data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var cfResp CloudflareResponse[DNSRecord]
+	if err := json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
+		return nil, fmt.Errorf("failed to decode record response: %w", err)
+	}
+
+	if cfResp.ResultInfo.MorePages() {
+		cf.log.Warn("Record lookup returned more than one page, only the first is checked", "record", recordName, "total_count", cfResp.ResultInfo.TotalCount)
+	}
+	// The API filters on name and type, but only an exact match is trusted
+	// so a near miss can never be picked up and overwritten.
+	var records []DNSRecord
+	for _, record := range cfResp.Result {
+		if record.Type == recordType && sameName(record.Name, recordName) {
+			cf.log.Info("Record found", "record", record.Name, "record_id", record.ID, "content", record.Content)
+			records = append(records, record)
+		}
+	}
+	// An ETag covers the whole response, so it only stands for a record
+	// that was alone in it.
+	if etag := resp.Header.Get("ETag"); etag != "" && len(cfResp.Result) == 1 && len(records) == 1 {
+		records[0].etag = etag
+	}
+	return records, nil
+}
+
+// sameName reports whether two DNS names are equal, ignoring case and a
+// trailing root dot.
+func sameName(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+func (cf *cloudflare) createDNSRecord(ctx context.Context, zoneID string, payload any) error {
+	endpoint := fmt.Sprintf("/zones/%s/dns_records", zoneID)
+	resp, err := cf.cfRequest(ctx, "POST", endpoint, payload)
+	if isPermissionDenied(err) {
+		return fmt.Errorf("failed to create DNS record: token lacks DNS:Edit for this zone: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create DNS record: %w", err)
+	}
+	defer resp.Body.Close()
+
+	cf.log.Info("DNS record created")
+	return nil
+}
+
+// updateDNSRecord replaces the record. With an etag, the update is
+// conditional on the record not having changed since it was read; see
+// isConcurrentUpdate.
+func (cf *cloudflare) updateDNSRecord(ctx context.Context, zoneID, recordID, etag string, payload any) error {
+	endpoint := fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, recordID)
+	var header http.Header
+	if etag != "" {
+		header = http.Header{"If-Match": {etag}}
+	}
+	resp, err := cf.cfRequestHeader(ctx, "PUT", endpoint, payload, header)
+	if isConcurrentUpdate(err) {
+		return fmt.Errorf("failed to update DNS record: record was modified since it was read: %w", err)
+	}
+	if isPermissionDenied(err) {
+		return fmt.Errorf("failed to update DNS record: token lacks DNS:Edit for this zone: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update DNS record: %w", err)
+	}
+	defer resp.Body.Close()
+
+	cf.log.Info("DNS record updated", "record_id", recordID)
+	return nil
+}
+
+// listDNSRecords returns every record in the zone.
+func (cf *cloudflare) listDNSRecords(ctx context.Context, zoneID string) ([]DNSRecord, error) {
+	const perPage = 100
+
+	var records []DNSRecord
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("/zones/%s/dns_records?per_page=%d&page=%d", zoneID, perPage, page)
+		resp, err := cf.cfRequest(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list DNS records: %w", err)
+		}
+
+		var cfResp CloudflareResponse[DNSRecord]
+		err = json.NewDecoder(resp.Body).Decode(&cfResp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode record list response: %w", err)
+		}
+
+		records = append(records, cfResp.Result...)
+		if info := cfResp.ResultInfo; info != nil {
+			if !info.MorePages() {
+				return records, nil
+			}
+		} else if len(cfResp.Result) < perPage {
+			return records, nil
+		}
+	}
+}
+
+func (cf *cloudflare) deleteDNSRecord(ctx context.Context, zoneID, recordID string) error {
+	endpoint := fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, recordID)
+	resp, err := cf.cfRequest(ctx, "DELETE", endpoint, nil)
+	if isPermissionDenied(err) {
+		return fmt.Errorf("failed to delete DNS record: token lacks DNS:Edit for this zone: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete DNS record: %w", err)
+	}
+	defer resp.Body.Close()
+
+	cf.log.Info("DNS record deleted", "record_id", recordID)
+	return nil
+}
+
+func buildPayload(record Record, content string) any {
+	if record.Type == "SRV" {
+		return SRVRecordPayload{
+			Type:    record.Type,
+			Name:    record.Name,
+			Data:    buildSRVData(record, content),
+			TTL:     record.TTL,
+			Comment: record.Comment,
+			Tags:    record.Tags,
+		}
+	}
+
+	payload := DNSRecordPayload{
+		Type:    record.Type,
+		Name:    record.Name,
+		Content: content,
+		Proxied: record.Proxied,
+		TTL:     record.TTL,
+		Comment: record.Comment,
+		Tags:    record.Tags,
+	}
+	if record.Type == "MX" {
+		payload.Priority = &record.Priority
+	}
+	return payload
+}
+
+func buildSRVData(record Record, target string) SRVData {
+	service, proto, host, _ := splitSRVName(record.Name)
+	return SRVData{
+		Service:  service,
+		Proto:    proto,
+		Name:     host,
+		Priority: record.Priority,
+		Weight:   record.Weight,
+		Port:     record.Port,
+		Target:   target,
+	}
+}
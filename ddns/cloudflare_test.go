@@ -0,0 +1,631 @@
+package ddns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testIP is what the fake Cloudflare server's /ip endpoint reports as the
+// public address.
+const testIP = "203.0.113.10"
+
+// fakeCloudflare is an in-memory Cloudflare API: zones, their DNS records
+// and the requests it received.
+type fakeCloudflare struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	zones    []Zone
+	records  map[string][]DNSRecord
+	requests []fakeRequest
+	nextID   int
+	// handle, when set, sees every request first and answers it itself by
+	// returning true.
+	handle func(w http.ResponseWriter, r *http.Request, body []byte) bool
+}
+
+type fakeRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// newFakeCloudflare starts a fake API serving one zone per name, with ID
+// "zone-" followed by the name.
+func newFakeCloudflare(t *testing.T, zones ...string) *fakeCloudflare {
+	t.Helper()
+	f := &fakeCloudflare{records: make(map[string][]DNSRecord)}
+	for _, name := range zones {
+		f.zones = append(f.zones, Zone{ID: "zone-" + name, Name: name})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ip", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, testIP)
+	})
+	mux.HandleFunc("GET /user/tokens/verify", func(w http.ResponseWriter, r *http.Request) {
+		writeResult(w, map[string]string{"status": "active"}, nil)
+	})
+	mux.HandleFunc("GET /zones", f.listZones)
+	mux.HandleFunc("GET /zones/{zone}/dns_records", f.listRecords)
+	mux.HandleFunc("POST /zones/{zone}/dns_records", f.createRecord)
+	mux.HandleFunc("PUT /zones/{zone}/dns_records/{id}", f.updateRecord)
+	mux.HandleFunc("DELETE /zones/{zone}/dns_records/{id}", f.deleteRecord)
+
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.mu.Lock()
+		f.requests = append(f.requests, fakeRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: body})
+		handle := f.handle
+		f.mu.Unlock()
+		if handle != nil && handle(w, r, body) {
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func writeResult(w http.ResponseWriter, result any, info *ResultInfo) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "errors": []any{}, "result": result, "result_info": info})
+}
+
+// writeError answers with status and one Cloudflare error of code.
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"success": false, "errors": []APIErrorDetail{{Code: code, Message: message}}})
+}
+
+func (f *fakeCloudflare) knownZone(id string) bool {
+	return slices.ContainsFunc(f.zones, func(z Zone) bool { return z.ID == id })
+}
+
+func (f *fakeCloudflare) listZones(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zones := []Zone{}
+	for _, zone := range f.zones {
+		name, account := r.URL.Query().Get("name"), r.URL.Query().Get("account.id")
+		if (name == "" || sameName(zone.Name, name)) && (account == "" || zone.Account.ID == account) {
+			zones = append(zones, zone)
+		}
+	}
+	writeResult(w, zones, nil)
+}
+
+func (f *fakeCloudflare) listRecords(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zoneID := r.PathValue("zone")
+	if !f.knownZone(zoneID) {
+		writeError(w, http.StatusNotFound, cfCodeInvalidZone, "Invalid zone identifier")
+		return
+	}
+	q := r.URL.Query()
+	records := []DNSRecord{}
+	for _, record := range f.records[zoneID] {
+		if (q.Get("name") == "" || sameName(record.Name, q.Get("name"))) && (q.Get("type") == "" || record.Type == q.Get("type")) {
+			records = append(records, record)
+		}
+	}
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if perPage <= 0 {
+		perPage = 100
+	}
+	page, _ := strconv.Atoi(q.Get("page"))
+	page = max(page, 1)
+	info := &ResultInfo{Page: page, PerPage: perPage, TotalCount: len(records), TotalPages: max(1, (len(records)+perPage-1)/perPage)}
+	start := min(len(records), (page-1)*perPage)
+	end := min(len(records), start+perPage)
+	info.Count = end - start
+	writeResult(w, records[start:end], info)
+}
+
+func (f *fakeCloudflare) createRecord(w http.ResponseWriter, r *http.Request) {
+	var record DNSRecord
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeError(w, http.StatusBadRequest, 1000, err.Error())
+		return
+	}
+	f.addRecord(r.PathValue("zone"), record)
+	writeResult(w, record, nil)
+}
+
+func (f *fakeCloudflare) updateRecord(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zoneID, id := r.PathValue("zone"), r.PathValue("id")
+	if !f.knownZone(zoneID) {
+		writeError(w, http.StatusNotFound, cfCodeInvalidZone, "Invalid zone identifier")
+		return
+	}
+	i := slices.IndexFunc(f.records[zoneID], func(record DNSRecord) bool { return record.ID == id })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, 81044, "Record does not exist")
+		return
+	}
+	var record DNSRecord
+	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeError(w, http.StatusBadRequest, 1000, err.Error())
+		return
+	}
+	record.ID = id
+	f.records[zoneID][i] = record
+	writeResult(w, record, nil)
+}
+
+func (f *fakeCloudflare) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	zoneID, id := r.PathValue("zone"), r.PathValue("id")
+	f.records[zoneID] = slices.DeleteFunc(f.records[zoneID], func(record DNSRecord) bool { return record.ID == id })
+	writeResult(w, map[string]string{"id": id}, nil)
+}
+
+// addRecord stores record in the zone zoneID, giving it an ID unless it has
+// one.
+func (f *fakeCloudflare) addRecord(zoneID string, record DNSRecord) DNSRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if record.ID == "" {
+		f.nextID++
+		record.ID = fmt.Sprintf("rec-%d", f.nextID)
+	}
+	f.records[zoneID] = append(f.records[zoneID], record)
+	return record
+}
+
+// recordsOf returns the records of the zone zoneID.
+func (f *fakeCloudflare) recordsOf(zoneID string) []DNSRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.records[zoneID])
+}
+
+// requestsFor returns the requests received with method, or all of them
+// when method is empty.
+func (f *fakeCloudflare) requestsFor(method string) []fakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []fakeRequest
+	for _, req := range f.requests {
+		if method == "" || req.Method == method {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
+
+// newTestUpdater returns an Updater for cfg talking to f. Unless cfg says
+// otherwise, it logs nothing, does not retry and detects testIP as the
+// public address of A records.
+func newTestUpdater(t *testing.T, cfg Config, f *fakeCloudflare) *Updater {
+	t.Helper()
+	if cfg.Logger == nil {
+		cfg.Logger = testLogger()
+	}
+	if cfg.Retry.Attempts == 0 {
+		cfg.Retry.Attempts = 1
+	}
+	if cfg.Content == "" && len(cfg.OverrideIPs) == 0 && len(cfg.IPProviders)+len(cfg.IPv4Providers)+len(cfg.IPv6Providers) == 0 && cfg.IPCommand == "" {
+		cfg.IPProviders = []string{f.srv.URL + "/ip"}
+	}
+	u, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for _, cf := range u.cf {
+		cf.baseURL = f.srv.URL
+	}
+	return u
+}
+
+// run runs u once and fails the test on an error.
+func run(t *testing.T, u *Updater) *Result {
+	t.Helper()
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return result
+}
+
+// decodeBody decodes the JSON body of req into a map.
+func decodeBody(t *testing.T, req fakeRequest) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(req.Body, &m); err != nil {
+		t.Fatalf("%s %s: invalid body %q: %v", req.Method, req.Path, req.Body, err)
+	}
+	return m
+}
+
+func TestMXRecordPriority(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "mx2.example.com", Type: "MX", Content: "old.example.net", Priority: 10, TTL: AutoTTL})
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Content:  "mail.example.net",
+		Records: []Record{
+			{Name: "mx1.example.com", Type: "MX", Priority: 5},
+			{Name: "mx2.example.com", Type: "MX", Priority: 20},
+		},
+	}, f)
+	run(t, u)
+
+	for method, want := range map[string]struct {
+		name     string
+		priority float64
+	}{
+		"POST": {"mx1.example.com", 5},
+		"PUT":  {"mx2.example.com", 20},
+	} {
+		reqs := f.requestsFor(method)
+		if len(reqs) != 1 {
+			t.Fatalf("%s: got %d requests, want 1", method, len(reqs))
+		}
+		body := decodeBody(t, reqs[0])
+		if body["name"] != want.name || body["priority"] != want.priority || body["content"] != "mail.example.net" {
+			t.Errorf("%s body = %s, want %s with priority %v", method, reqs[0].Body, want.name, want.priority)
+		}
+	}
+}
+
+func TestSRVPayloadData(t *testing.T) {
+	record := Record{Name: "_sip._tcp.example.com", Type: "SRV", Priority: 10, Weight: 5, Port: 5060, TTL: 300, Comment: "sip"}
+	data, err := json.Marshal(buildPayload(record, "sip.example.net"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["content"]; ok {
+		t.Errorf("SRV payload carries content: %s", data)
+	}
+	want := map[string]any{"service": "_sip", "proto": "_tcp", "name": "example.com", "priority": 10.0, "weight": 5.0, "port": 5060.0, "target": "sip.example.net"}
+	srv, ok := got["data"].(map[string]any)
+	if !ok {
+		t.Fatalf("SRV payload has no data object: %s", data)
+	}
+	for key, value := range want {
+		if srv[key] != value {
+			t.Errorf("data.%s = %v, want %v", key, srv[key], value)
+		}
+	}
+	if got["type"] != "SRV" || got["name"] != "_sip._tcp.example.com" || got["ttl"] != 300.0 || got["comment"] != "sip" {
+		t.Errorf("SRV payload = %s", data)
+	}
+}
+
+func TestCreatePermissionDenied(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		status int
+		code   int
+		denied bool
+	}{
+		{"authentication error", http.StatusForbidden, cfCodeAuthentication, true},
+		{"unauthorized", http.StatusForbidden, cfCodeUnauthorized, true},
+		{"other 403 code", http.StatusForbidden, 1004, false},
+		{"bad request", http.StatusBadRequest, cfCodeUnauthorized, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+				if r.Method != "POST" {
+					return false
+				}
+				writeError(w, c.status, c.code, "Refused")
+				return true
+			}
+			u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", Records: []Record{{Name: "home.example.com"}}}, f)
+			result, _ := u.Run(context.Background())
+			rr := result.Records[0]
+			if rr.Action != ActionFailed || rr.Err == nil {
+				t.Fatalf("action %q, error %v; want a failure", rr.Action, rr.Err)
+			}
+			if got := isPermissionDenied(rr.Err); got != c.denied {
+				t.Errorf("isPermissionDenied(%v) = %t, want %t", rr.Err, got, c.denied)
+			}
+			if hinted := strings.Contains(rr.Err.Error(), "token lacks DNS:Edit"); hinted != c.denied {
+				t.Errorf("error %q: permission hint %t, want %t", rr.Err, hinted, c.denied)
+			}
+		})
+	}
+}
+
+func TestListDNSRecordsPagination(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	for i := range 250 {
+		f.addRecord("zone-example.com", DNSRecord{Name: fmt.Sprintf("h%d.example.com", i), Type: "A", Content: testIP})
+	}
+	u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", Records: []Record{{Name: "home.example.com"}}}, f)
+
+	records, err := u.cf[""].listDNSRecords(context.Background(), "zone-example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 250 || records[0].Name != "h0.example.com" || records[249].Name != "h249.example.com" {
+		t.Fatalf("listed %d records, want all 250 in order", len(records))
+	}
+	var pages []string
+	for _, req := range f.requestsFor("GET") {
+		pages = append(pages, req.Query.Get("page"))
+	}
+	if !slices.Equal(pages, []string{"1", "2", "3"}) {
+		t.Errorf("requested pages %q, want 1, 2 and 3", pages)
+	}
+}
+
+func TestListDNSRecordsWithoutResultInfo(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		n := map[int]int{1: 100, 2: 30}[page]
+		records := make([]DNSRecord, n)
+		for i := range records {
+			records[i] = DNSRecord{ID: fmt.Sprintf("p%d-%d", page, i), Type: "A"}
+		}
+		writeResult(w, records, nil)
+		return true
+	}
+	u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", Records: []Record{{Name: "home.example.com"}}}, f)
+
+	records, err := u.cf[""].listDNSRecords(context.Background(), "zone-example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 130 {
+		t.Errorf("listed %d records, want 130 from a full page and a short one", len(records))
+	}
+	if got := len(f.requestsFor("GET")); got != 2 {
+		t.Errorf("sent %d list requests, want 2", got)
+	}
+}
+
+func TestGetRecordDataNearMisses(t *testing.T) {
+	nearMisses := []DNSRecord{
+		{ID: "sub", Name: "x.api.example.com", Type: "A", Content: "192.0.2.1"},
+		{ID: "prefix", Name: "xapi.example.com", Type: "A", Content: "192.0.2.2"},
+		{ID: "nested", Name: "api.example.com.example.com", Type: "A", Content: "192.0.2.3"},
+		{ID: "other-type", Name: "api.example.com", Type: "AAAA", Content: "2001:db8::1"},
+	}
+	for _, c := range []struct {
+		name   string
+		result []DNSRecord
+		want   string
+	}{
+		{"only near misses", nearMisses, ""},
+		{"exact after near misses", append(slices.Clone(nearMisses), DNSRecord{ID: "exact", Name: "API.example.com.", Type: "A", Content: "192.0.2.9"}), "exact"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			// The fake answers this lookup as a careless API would,
+			// without filtering on name or type.
+			f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+				if r.Method != "GET" || r.URL.Query().Get("name") == "" {
+					return false
+				}
+				writeResult(w, c.result, nil)
+				return true
+			}
+			u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", Records: []Record{{Name: "api.example.com"}}}, f)
+
+			got, err := u.cf[""].getRecordData(context.Background(), "zone-example.com", "api.example.com", "A")
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case c.want == "" && got != nil:
+				t.Errorf("picked %s (%s), want no record", got.ID, got.Name)
+			case c.want != "" && (got == nil || got.ID != c.want):
+				t.Errorf("picked %+v, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestServerErrorBodyCap(t *testing.T) {
+	page := "<html><head><title>502 Bad Gateway</title></head><body>" + strings.Repeat("<p>cloudflare</p>", 1000) + "</body></html>"
+	f := newFakeCloudflare(t, "example.com")
+	f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		if r.URL.Path != "/zones" {
+			return false
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, page)
+		return true
+	}
+	u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", Records: []Record{{Name: "home.example.com"}}}, f)
+
+	_, err := u.cf[""].getZoneID(context.Background(), "example.com")
+	var serverErr *ServerError
+	if !errors.As(err, &serverErr) {
+		t.Fatalf("error %v, want a ServerError", err)
+	}
+	if serverErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("status %d, want 502", serverErr.StatusCode)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Error("ServerError does not match APIError")
+	}
+	body := serverErr.Body
+	if want := page[:maxErrorBody] + "... (truncated)"; body != want {
+		t.Errorf("kept %d bytes of the body, want the first %d and a truncation mark", len(body), maxErrorBody)
+	}
+	if !isOutage(err) {
+		t.Error("isOutage = false for a 502")
+	}
+}
+
+// accountZones replaces the zones of f with example.com in each of the
+// accounts named, with ID "zone-" followed by the account ID.
+func accountZones(f *fakeCloudflare, accounts ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.zones = nil
+	for _, account := range accounts {
+		zone := Zone{ID: "zone-" + account, Name: "example.com"}
+		zone.Account.ID, zone.Account.Name = account, "Account "+account
+		f.zones = append(f.zones, zone)
+	}
+}
+
+func TestGetZoneIDAccount(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		accountID string
+		// unfiltered makes the fake ignore the account.id filter, as an
+		// API that does not honor it.
+		unfiltered bool
+		want       string
+		wantErr    string
+	}{
+		{"ambiguous without an account", "", false, "", "zone name is ambiguous, the token sees it in 2 accounts: acc-a (Account acc-a), acc-b (Account acc-b); set an account ID"},
+		{"account filter", "acc-b", false, "zone-acc-b", ""},
+		{"account filter ignored by the API", "acc-b", true, "zone-acc-b", ""},
+		{"unknown account", "acc-c", false, "", "zone not found"},
+		{"unknown account unfiltered", "acc-c", true, "", "zone not found"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t)
+			accountZones(f, "acc-a", "acc-b")
+			if c.unfiltered {
+				f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+					if r.URL.Path != "/zones" {
+						return false
+					}
+					f.mu.Lock()
+					defer f.mu.Unlock()
+					writeResult(w, f.zones, nil)
+					return true
+				}
+			}
+			u := newTestUpdater(t, Config{
+				ZoneName:  "example.com",
+				APIToken:  "token",
+				AccountID: c.accountID,
+				Content:   "198.51.100.1",
+				Records:   []Record{{Name: "home.example.com"}},
+			}, f)
+
+			got, err := u.cf[""].getZoneID(context.Background(), "Example.com")
+			if c.wantErr != "" {
+				if err == nil || err.Error() != c.wantErr {
+					t.Errorf("getZoneID = %q, %v; want error %q", got, err, c.wantErr)
+				}
+			} else if err != nil || got != c.want {
+				t.Errorf("getZoneID = %q, %v; want %s", got, err, c.want)
+			}
+
+			lookups := f.requestsFor("GET")
+			if len(lookups) != 1 || lookups[0].Query.Get("name") != "example.com" || lookups[0].Query.Get("account.id") != c.accountID {
+				t.Errorf("zone lookups %+v, want one for example.com in account %q", lookups, c.accountID)
+			}
+		})
+	}
+}
+
+func TestRunZoneSelect(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		policy    ZoneSelect
+		accountID string
+		// zone is where the record is written, "" when the lookup fails
+		// with wantErr.
+		zone    string
+		wantErr string
+	}{
+		{"error", ZoneSelectError, "", "", "zone name is ambiguous, the token sees it in 3 accounts"},
+		// A zone without account details may be the account's too.
+		{"error with an account", ZoneSelectError, "acc-b", "", "zone name is ambiguous, the token sees it in 2 accounts"},
+		{"first", ZoneSelectFirst, "", "zone-acc-a", ""},
+		{"first with an account", ZoneSelectFirst, "acc-b", "zone-acc-b", ""},
+		{"account", ZoneSelectAccount, "acc-b", "zone-acc-b", ""},
+		{"account not seen", ZoneSelectAccount, "acc-c", "", "zone not found in account acc-c"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t)
+			accountZones(f, "acc-a", "acc-b", "")
+			// The API returns every zone of the name, whatever the account
+			// filter, so the policy alone decides.
+			f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+				if r.URL.Path != "/zones" {
+					return false
+				}
+				f.mu.Lock()
+				defer f.mu.Unlock()
+				writeResult(w, f.zones, nil)
+				return true
+			}
+			u := newTestUpdater(t, Config{
+				ZoneName:   "example.com",
+				APIToken:   "token",
+				AccountID:  c.accountID,
+				ZoneSelect: c.policy,
+				Content:    "198.51.100.1",
+				Records:    []Record{{Name: "home.example.com"}},
+			}, f)
+
+			result, err := u.Run(context.Background())
+			if c.wantErr != "" {
+				if err == nil || result.Records[0].Err == nil || !strings.Contains(result.Records[0].Err.Error(), c.wantErr) {
+					t.Errorf("Run = %v (%v), want a record error containing %q", err, result.Records[0].Err, c.wantErr)
+				}
+				if posts := f.requestsFor("POST"); len(posts) != 0 {
+					t.Errorf("sent %d creates, want none", len(posts))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			posts := f.requestsFor("POST")
+			if len(posts) != 1 || posts[0].Path != "/zones/"+c.zone+"/dns_records" {
+				t.Errorf("creates %+v, want one in %s", posts, c.zone)
+			}
+		})
+	}
+}
+
+func TestZoneSelectAccountRequiresAccountID(t *testing.T) {
+	_, err := New(Config{
+		ZoneName:   "example.com",
+		APIToken:   "token",
+		ZoneSelect: ZoneSelectAccount,
+		Content:    "198.51.100.1",
+		Records:    []Record{{Name: "home.example.com"}},
+		Logger:     testLogger(),
+	})
+	if err == nil || !strings.Contains(err.Error(), "requires an account ID") {
+		t.Errorf("New = %v, want the missing account ID error", err)
+	}
+}
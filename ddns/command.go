@@ -0,0 +1,50 @@
+package ddns
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// commandProvider is the IP provider entry that runs Config.IPCommand.
+const commandProvider = "command"
+
+// commandIP runs Config.IPCommand with sh and returns the address it prints.
+// The command learns the family wanted from DDNS_IP_FAMILY ("IPv4" or
+// "IPv6") and is killed after Config.IPCommandTimeout.
+func (u *Updater) commandIP(ctx context.Context, family ipFamily) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.cfg.IPCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", u.cfg.IPCommand)
+	cmd.Env = append(os.Environ(), "DDNS_IP_FAMILY="+string(family))
+	// Children of the shell may hold stdout open after it is killed; stop
+	// waiting for them shortly after.
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("IP command timed out after %s", u.cfg.IPCommandTimeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("IP command failed: %w: %s", err, truncate(msg, 256))
+		}
+		return "", fmt.Errorf("IP command failed: %w", err)
+	}
+
+	ip, err := parseIP(stdout.String())
+	if err != nil {
+		return "", err
+	}
+	if got := familyOf(net.ParseIP(ip)); got != family {
+		return "", fmt.Errorf("returned %s address %s, want %s", got, ip, family)
+	}
+	return ip, nil
+}
@@ -0,0 +1,76 @@
+package ddns
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandIP(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run IP commands with")
+	}
+	for _, c := range []struct {
+		name    string
+		command string
+		family  ipFamily
+		want    string
+		wantErr string
+	}{
+		{"address", "echo 198.51.100.7", ipv4, "198.51.100.7", ""},
+		{"family from the environment", `if [ "$DDNS_IP_FAMILY" = IPv6 ]; then echo 2001:db8::7; else echo 198.51.100.7; fi`, ipv6, "2001:db8::7", ""},
+		{"surrounding whitespace", "printf '  198.51.100.7\\n\\n'", ipv4, "198.51.100.7", ""},
+		{"non-zero exit", "echo no route >&2; exit 3", ipv4, "", "IP command failed: exit status 3: no route"},
+		{"non-zero exit without output", "exit 1", ipv4, "", "IP command failed: exit status 1"},
+		{"garbage output", "echo not-an-address", ipv4, "", "not-an-address"},
+		{"wrong family", "echo 2001:db8::7", ipv4, "", "returned IPv6 address 2001:db8::7, want IPv4"},
+		{"timeout", "sleep 5", ipv4, "", "IP command timed out after 100ms"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			u := &Updater{cfg: Config{IPCommand: c.command, IPCommandTimeout: 100 * time.Millisecond}}
+			got, err := u.commandIP(context.Background(), c.family)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Fatalf("commandIP = %q, %v; want an error containing %q", got, err, c.wantErr)
+				}
+				return
+			}
+			if err != nil || got != c.want {
+				t.Errorf("commandIP = %q, %v; want %q", got, err, c.want)
+			}
+		})
+	}
+}
+
+func TestRunIPCommandFallsThrough(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run IP commands with")
+	}
+	for _, c := range []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{"command wins", "echo 198.51.100.7", "198.51.100.7"},
+		{"failing command", "exit 1", testIP},
+		{"garbage output", "echo garbage", testIP},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+			u := newTestUpdater(t, Config{
+				ZoneName:    "example.com",
+				APIToken:    "token",
+				IPCommand:   c.command,
+				IPProviders: []string{f.srv.URL + "/ip"},
+				Records:     []Record{{Name: "home.example.com"}},
+			}, f)
+			result := run(t, u)
+			if got := result.Records[0].Content; got != c.want {
+				t.Errorf("content %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package ddns
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	modulePath         = "github.com/casantosmu/ddns-updater"
+	commentStampPrefix = "[ddns-updater "
+)
+
+// toolVersion is the module version this binary was built from, "devel"
+// for builds outside a tagged module.
+var toolVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	version := info.Main.Version
+	if info.Main.Path != modulePath {
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+			}
+		}
+	}
+	if version == "" || version == "(devel)" {
+		return "devel"
+	}
+	return version
+})
+
+// stampComment appends the tool version and t to comment, as in
+// "home router [ddns-updater v1.4.0 2026-01-02T15:04:05Z]".
+func stampComment(comment string, t time.Time) string {
+	stamp := fmt.Sprintf("%s%s %s]", commentStampPrefix, toolVersion(), t.UTC().Format(time.RFC3339))
+	if comment == "" {
+		return stamp
+	}
+	return comment + " " + stamp
+}
+
+// stripCommentStamp removes the suffix added by stampComment, so a stamped
+// comment compares equal to the configured one.
+func stripCommentStamp(comment string) string {
+	i := strings.LastIndex(comment, commentStampPrefix)
+	if i < 0 || !strings.HasSuffix(comment, "]") {
+		return comment
+	}
+	return strings.TrimRight(comment[:i], " ")
+}
+
+// stampRecord stamps the comment of a record about to be written when
+// Config.CommentTimestamp is set.
+func (u *Updater) stampRecord(record Record) Record {
+	if u.cfg.CommentTimestamp {
+		record.Comment = stampComment(record.Comment, time.Now())
+	}
+	return record
+}
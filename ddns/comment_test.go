@@ -0,0 +1,61 @@
+package ddns
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestStampComment(t *testing.T) {
+	at := time.Date(2026, 1, 2, 16, 4, 5, 0, time.FixedZone("CET", 3600))
+	want := "[ddns-updater " + toolVersion() + " 2026-01-02T15:04:05Z]"
+	if got := stampComment("", at); got != want {
+		t.Errorf("stampComment(\"\") = %q, want %q", got, want)
+	}
+	if got := stampComment("home router", at); got != "home router "+want {
+		t.Errorf("stampComment(home router) = %q, want %q", got, "home router "+want)
+	}
+	if !regexp.MustCompile(`^\[ddns-updater \S+ \d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ\]$`).MatchString(want) {
+		t.Errorf("stamp %q does not match the documented format", want)
+	}
+}
+
+func TestStripCommentStamp(t *testing.T) {
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, c := range []struct {
+		comment, want string
+	}{
+		{stampComment("home router", at), "home router"},
+		{stampComment("", at), ""},
+		{"home router [ddns-updater v1.4.0 2025-12-31T00:00:00Z]", "home router"},
+		{"home router", "home router"},
+		{"home [router]", "home [router]"},
+		{"[ddns-updater v1.4.0 2025-12-31T00:00:00Z] edited", "[ddns-updater v1.4.0 2025-12-31T00:00:00Z] edited"},
+	} {
+		if got := stripCommentStamp(c.comment); got != c.want {
+			t.Errorf("stripCommentStamp(%q) = %q, want %q", c.comment, got, c.want)
+		}
+	}
+}
+
+func TestRunCommentTimestamp(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL, Comment: "home router"})
+	u := newTestUpdater(t, Config{
+		ZoneName:         "example.com",
+		APIToken:         "token",
+		CommentTimestamp: true,
+		Records:          []Record{{Name: "home.example.com", Comment: "home router"}},
+	}, f)
+
+	run(t, u)
+	stamped := regexp.MustCompile(`^home router \[ddns-updater \S+ \d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ\]$`)
+	if got := f.recordsOf("zone-example.com")[0].Comment; !stamped.MatchString(got) {
+		t.Errorf("comment %q, want the configured one stamped", got)
+	}
+
+	// The stamp alone does not make the record differ on the next run.
+	if result := run(t, u); result.Records[0].Action != ActionUnchanged {
+		t.Errorf("second run: action %q, want %q", result.Records[0].Action, ActionUnchanged)
+	}
+}
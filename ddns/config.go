@@ -0,0 +1,887 @@
+package ddns
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// AutoTTL asks Cloudflare to pick the TTL automatically.
+	AutoTTL = 1
+
+	// DefaultIPProvider and DefaultIPv6Provider are used for A and AAAA
+	// records when no IP providers are configured.
+	DefaultIPProvider   = "https://api.ipify.org"
+	DefaultIPv6Provider = "https://api6.ipify.org"
+
+	// DefaultHTTPTimeout applies to IP provider and Cloudflare requests
+	// when no explicit timeout is configured.
+	DefaultHTTPTimeout = 10 * time.Second
+
+	// DefaultMaxRecords caps the number of records a single run may manage.
+	DefaultMaxRecords = 50
+)
+
+// LockedPolicy decides what happens to a record that needs an update but
+// is locked on Cloudflare's side and cannot be edited.
+type LockedPolicy string
+
+const (
+	// LockedSkip warns and reports the record as skipped. It is the default.
+	LockedSkip LockedPolicy = "skip"
+	// LockedError reports the record as failed, failing the run.
+	LockedError LockedPolicy = "error"
+)
+
+// MissingPolicy decides what happens to a configured record that does not
+// exist on the DNS host, for instance after being deleted out of band.
+type MissingPolicy string
+
+const (
+	// MissingCreate creates the record. It is the default.
+	MissingCreate MissingPolicy = "create"
+	// MissingSkip warns and reports the record as skipped.
+	MissingSkip MissingPolicy = "skip"
+	// MissingError reports the record as failed, failing the run.
+	MissingError MissingPolicy = "error"
+)
+
+// PlaceholderPolicy decides what happens to a record whose current content
+// looks like a Cloudflare placeholder rather than a real origin address.
+type PlaceholderPolicy string
+
+const (
+	// PlaceholderSkip warns and reports the record as skipped, leaving it for
+	// a manual look. It is the default.
+	PlaceholderSkip PlaceholderPolicy = "skip"
+	// PlaceholderUpdate overwrites the placeholder with the desired content.
+	PlaceholderUpdate PlaceholderPolicy = "update"
+)
+
+// ZoneSelect decides which zone a lookup resolves to when the token sees
+// the zone name in more than one account.
+type ZoneSelect string
+
+const (
+	// ZoneSelectError fails the lookup and lists the accounts. It is the
+	// default.
+	ZoneSelectError ZoneSelect = "error"
+	// ZoneSelectFirst uses the first zone Cloudflare returns and warns.
+	ZoneSelectFirst ZoneSelect = "first"
+	// ZoneSelectAccount only accepts the zone of the configured account ID,
+	// which every Cloudflare token then requires.
+	ZoneSelectAccount ZoneSelect = "account"
+)
+
+// MultiRecordStrategy decides how many DNS records a configured record
+// maps to.
+type MultiRecordStrategy string
+
+const (
+	// MultiRecordSingle keeps one DNS record per name and type. It is the
+	// default.
+	MultiRecordSingle MultiRecordStrategy = "single"
+	// MultiRecordAll keeps one DNS record per address under the same name,
+	// a primitive round-robin. Content is then a comma-separated list;
+	// records are added, updated or deleted until they match it.
+	MultiRecordAll MultiRecordStrategy = "all"
+)
+
+// Stack picks the address families whose records are managed.
+type Stack string
+
+const (
+	// StackV4 manages only A records.
+	StackV4 Stack = "v4"
+	// StackV6 manages only AAAA records.
+	StackV6 Stack = "v6"
+	// StackBoth manages A and AAAA records.
+	StackBoth Stack = "both"
+)
+
+// manages reports whether records of recordType are managed under s. An
+// empty Stack manages every record as configured, and record types other
+// than A and AAAA are always managed.
+func (s Stack) manages(recordType string) bool {
+	switch recordType {
+	case "A":
+		return s != StackV6
+	case "AAAA":
+		return s != StackV4
+	}
+	return true
+}
+
+// types returns the record types a record without one stands for.
+func (s Stack) types() []string {
+	switch s {
+	case StackV6:
+		return []string{"AAAA"}
+	case StackBoth:
+		return []string{"A", "AAAA"}
+	}
+	return []string{"A"}
+}
+
+// ProviderContentType is what IP provider responses are expected to hold.
+type ProviderContentType string
+
+const (
+	// ContentTypeAuto decodes responses that are JSON with
+	// Config.IPProviderJSONPath and reads the rest as text. It is the
+	// default.
+	ContentTypeAuto ProviderContentType = "auto"
+	// ContentTypeText reads every response as text and rejects JSON ones.
+	ContentTypeText ProviderContentType = "text"
+	// ContentTypeJSON decodes every response with Config.IPProviderJSONPath,
+	// which it then requires, and rejects responses that are not JSON.
+	ContentTypeJSON ProviderContentType = "json"
+)
+
+// Config describes the records to keep up to date and how to reach them.
+type Config struct {
+	// ZoneName and APIToken apply to records that do not name their own
+	// zone or credential.
+	ZoneName string
+	APIToken string
+	// AccountID, when set, limits APIToken's zone lookups to one Cloudflare
+	// account, for account-scoped tokens that see the same zone name in
+	// several accounts.
+	AccountID string
+	// ZoneSelect resolves zone names seen in several accounts. Defaults to
+	// ZoneSelectError.
+	ZoneSelect ZoneSelect
+	Records    []Record
+
+	// Credentials are named credentials that records can refer to, so one
+	// run can touch zones in several accounts and DNS hosts.
+	Credentials map[string]Credential
+
+	// RecordNamePrefix and RecordNameSuffix are added to the first label of
+	// every record name, after the _service._proto labels of SRV records, so
+	// one config serves several environments: with prefix "dev-",
+	// home.example.com becomes dev-home.example.com. The result must still
+	// be in the record's zone.
+	RecordNamePrefix string
+	RecordNameSuffix string
+
+	// MaxRecords is a safety limit on len(Records), guarding against a
+	// mis-split record list. Defaults to DefaultMaxRecords.
+	MaxRecords int
+
+	// ConfirmApexProxy allows proxied records at the zone apex, which are
+	// otherwise refused since the proxy only carries HTTP(S) and can break
+	// mail and other services on the apex.
+	ConfirmApexProxy bool
+
+	// Content, when set, is written to every record verbatim and public IP
+	// detection is skipped. It is required for record types other than A
+	// and AAAA.
+	Content string
+
+	// OverrideIPs, when set, replace public IP detection: A records get the
+	// IPv4 address and AAAA records the IPv6 one, at most one of each, and
+	// no IP provider is queried. They also win over Failover. Every record
+	// must be an A or AAAA record with an address of its family here, and
+	// Content must be empty.
+	OverrideIPs []string
+
+	// IPProviders are queried in order until one returns a valid address.
+	// Entries are URLs, the aliases "ipify" and "cloudflare", which pick
+	// the IPv4 or IPv6 endpoint as needed, "interface:<name>" to read the
+	// address of a local network interface, or "command" to run IPCommand.
+	// Providers known to serve only the other address family are skipped.
+	// Defaults to DefaultIPProvider for A records and DefaultIPv6Provider
+	// for AAAA records. Must be empty when Content is set.
+	IPProviders []string
+	// IPv4Providers and IPv6Providers, when set, replace IPProviders for
+	// their address family.
+	IPv4Providers    []string
+	IPv6Providers    []string
+	ShuffleProviders bool
+	// IPCommand, when set, is a shell command printing the address of the
+	// family named by its DDNS_IP_FAMILY environment variable. It is tried
+	// before the providers unless they list "command". A non-zero exit, or
+	// running longer than IPCommandTimeout, falls through to the next
+	// provider. IPCommandTimeout defaults to DefaultHTTPTimeout.
+	IPCommand        string
+	IPCommandTimeout time.Duration
+	// IPv6Select picks among the IPv6 addresses of an interface provider.
+	// Defaults to IPv6SelectFirst.
+	IPv6Select IPv6Select
+	// Stack, when set, names the address families to manage: a record
+	// without a type becomes an A record, an AAAA record or both, and A or
+	// AAAA records of the other family are skipped without being read or
+	// written. Both addresses stay detectable, for instance by Diagnose.
+	// When empty, records are managed as configured and default to A.
+	Stack Stack
+	// IPProviderRegex, when set, extracts the address from the first capture
+	// group of its match in a provider's response instead of taking the whole
+	// body. It does not apply to Cloudflare's trace endpoints.
+	IPProviderRegex *regexp.Regexp
+	// IPProviderJSONPath, when set, is a dot path such as "$.ip" or
+	// "data.address" to the address in JSON responses. Numeric segments
+	// index arrays. Responses that are not JSON are still read as a plain
+	// address, so JSON and plain-text providers can share a chain. Mutually
+	// exclusive with IPProviderRegex.
+	IPProviderJSONPath string
+	// IPProviderContentType makes a response that is not of the expected
+	// kind an error instead of guessing. Cloudflare's trace endpoints are
+	// always read as text. Defaults to ContentTypeAuto.
+	IPProviderContentType ProviderContentType
+	CGNATCheck            bool
+	// EdgeCheck warns when a detected address is one of Cloudflare's edge
+	// addresses, as returned by an IP provider behind Cloudflare. The ranges
+	// are built in, or fetched once from EdgeRangesURL when it is set, for
+	// instance to DefaultEdgeRangesURL.
+	EdgeCheck     bool
+	EdgeRangesURL string
+
+	// RefreshAfter, when positive, rewrites unchanged records that
+	// Cloudflare reports as last modified longer ago than this, so a record
+	// that should self-heal is periodically confirmed even when it appears
+	// up to date.
+	RefreshAfter time.Duration
+
+	// CommentTimestamp appends the tool version and time to the comment of
+	// every record written, e.g. "home [ddns-updater v1.4.0
+	// 2026-01-02T15:04:05Z]". The stamp is ignored when comparing comments,
+	// so it only changes with a write that was due anyway; combine it with
+	// RefreshAfter to renew it on unchanged records. Cloudflare limits
+	// comment length, so keep the configured comment short.
+	CommentTimestamp bool
+
+	// ConfirmStable, when positive, waits this long after a detected address
+	// differs from a record's content and detects it again, only updating
+	// if it has not moved, so brief flaps at reconnect are not chased.
+	ConfirmStable time.Duration
+
+	// AllowedIPRanges, when set, restricts detected addresses to these
+	// networks. Records are skipped rather than pointed at an address
+	// outside them.
+	AllowedIPRanges []*net.IPNet
+
+	IPHTTPTimeout time.Duration
+	CFHTTPTimeout time.Duration
+	// RequestTimeout, when positive, bounds each attempt of a Cloudflare
+	// request, reading the response included, so a slow attempt is retried
+	// on schedule. CFHTTPTimeout remains a backstop.
+	RequestTimeout time.Duration
+	// CFRateLimit, when positive, paces Cloudflare requests to this many per
+	// second across all credentials, in bursts of up to that many. Retries
+	// count against it.
+	CFRateLimit float64
+
+	// Retry applies to Cloudflare calls and to rounds over IPProviders.
+	Retry RetryPolicy
+
+	// UpdateFields lists the fields that trigger an update when the existing
+	// record differs from the desired one: "content", "proxied", "ttl" and
+	// "comment". Content is always compared. Defaults to all of them.
+	UpdateFields []string
+
+	// OnLocked handles locked records. Defaults to LockedSkip.
+	OnLocked LockedPolicy
+
+	// UpdateWindow, when set, holds back creates and updates outside this
+	// daily window. Differences are still detected and reported as skipped
+	// records, with Result.DeferredUntil telling when to retry.
+	UpdateWindow *TimeWindow
+
+	// OnMissing handles records that do not exist. Defaults to
+	// MissingCreate.
+	OnMissing MissingPolicy
+
+	// OnPlaceholder handles proxied records holding a placeholder address,
+	// one in 100.64.0.0/10 or 100::/64, that differs from the desired
+	// content. Unproxied records in those ranges are updated as usual.
+	// Defaults to PlaceholderSkip.
+	OnPlaceholder PlaceholderPolicy
+
+	// TXTStrategy defaults to TXTReplace. TXTAppend and TXTRemove edit the
+	// existing content of TXT records, such as the includes of an SPF
+	// record, one term of Content at a time; they require Content and
+	// only TXT records.
+	TXTStrategy TXTStrategy
+
+	// SelectBy defaults to SelectByName. The other modes find records by
+	// comment or tags across the zone, so Record.Name becomes optional: it
+	// is only used when no record matches. They require Cloudflare DNS
+	// records, and MultiRecordAll to update more than one match.
+	// SelectByGlob instead matches Record.Name against the zone's names and
+	// updates one record per matching name, up to MaxRecords of them.
+	SelectBy SelectBy
+
+	// MultiRecordStrategy defaults to MultiRecordAll when Content lists
+	// several addresses, as for a failover pool, and to MultiRecordSingle
+	// otherwise. MultiRecordAll only supports A and AAAA records on
+	// Cloudflare, and each record only takes the addresses of its own family
+	// from Content.
+	MultiRecordStrategy MultiRecordStrategy
+
+	// StateFile, when set, keeps the content last written to each record.
+	// When Cloudflare returns a record without its content, as for proxied
+	// records read by tokens with limited permissions, the record is compared
+	// with that content instead of being rewritten on every run.
+	StateFile string
+
+	// AuditLog, when set, receives an AuditEntry as a JSON line for every
+	// create, update and delete, including those of Prune.
+	AuditLog io.Writer
+
+	// DryRun reports what a run would change without creating or updating
+	// anything. Each RecordResult carries a Diff and notifiers are not
+	// called.
+	DryRun bool
+
+	// Provider, when set, manages the records on another DNS host instead
+	// of Cloudflare. Only A and AAAA records are supported and the
+	// Cloudflare-specific options are ignored.
+	Provider Provider
+	// FallbackProvider, when set, is a second DNS host serving the same
+	// zones. A record whose primary host, Cloudflare or Provider, is down or
+	// failing on its side is synced there instead and reported with
+	// RecordResult.Fallback set. Only A and AAAA records selected by name
+	// are supported.
+	FallbackProvider Provider
+
+	// SaaS manages Cloudflare for SaaS custom hostnames instead of DNS
+	// records: each record name is a custom hostname in its zone and
+	// Content is its custom origin server. New hostnames use HTTP DCV. The
+	// API token needs the SSL and Certificates Edit permission on the zone.
+	SaaS bool
+
+	// DebugDump logs the full request and response of every failed
+	// Cloudflare call, with the API token redacted. Response bodies may
+	// contain record data, so leave it off in normal operation.
+	DebugDump bool
+
+	// Propagation, when set, waits for public resolvers to see every
+	// created or updated address record before the run finishes.
+	Propagation *PropagationCheck
+
+	// Failover, when set, points the A and AAAA records at a failover
+	// address while a primary health check fails.
+	Failover *FailoverCheck
+
+	// Notifiers are told about runs that change a record or fail.
+	// NotifyConcurrency caps how many are notified at once; zero means all
+	// of them. Each gets NotifyTimeout, which defaults to DefaultHTTPTimeout.
+	Notifiers         []Notifier
+	NotifyConcurrency int
+	NotifyTimeout     time.Duration
+	// NotifyRepeatInterval is how long a failure that repeats run after run
+	// stays quiet before it is notified again; zero means until it clears.
+	// A run that changes a record is always notified, and the first run
+	// without the failure sends a single "resolved" notification. This only
+	// spans the runs of one Updater.
+	NotifyRepeatInterval time.Duration
+
+	// Logger receives progress and diagnostics. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Credential authenticates against a Cloudflare account, or against
+// another DNS host when Provider is set.
+type Credential struct {
+	APIToken string
+	// AccountID is Config.AccountID for this credential. It does not
+	// default to Config.AccountID, since a credential is usually a separate
+	// account.
+	AccountID string
+	// Provider, when set, updates the records using this credential
+	// instead of Cloudflare. APIToken is then unused.
+	Provider Provider
+}
+
+// Record is a single DNS record managed by the updater.
+type Record struct {
+	Name string
+	// Zone defaults to Config.ZoneName.
+	Zone string
+	// Credential names an entry in Config.Credentials. When empty,
+	// Config.APIToken is used.
+	Credential string
+	// Type defaults to "A".
+	Type string
+	// Priority is used by MX and SRV records.
+	Priority int
+	// Weight and Port are used by SRV records.
+	Weight int
+	Port   int
+
+	Proxied bool
+	// TTL defaults to AutoTTL. Proxied records always use AutoTTL.
+	TTL int
+	// Comment is sent on create and update. When empty, the comment already
+	// on an existing record is preserved.
+	Comment string
+
+	// Tags are sent on create and update. When nil, the tags already on an
+	// existing record are preserved.
+	Tags []string
+}
+
+// updateFields are the fields Config.UpdateFields may name.
+var updateFields = []string{"content", "proxied", "ttl", "comment"}
+
+func (cfg *Config) setDefaults() {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.Propagation != nil {
+		check := *cfg.Propagation
+		check.setDefaults()
+		cfg.Propagation = &check
+	}
+	cfg.Retry.setDefaults()
+	if cfg.UpdateFields == nil {
+		cfg.UpdateFields = slices.Clone(updateFields)
+	}
+	if cfg.OnLocked == "" {
+		cfg.OnLocked = LockedSkip
+	}
+	if cfg.OnMissing == "" {
+		cfg.OnMissing = MissingCreate
+	}
+	if cfg.OnPlaceholder == "" {
+		cfg.OnPlaceholder = PlaceholderSkip
+	}
+	if cfg.ZoneSelect == "" {
+		cfg.ZoneSelect = ZoneSelectError
+	}
+	if cfg.TXTStrategy == "" {
+		cfg.TXTStrategy = TXTReplace
+	}
+	if cfg.SelectBy == "" {
+		cfg.SelectBy = SelectByName
+	}
+	if cfg.IPv6Select == "" {
+		cfg.IPv6Select = IPv6SelectFirst
+	}
+	if cfg.IPProviderContentType == "" {
+		cfg.IPProviderContentType = ContentTypeAuto
+	}
+	if cfg.MultiRecordStrategy == "" {
+		cfg.MultiRecordStrategy = MultiRecordSingle
+		if isAddressList(cfg.Content) {
+			cfg.MultiRecordStrategy = MultiRecordAll
+		}
+	}
+	if cfg.MaxRecords == 0 {
+		cfg.MaxRecords = DefaultMaxRecords
+	}
+	if cfg.IPHTTPTimeout == 0 {
+		cfg.IPHTTPTimeout = DefaultHTTPTimeout
+	}
+	if cfg.CFHTTPTimeout == 0 {
+		cfg.CFHTTPTimeout = DefaultHTTPTimeout
+	}
+	if cfg.IPCommandTimeout == 0 {
+		cfg.IPCommandTimeout = DefaultHTTPTimeout
+	}
+	if cfg.NotifyTimeout == 0 {
+		cfg.NotifyTimeout = DefaultHTTPTimeout
+	}
+
+	records := make([]Record, 0, len(cfg.Records))
+	for _, record := range cfg.Records {
+		types := []string{strings.ToUpper(record.Type)}
+		if types[0] == "" {
+			types = cfg.Stack.types()
+		}
+		for _, recordType := range types {
+			record := record
+			record.Type = recordType
+			if record.TTL == 0 {
+				record.TTL = AutoTTL
+			}
+			if record.Proxied && record.TTL != AutoTTL {
+				// Cloudflare forces automatic TTL on proxied records, so
+				// asking for another one would report a change on every run.
+				cfg.Logger.Warn("TTL is ignored for proxied records, using auto", "record", record.Name, "ttl", record.TTL)
+				record.TTL = AutoTTL
+			}
+			if record.Zone == "" {
+				record.Zone = cfg.ZoneName
+			}
+			record.Name = affixName(record.Name, record.Type, cfg.RecordNamePrefix, cfg.RecordNameSuffix)
+			records = append(records, record)
+		}
+	}
+	cfg.Records = records
+}
+
+func (cfg *Config) validate() error {
+	for name, cred := range cfg.Credentials {
+		if cred.Provider == nil && cred.APIToken == "" {
+			return fmt.Errorf("credential %s has no API token", name)
+		}
+		if cred.Provider == nil && cred.AccountID == "" && cfg.ZoneSelect == ZoneSelectAccount {
+			return fmt.Errorf("credential %s: zone selection %s requires an account ID", name, ZoneSelectAccount)
+		}
+	}
+	switch cfg.ZoneSelect {
+	case ZoneSelectError, ZoneSelectFirst:
+	case ZoneSelectAccount:
+		if cfg.APIToken != "" && cfg.AccountID == "" {
+			return fmt.Errorf("zone selection %s requires an account ID", ZoneSelectAccount)
+		}
+	default:
+		return fmt.Errorf("invalid zone selection %q: must be %s, %s or %s", cfg.ZoneSelect, ZoneSelectError, ZoneSelectFirst, ZoneSelectAccount)
+	}
+	if len(cfg.Records) == 0 {
+		return errors.New("at least one record is required")
+	}
+	if err := cfg.checkRecordLimit(len(cfg.Records)); err != nil {
+		return err
+	}
+	if err := cfg.Retry.validate(); err != nil {
+		return err
+	}
+	for _, field := range cfg.UpdateFields {
+		if !slices.Contains(updateFields, field) {
+			return fmt.Errorf("invalid update field %q: must be one of %s", field, strings.Join(updateFields, ", "))
+		}
+	}
+	if cfg.OnLocked != LockedSkip && cfg.OnLocked != LockedError {
+		return fmt.Errorf("invalid locked record policy %q: must be %s or %s", cfg.OnLocked, LockedSkip, LockedError)
+	}
+	switch cfg.OnMissing {
+	case MissingCreate, MissingSkip, MissingError:
+	default:
+		return fmt.Errorf("invalid missing record policy %q: must be %s, %s or %s", cfg.OnMissing, MissingCreate, MissingSkip, MissingError)
+	}
+	if cfg.OnPlaceholder != PlaceholderSkip && cfg.OnPlaceholder != PlaceholderUpdate {
+		return fmt.Errorf("invalid placeholder policy %q: must be %s or %s", cfg.OnPlaceholder, PlaceholderSkip, PlaceholderUpdate)
+	}
+	switch cfg.TXTStrategy {
+	case TXTReplace:
+	case TXTAppend, TXTRemove:
+		if err := cfg.validateTXTStrategy(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid TXT strategy %q: must be %s, %s or %s", cfg.TXTStrategy, TXTReplace, TXTAppend, TXTRemove)
+	}
+	switch cfg.SelectBy {
+	case SelectByName, SelectByComment, SelectByTag, SelectByGlob:
+	default:
+		return fmt.Errorf("invalid record selection %q: must be %s, %s, %s or %s", cfg.SelectBy, SelectByName, SelectByComment, SelectByTag, SelectByGlob)
+	}
+	if cfg.SelectBy != SelectByName && cfg.SaaS {
+		return fmt.Errorf("selecting records by %s does not apply to custom hostnames", cfg.SelectBy)
+	}
+	switch cfg.Stack {
+	case "", StackV4, StackV6, StackBoth:
+	default:
+		return fmt.Errorf("invalid stack %q: must be %s, %s or %s", cfg.Stack, StackV4, StackV6, StackBoth)
+	}
+	switch cfg.IPv6Select {
+	case IPv6SelectFirst, IPv6SelectPermanent, IPv6SelectTemporary:
+	default:
+		return fmt.Errorf("invalid IPv6 selection %q: must be %s, %s or %s", cfg.IPv6Select, IPv6SelectFirst, IPv6SelectPermanent, IPv6SelectTemporary)
+	}
+	switch cfg.MultiRecordStrategy {
+	case MultiRecordSingle:
+	case MultiRecordAll:
+		if err := cfg.validateMultiRecord(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid multi-record strategy %q: must be %s or %s", cfg.MultiRecordStrategy, MultiRecordSingle, MultiRecordAll)
+	}
+	if cfg.FallbackProvider != nil {
+		if err := cfg.validateFallback(); err != nil {
+			return err
+		}
+	}
+	if cfg.Failover != nil {
+		if err := cfg.Failover.validate(); err != nil {
+			return err
+		}
+		for _, record := range cfg.Records {
+			if record.Type != "A" && record.Type != "AAAA" {
+				return fmt.Errorf("record %s: failover only applies to A and AAAA records", record.Name)
+			}
+		}
+	}
+	if cfg.NotifyRepeatInterval < 0 {
+		return fmt.Errorf("invalid notify repeat interval %s: must not be negative", cfg.NotifyRepeatInterval)
+	}
+	if cfg.NotifyConcurrency < 0 {
+		return fmt.Errorf("invalid notify concurrency %d: must not be negative", cfg.NotifyConcurrency)
+	}
+	if cfg.RefreshAfter < 0 {
+		return errors.New("refresh after duration must not be negative")
+	}
+	if cfg.ConfirmStable < 0 {
+		return errors.New("confirm stable duration must not be negative")
+	}
+	if cfg.CFRateLimit < 0 {
+		return fmt.Errorf("invalid Cloudflare rate limit %g: must not be negative", cfg.CFRateLimit)
+	}
+	if cfg.IPProviderRegex != nil && cfg.IPProviderRegex.NumSubexp() == 0 {
+		return fmt.Errorf("IP provider regex %q has no capture group", cfg.IPProviderRegex)
+	}
+	if cfg.IPProviderJSONPath != "" {
+		if cfg.IPProviderRegex != nil {
+			return errors.New("IP provider regex and JSON path are mutually exclusive")
+		}
+		if _, err := splitJSONPath(cfg.IPProviderJSONPath); err != nil {
+			return err
+		}
+	}
+	switch cfg.IPProviderContentType {
+	case ContentTypeAuto:
+	case ContentTypeText:
+		if cfg.IPProviderJSONPath != "" {
+			return errors.New("IP provider JSON path cannot be used with content type text")
+		}
+	case ContentTypeJSON:
+		if cfg.IPProviderJSONPath == "" {
+			return errors.New("IP provider content type json requires a JSON path")
+		}
+	default:
+		return fmt.Errorf("invalid IP provider content type %q: must be %s, %s or %s", cfg.IPProviderContentType, ContentTypeAuto, ContentTypeText, ContentTypeJSON)
+	}
+	if cfg.Content != "" && len(cfg.IPProviders)+len(cfg.IPv4Providers)+len(cfg.IPv6Providers) > 0 {
+		return errors.New("record content and IP providers are mutually exclusive")
+	}
+	if cfg.Content != "" && cfg.IPCommand != "" {
+		return errors.New("record content and IP command are mutually exclusive")
+	}
+	if cfg.IPCommand == "" && slices.Contains(slices.Concat(cfg.IPProviders, cfg.IPv4Providers, cfg.IPv6Providers), commandProvider) {
+		return fmt.Errorf("IP provider %q requires an IP command", commandProvider)
+	}
+	if len(cfg.OverrideIPs) > 0 {
+		if err := cfg.validateOverride(); err != nil {
+			return err
+		}
+	}
+	if cfg.SaaS && cfg.Content == "" {
+		return errors.New("custom hostnames require record content: the custom origin server")
+	}
+
+	for _, record := range cfg.Records {
+		if err := cfg.validateRecord(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// providerFor returns the provider that updates record: its credential's,
+// else Config.Provider. It returns nil for Cloudflare.
+func (cfg *Config) providerFor(record Record) Provider {
+	if cred, ok := cfg.Credentials[record.Credential]; ok && record.Credential != "" && cred.Provider != nil {
+		return cred.Provider
+	}
+	return cfg.Provider
+}
+
+func (cfg *Config) validateOverride() error {
+	if cfg.Content != "" {
+		return errors.New("record content and override IPs are mutually exclusive")
+	}
+	if err := validateFamilyAddresses("override", cfg.OverrideIPs); err != nil {
+		return err
+	}
+	for _, record := range cfg.Records {
+		if !isIPType(record.Type) {
+			return fmt.Errorf("record %s: override IPs only apply to A and AAAA records", record.Name)
+		}
+		if family := familyForType(record.Type); familyAddress(cfg.OverrideIPs, family) == "" {
+			return fmt.Errorf("record %s: override IPs hold no %s address for its %s type", record.Name, family, record.Type)
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) validateFallback() error {
+	name := cfg.FallbackProvider.Name()
+	switch {
+	case cfg.SaaS:
+		return fmt.Errorf("fallback provider %s does not apply to custom hostnames", name)
+	case cfg.MultiRecordStrategy == MultiRecordAll:
+		return fmt.Errorf("fallback provider %s does not support multi-record strategy %s", name, MultiRecordAll)
+	case cfg.SelectBy != SelectByName:
+		return fmt.Errorf("fallback provider %s does not support selecting records by %s", name, cfg.SelectBy)
+	}
+	for _, record := range cfg.Records {
+		if !isIPType(record.Type) {
+			return fmt.Errorf("record %s: fallback provider %s only supports A and AAAA records", record.Name, name)
+		}
+		if p := cfg.providerFor(record); p != nil && p.Name() == name {
+			return fmt.Errorf("record %s: fallback provider %s is already its provider", record.Name, name)
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) validateMultiRecord() error {
+	if cfg.SaaS {
+		return fmt.Errorf("multi-record strategy %s does not apply to custom hostnames", MultiRecordAll)
+	}
+	for _, value := range splitContent(cfg.Content) {
+		if net.ParseIP(value) == nil {
+			return fmt.Errorf("invalid record content %q: multi-record strategy %s requires IP addresses", value, MultiRecordAll)
+		}
+	}
+	for _, record := range cfg.Records {
+		if p := cfg.providerFor(record); p != nil {
+			return fmt.Errorf("multi-record strategy %s is not supported by provider %s", MultiRecordAll, p.Name())
+		}
+		if !isIPType(record.Type) {
+			return fmt.Errorf("record %s: multi-record strategy %s only supports A and AAAA records", record.Name, MultiRecordAll)
+		}
+	}
+	return nil
+}
+
+func (cfg *Config) checkRecordLimit(count int) error {
+	if count > cfg.MaxRecords {
+		return fmt.Errorf("refusing to manage %d records: exceeds the record limit of %d", count, cfg.MaxRecords)
+	}
+	return nil
+}
+
+func (cfg *Config) validateRecord(record Record) error {
+	if err := cfg.validateSelector(record); err != nil {
+		return err
+	}
+	if record.Zone == "" {
+		return fmt.Errorf("record %s: zone name is required", record.Name)
+	}
+	if record.Name != "" && (cfg.RecordNamePrefix != "" || cfg.RecordNameSuffix != "") {
+		if _, err := relativeName(record.Name, record.Zone); err != nil {
+			return err
+		}
+	}
+	if p := cfg.providerFor(record); p != nil {
+		if cfg.SaaS {
+			return fmt.Errorf("custom hostnames are not supported by provider %s", p.Name())
+		}
+		if !isIPType(record.Type) {
+			return fmt.Errorf("record %s: provider %s only supports A and AAAA records", record.Name, p.Name())
+		}
+		if record.Credential != "" {
+			if _, ok := cfg.Credentials[record.Credential]; !ok {
+				return fmt.Errorf("record %s: unknown credential %q", record.Name, record.Credential)
+			}
+		}
+	} else if record.Credential == "" {
+		if cfg.APIToken == "" {
+			return fmt.Errorf("record %s: API token is required", record.Name)
+		}
+	} else if _, ok := cfg.Credentials[record.Credential]; !ok {
+		return fmt.Errorf("record %s: unknown credential %q", record.Name, record.Credential)
+	}
+	if err := cfg.checkApexProxy(record); err != nil {
+		return err
+	}
+	if !validTTL(record.TTL) {
+		return fmt.Errorf("record %s has invalid ttl %d: must be 1 (auto) or between 60 and 86400", record.Name, record.TTL)
+	}
+	if cfg.Content == "" && !isIPType(record.Type) {
+		return fmt.Errorf("record %s: content is required for %s records", record.Name, record.Type)
+	}
+	if record.Type == "SRV" {
+		if _, _, _, err := splitSRVName(record.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkApexProxy refuses a proxied record at the zone apex unless
+// ConfirmApexProxy is set, and warns when it is.
+func (cfg *Config) checkApexProxy(record Record) error {
+	if !record.Proxied || !sameName(record.Name, record.Zone) || cfg.providerFor(record) != nil {
+		return nil
+	}
+	if !cfg.ConfirmApexProxy {
+		return fmt.Errorf("record %s is the zone apex and proxied: only HTTP(S) reaches it through Cloudflare, which can break mail and other services on the apex; confirm the apex proxy to proceed", record.Name)
+	}
+	cfg.Logger.Warn("Proxying the zone apex: only HTTP(S) reaches it through Cloudflare, mail and other services on the apex need records of their own", "record", record.Name)
+	return nil
+}
+
+// splitSRVName splits an SRV record name such as _sip._tcp.example.com into
+// its service, protocol and host parts.
+func splitSRVName(name string) (service, proto, host string, err error) {
+	parts := strings.SplitN(name, ".", 3)
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "_") || !strings.HasPrefix(parts[1], "_") {
+		return "", "", "", fmt.Errorf("invalid SRV record name %q: expected _service._proto.name", name)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// validateSelector checks that record names the records it updates: by
+// name or pattern, or by the comment or tags SelectBy matches on.
+func (cfg *Config) validateSelector(record Record) error {
+	switch cfg.SelectBy {
+	case SelectByComment:
+		if record.Comment == "" {
+			return fmt.Errorf("record %s: a comment is required to select records by comment", record.Name)
+		}
+	case SelectByTag:
+		if len(record.Tags) == 0 {
+			return fmt.Errorf("record %s: tags are required to select records by tag", record.Name)
+		}
+	case SelectByGlob:
+		if record.Name == "" {
+			return errors.New("record pattern is required")
+		}
+		if strings.Trim(record.Name, "*?.") == "" {
+			return fmt.Errorf("record pattern %q would match every record", record.Name)
+		}
+		if _, err := path.Match(record.Name, ""); err != nil {
+			return fmt.Errorf("invalid record pattern %q: %w", record.Name, err)
+		}
+	default:
+		if record.Name == "" {
+			return errors.New("record name is required")
+		}
+		return nil
+	}
+	if p := cfg.providerFor(record); p != nil {
+		return fmt.Errorf("selecting records by %s is not supported by provider %s", cfg.SelectBy, p.Name())
+	}
+	return nil
+}
+
+// affixName adds prefix and suffix to the first label of name that is not
+// an SRV _service or _proto label.
+func affixName(name, recordType, prefix, suffix string) string {
+	if name == "" || prefix == "" && suffix == "" {
+		return name
+	}
+	var head string
+	if recordType == "SRV" {
+		if service, proto, host, err := splitSRVName(name); err == nil {
+			head, name = service+"."+proto+".", host
+		}
+	}
+	label, rest, _ := strings.Cut(name, ".")
+	if rest != "" {
+		rest = "." + rest
+	}
+	return head + prefix + label + suffix + rest
+}
+
+func validTTL(ttl int) bool {
+	return ttl == AutoTTL || (ttl >= 60 && ttl <= 86400)
+}
+
+func isIPType(recordType string) bool {
+	return recordType == "A" || recordType == "AAAA"
+}
@@ -0,0 +1,119 @@
+package ddns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAffixName(t *testing.T) {
+	for _, c := range []struct {
+		name, recordType, prefix, suffix, want string
+	}{
+		{"home.example.com", "A", "dev-", "", "dev-home.example.com"},
+		{"home.example.com", "A", "", "-staging", "home-staging.example.com"},
+		{"home.example.com", "AAAA", "dev-", "-2", "dev-home-2.example.com"},
+		{"a.b.example.com", "A", "dev-", "", "dev-a.b.example.com"},
+		{"_sip._tcp.pbx.example.com", "SRV", "dev-", "", "_sip._tcp.dev-pbx.example.com"},
+		{"_sip._tcp.pbx.example.com", "TXT", "dev-", "", "dev-_sip._tcp.pbx.example.com"},
+		{"home.example.com", "A", "", "", "home.example.com"},
+		{"", "A", "dev-", "", ""},
+		{"localhost", "A", "dev-", "", "dev-localhost"},
+	} {
+		if got := affixName(c.name, c.recordType, c.prefix, c.suffix); got != c.want {
+			t.Errorf("affixName(%q, %s, %q, %q) = %q, want %q", c.name, c.recordType, c.prefix, c.suffix, got, c.want)
+		}
+	}
+}
+
+func TestRecordNameAffixesInNew(t *testing.T) {
+	u, err := New(Config{
+		ZoneName:         "example.com",
+		APIToken:         "token",
+		Content:          "198.51.100.1",
+		Logger:           testLogger(),
+		RecordNamePrefix: "dev-",
+		RecordNameSuffix: "-1",
+		Records:          []Record{{Name: "home.example.com"}, {Name: "vpn.example.org", Zone: "example.org"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"dev-home-1.example.com", "dev-vpn-1.example.org"}
+	for i, record := range u.Config().Records {
+		if record.Name != want[i] {
+			t.Errorf("record %d = %s, want %s", i, record.Name, want[i])
+		}
+	}
+
+	// Affixing the apex name leaves the zone.
+	_, err = New(Config{
+		ZoneName:         "example.com",
+		APIToken:         "token",
+		Content:          "198.51.100.1",
+		Logger:           testLogger(),
+		RecordNamePrefix: "dev-",
+		Records:          []Record{{Name: "example.com"}},
+	})
+	if err == nil {
+		t.Error("New with a prefixed apex succeeded, want the name to fall outside the zone")
+	}
+}
+
+func TestApexProxyNeedsConfirmation(t *testing.T) {
+	for _, c := range []struct {
+		name, zone, record string
+		proxied, confirm   bool
+		wantErr            bool
+	}{
+		{"apex", "example.com", "example.com", true, false, true},
+		{"mixed case apex", "example.com", "Example.COM", true, false, true},
+		{"apex with trailing dot", "example.com", "example.com.", true, false, true},
+		{"mixed case zone with trailing dot", "Example.com.", "EXAMPLE.com", true, false, true},
+		{"confirmed apex", "example.com", "Example.COM.", true, true, false},
+		{"unproxied apex", "example.com", "Example.COM.", false, false, false},
+		{"subdomain", "example.com", "home.example.com", true, false, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := Config{ConfirmApexProxy: c.confirm, Logger: testLogger()}
+			err := cfg.checkApexProxy(Record{Name: c.record, Zone: c.zone, Proxied: c.proxied})
+			if (err != nil) != c.wantErr {
+				t.Errorf("checkApexProxy = %v, want error %t", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewRefusesUnconfirmedApexProxy(t *testing.T) {
+	_, err := New(Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Content:  "198.51.100.1",
+		Logger:   testLogger(),
+		Records:  []Record{{Name: "Example.COM.", Proxied: true}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "zone apex") {
+		t.Errorf("New with an unconfirmed proxied apex: %v, want the zone apex error", err)
+	}
+}
+
+func TestNewRefusesOverrideOfAnotherFamily(t *testing.T) {
+	for _, c := range []struct {
+		recordType string
+		override   []string
+	}{
+		{"A", []string{"2001:db8::10"}},
+		{"AAAA", []string{"203.0.113.99"}},
+		{"TXT", []string{"203.0.113.99"}},
+	} {
+		_, err := New(Config{
+			ZoneName:    "example.com",
+			APIToken:    "token",
+			OverrideIPs: c.override,
+			Logger:      testLogger(),
+			Records:     []Record{{Name: "home.example.com", Type: c.recordType}},
+		})
+		if err == nil || !strings.Contains(err.Error(), "override IPs") {
+			t.Errorf("%s record with override %q: error %v, want the override refused", c.recordType, c.override, err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	desecBaseURL = "https://desec.io/api/v1"
+
+	// DeSECMinTTL is the lowest TTL deSEC accepts on most accounts. Lower
+	// TTLs, and AutoTTL, are raised to it.
+	DeSECMinTTL = 3600
+)
+
+type desecProvider struct {
+	api restClient
+}
+
+type desecRRset struct {
+	Subname string   `json:"subname"`
+	Type    string   `json:"type"`
+	Records []string `json:"records"`
+	TTL     int      `json:"ttl"`
+}
+
+// NewDeSECProvider manages RRsets on deSEC (desec.io) with an API token.
+func NewDeSECProvider(token string) (Provider, error) {
+	if token == "" {
+		return nil, errors.New("deSEC token is required")
+	}
+	return &desecProvider{api: restClient{
+		client:  &http.Client{Timeout: DefaultHTTPTimeout},
+		baseURL: desecBaseURL,
+		auth:    "Token " + token,
+	}}, nil
+}
+
+func (p *desecProvider) Name() string {
+	return "desec"
+}
+
+// rrsetPath addresses an RRset; deSEC spells the apex subname "@" in URLs.
+func (p *desecProvider) rrsetPath(record Record) (string, error) {
+	subname, err := relativeName(record.Name, record.Zone)
+	if err != nil {
+		return "", err
+	}
+	if subname == "" {
+		subname = "@"
+	}
+	return fmt.Sprintf("/domains/%s/rrsets/%s/%s/", record.Zone, subname, record.Type), nil
+}
+
+func (p *desecProvider) Get(ctx context.Context, record Record) (*RRset, error) {
+	path, err := p.rrsetPath(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var rrset desecRRset
+	err = p.api.do(ctx, "GET", path, nil, &rrset)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &RRset{Values: rrset.Records, TTL: rrset.TTL}, nil
+}
+
+func (p *desecProvider) Set(ctx context.Context, record Record, content string) error {
+	subname, err := relativeName(record.Name, record.Zone)
+	if err != nil {
+		return err
+	}
+	rrset := desecRRset{
+		Subname: subname,
+		Type:    record.Type,
+		Records: []string{content},
+		TTL:     max(record.TTL, DeSECMinTTL),
+	}
+
+	// A bulk PUT on the RRset collection creates or replaces in one call.
+	return p.api.do(ctx, "PUT", fmt.Sprintf("/domains/%s/rrsets/", record.Zone), []desecRRset{rrset}, nil)
+}
@@ -0,0 +1,76 @@
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func newTestDeSEC(t *testing.T, handler http.HandlerFunc) (*desecProvider, *providerServer) {
+	t.Helper()
+	srv := newProviderServer(t, handler)
+	p, err := NewDeSECProvider("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*desecProvider).api.baseURL = srv.URL
+	return p.(*desecProvider), srv
+}
+
+func TestDeSECGet(t *testing.T) {
+	p, srv := newTestDeSEC(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/domains/example.com/rrsets/home/A/":
+			writeJSON(w, desecRRset{Subname: "home", Type: "A", Records: []string{"192.0.2.1"}, TTL: 3600})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	ctx := context.Background()
+
+	rrset, err := p.Get(ctx, Record{Name: "home.example.com", Zone: "example.com", Type: "A"})
+	if err != nil || rrset == nil || len(rrset.Values) != 1 || rrset.Values[0] != "192.0.2.1" || rrset.TTL != 3600 {
+		t.Errorf("Get existing = %+v, %v", rrset, err)
+	}
+	rrset, err = p.Get(ctx, Record{Name: "example.com", Zone: "example.com", Type: "A"})
+	if err != nil || rrset != nil {
+		t.Errorf("Get missing = %+v, %v; want nil, nil", rrset, err)
+	}
+	if got := srv.requests[1].Path; got != "/domains/example.com/rrsets/@/A/" {
+		t.Errorf("apex path = %q, want the @ subname", got)
+	}
+	if got := srv.requests[0].Header.Get("Authorization"); got != "Token secret" {
+		t.Errorf("Authorization = %q, want Token secret", got)
+	}
+}
+
+func TestDeSECSetClampsTTL(t *testing.T) {
+	for _, c := range []struct {
+		ttl, want int
+	}{
+		{AutoTTL, DeSECMinTTL},
+		{60, DeSECMinTTL},
+		{DeSECMinTTL, DeSECMinTTL},
+		{7200, 7200},
+	} {
+		p, srv := newTestDeSEC(t, func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, []desecRRset{})
+		})
+		if err := p.Set(context.Background(), Record{Name: "home.example.com", Zone: "example.com", Type: "AAAA", TTL: c.ttl}, "2001:db8::1"); err != nil {
+			t.Fatal(err)
+		}
+		writes := srv.writes()
+		if len(writes) != 1 || writes[0].Method != "PUT" || writes[0].Path != "/domains/example.com/rrsets/" {
+			t.Fatalf("ttl %d: writes = %+v, want one bulk PUT", c.ttl, writes)
+		}
+		var got []desecRRset
+		if err := json.Unmarshal(writes[0].Body, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := desecRRset{Subname: "home", Type: "AAAA", Records: []string{"2001:db8::1"}, TTL: c.want}
+		if len(got) != 1 || got[0].Subname != want.Subname || got[0].Type != want.Type || len(got[0].Records) != 1 || got[0].Records[0] != want.Records[0] || got[0].TTL != want.TTL {
+			t.Errorf("ttl %d: sent %s, want %+v", c.ttl, writes[0].Body, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// Observation is one address seen by Diagnose.
+type Observation struct {
+	// Source is "http", "stun", "interface" or "dns".
+	Source string
+	// Detail names the provider, server or record queried.
+	Detail  string
+	Family  string
+	Address string
+	Err     error
+}
+
+// Diagnosis is the outcome of Diagnose.
+type Diagnosis struct {
+	Observations []Observation
+	// Hints interpret the observations, e.g. pointing out carrier-grade NAT
+	// or a record that lags behind the public address.
+	Hints []string
+}
+
+// Diagnose looks up the public address of every address family in use from
+// each IP provider, a STUN server and the outbound interface, and resolves
+// each record through the system resolver, for troubleshooting. It never
+// changes any record.
+func (u *Updater) Diagnose(ctx context.Context, stunServer string) Diagnosis {
+	if stunServer == "" {
+		stunServer = DefaultSTUNServer
+	}
+
+	var d Diagnosis
+	edge := u.edgeRanges(ctx)
+	for _, family := range u.families() {
+		var obs []Observation
+
+		providers := u.providersFor(family)
+		if len(providers) == 0 {
+			providers = []string{"ipify", "cloudflare"}
+		}
+		for _, provider := range providers {
+			provider = resolveProvider(provider, family)
+			if only, ok := providerFamily(provider); ok && only != family {
+				continue
+			}
+			ip, err := u.fetchIP(ctx, family, provider)
+			obs = append(obs, Observation{Source: "http", Detail: provider, Family: string(family), Address: ip, Err: err})
+		}
+
+		network, target := "udp4", "1.1.1.1:80"
+		if family == ipv6 {
+			network, target = "udp6", "[2606:4700:4700::1111]:80"
+		}
+		o := Observation{Source: "stun", Detail: stunServer, Family: string(family)}
+		if ip, err := stunAddress(ctx, network, stunServer); err != nil {
+			o.Err = err
+		} else {
+			o.Address = ip.String()
+		}
+		obs = append(obs, o)
+
+		o = Observation{Source: "interface", Detail: "outbound", Family: string(family)}
+		if ip, err := outboundAddr(network, target); err != nil {
+			o.Err = err
+		} else {
+			o.Address = ip.String()
+		}
+		obs = append(obs, o)
+
+		lookupNetwork := "ip4"
+		if family == ipv6 {
+			lookupNetwork = "ip6"
+		}
+		for _, record := range u.cfg.Records {
+			if record.Name == "" || !isIPType(record.Type) || familyForType(record.Type) != family {
+				continue
+			}
+			o := Observation{Source: "dns", Detail: record.Name, Family: string(family)}
+			ips, err := net.DefaultResolver.LookupIP(ctx, lookupNetwork, record.Name)
+			if err != nil {
+				o.Err = err
+			} else {
+				addrs := make([]string, len(ips))
+				for i, ip := range ips {
+					addrs[i] = ip.String()
+				}
+				o.Address = strings.Join(addrs, ",")
+			}
+			obs = append(obs, o)
+		}
+
+		d.Observations = append(d.Observations, obs...)
+		d.Hints = append(d.Hints, u.diagnosisHints(family, obs, edge)...)
+	}
+	return d
+}
+
+// diagnosisHints interprets the observations of one address family. edge
+// holds Cloudflare's edge ranges.
+func (u *Updater) diagnosisHints(family ipFamily, obs []Observation, edge []*net.IPNet) []string {
+	var hints []string
+	addrs := func(source string) []string {
+		var out []string
+		for _, o := range obs {
+			if o.Source == source && o.Err == nil && !slices.Contains(out, o.Address) {
+				out = append(out, o.Address)
+			}
+		}
+		return out
+	}
+
+	httpAddrs, stunAddrs, ifaceAddrs := addrs("http"), addrs("stun"), addrs("interface")
+	public := ""
+	switch {
+	case len(httpAddrs) > 0:
+		public = httpAddrs[0]
+	case len(stunAddrs) > 0:
+		public = stunAddrs[0]
+	default:
+		return []string{fmt.Sprintf("No public %s address was detected: check %s connectivity", family, family)}
+	}
+
+	for _, o := range obs {
+		if o.Source == "http" && o.Err == nil && inRanges(edge, o.Address) {
+			hints = append(hints, fmt.Sprintf("Provider %s returned %s, a Cloudflare edge address: it sits behind Cloudflare, use the cloudflare provider instead", o.Detail, o.Address))
+		}
+	}
+	if len(httpAddrs) > 1 {
+		hints = append(hints, fmt.Sprintf("HTTP providers disagree on the %s address (%s): traffic may leave through several routes", family, strings.Join(httpAddrs, ", ")))
+	}
+	if len(httpAddrs) > 0 && len(stunAddrs) > 0 && !sameContent(httpAddrs[0], stunAddrs[0]) {
+		hints = append(hints, fmt.Sprintf("STUN sees %s but HTTP sees %s: an HTTP proxy or policy routing may be involved", stunAddrs[0], httpAddrs[0]))
+	}
+	if cgnatRange.Contains(net.ParseIP(public)) {
+		hints = append(hints, fmt.Sprintf("The public address %s is in the CGNAT range: it is shared and not reachable from the internet", public))
+	}
+
+	if len(ifaceAddrs) > 0 {
+		iface := net.ParseIP(ifaceAddrs[0])
+		switch {
+		case cgnatRange.Contains(iface):
+			hints = append(hints, fmt.Sprintf("The interface address %s is in the CGNAT range: your ISP uses carrier-grade NAT, so inbound connections will not reach this host", iface))
+		case iface.IsPrivate() || iface.IsLinkLocalUnicast():
+			if family == ipv6 {
+				hints = append(hints, fmt.Sprintf("The interface address %s is not globally routable: IPv6 traffic is translated upstream", iface))
+			} else {
+				hints = append(hints, fmt.Sprintf("The interface address %s is private: the host is behind NAT, forward ports on the router to reach it", iface))
+			}
+		case !sameContent(iface.String(), public):
+			hints = append(hints, fmt.Sprintf("The interface address %s is public but differs from %s: an upstream NAT or another WAN link is in use", iface, public))
+		default:
+			hints = append(hints, fmt.Sprintf("The interface holds the public %s address: no NAT is involved", family))
+		}
+	}
+
+	for _, o := range obs {
+		if o.Source != "dns" {
+			continue
+		}
+		if o.Err != nil {
+			hints = append(hints, fmt.Sprintf("Record %s does not resolve: it may not exist yet", o.Detail))
+			continue
+		}
+		if !slices.ContainsFunc(strings.Split(o.Address, ","), func(a string) bool { return sameContent(a, public) }) {
+			hint := fmt.Sprintf("Record %s resolves to %s, not the public address %s", o.Detail, o.Address, public)
+			if u.recordProxied(o.Detail) {
+				hint += ": expected, it is proxied and resolves to Cloudflare's edge"
+			}
+			hints = append(hints, hint)
+		}
+	}
+	return hints
+}
+
+func (u *Updater) recordProxied(name string) bool {
+	for _, record := range u.cfg.Records {
+		if sameName(record.Name, name) && record.Proxied {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package ddns
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// FieldDiff compares one field of the existing record with the value a run
+// would write.
+type FieldDiff struct {
+	Field string
+	// Current is empty when the record does not exist.
+	Current string
+	Desired string
+}
+
+// Changed reports whether the field would be modified.
+func (d FieldDiff) Changed() bool {
+	if d.Field == "content" {
+		return !sameContent(d.Current, d.Desired)
+	}
+	return d.Current != d.Desired
+}
+
+// recordDiff lists the type, content, TTL and proxied status of existing
+// next to the desired values. existing may be nil for a record that would be
+// created.
+func recordDiff(record Record, existing *DNSRecord, content string) []FieldDiff {
+	diff := []FieldDiff{
+		{Field: "type", Desired: record.Type},
+		{Field: "content", Desired: content},
+		{Field: "ttl", Desired: formatTTL(record.TTL)},
+	}
+	if record.Type == "SRV" {
+		diff[1].Desired = formatSRVData(buildSRVData(record, content))
+	} else {
+		diff = append(diff, FieldDiff{Field: "proxied", Desired: strconv.FormatBool(record.Proxied)})
+	}
+	if record.Type == "MX" {
+		diff = append(diff, FieldDiff{Field: "priority", Desired: strconv.Itoa(record.Priority)})
+	}
+	if record.Comment != "" || (existing != nil && existing.Comment != "") {
+		diff = append(diff, FieldDiff{Field: "comment", Desired: record.Comment})
+	}
+	if existing == nil {
+		return diff
+	}
+
+	for i := range diff {
+		d := &diff[i]
+		switch d.Field {
+		case "type":
+			d.Current = existing.Type
+		case "content":
+			d.Current = existing.Content
+			if record.Type == "SRV" && existing.Data != nil {
+				d.Current = formatSRVData(*existing.Data)
+			}
+		case "ttl":
+			d.Current = formatTTL(existing.TTL)
+		case "proxied":
+			d.Current = strconv.FormatBool(existing.Proxied)
+		case "priority":
+			d.Current = strconv.Itoa(existing.Priority)
+		case "comment":
+			d.Current = existing.Comment
+			if record.Comment == "" {
+				d.Desired = existing.Comment
+			}
+		}
+	}
+	return diff
+}
+
+func formatTTL(ttl int) string {
+	if ttl == AutoTTL {
+		return "auto"
+	}
+	return strconv.Itoa(ttl)
+}
+
+func formatSRVData(data SRVData) string {
+	return fmt.Sprintf("%d %d %d %s", data.Priority, data.Weight, data.Port, data.Target)
+}
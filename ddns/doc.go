@@ -0,0 +1,35 @@
+// Package ddns keeps Cloudflare DNS records pointed at the host's public IP
+// address, or at a fixed value for non-address record types.
+//
+// The ddns-updater command is a thin wrapper around this package that builds
+// a Config from environment variables. Programs embedding the updater build
+// the Config themselves:
+//
+//	updater, err := ddns.New(ddns.Config{
+//		ZoneName: "example.com",
+//		APIToken: token,
+//		Records: []ddns.Record{
+//			{Name: "home.example.com"},
+//			{Name: "vpn.example.com", TTL: 300},
+//		},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//
+//	result, err := updater.Run(ctx)
+//	for _, r := range result.Records {
+//		fmt.Printf("%s: %s\n", r.Name, r.Action)
+//	}
+//
+// Run never exits the process. Every failure is returned as an error, and a
+// Result is returned even when some records fail so callers can report on
+// the ones that succeeded. Progress is written to Config.Logger, which
+// defaults to slog.Default():
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+//	updater, err := ddns.New(ddns.Config{
+//		// ...
+//		Logger: logger.With("component", "ddns"),
+//	})
+package ddns
@@ -0,0 +1,131 @@
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// DefaultEdgeRangesURL is Cloudflare's published list of its edge ranges,
+// for Config.EdgeRangesURL.
+const DefaultEdgeRangesURL = cloudflareBaseURL + "/ips"
+
+// cloudflareEdgeRanges is the list published at https://www.cloudflare.com/ips/,
+// used when Config.EdgeRangesURL is unset or cannot be fetched.
+var cloudflareEdgeRanges = mustParseCIDRs(
+	"173.245.48.0/20", "103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
+	"141.101.64.0/18", "108.162.192.0/18", "190.93.240.0/20", "188.114.96.0/20",
+	"197.234.240.0/22", "198.41.128.0/17", "162.158.0.0/15", "104.16.0.0/13",
+	"104.24.0.0/14", "172.64.0.0/13", "131.0.72.0/22",
+	"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32", "2405:b500::/32",
+	"2405:8100::/32", "2a06:98c0::/29", "2c0f:f248::/32",
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets, err := parseCIDRs(cidrs)
+	if err != nil {
+		panic(err)
+	}
+	return nets
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, network)
+	}
+	return nets, nil
+}
+
+// inRanges reports whether ip is inside any of ranges.
+func inRanges(ranges []*net.IPNet, ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, network := range ranges {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkEdge warns when a detected address belongs to Cloudflare, which
+// happens when the IP provider sits behind Cloudflare and sees the edge
+// that proxied the request rather than this host.
+func (u *Updater) checkEdge(ctx context.Context, ip string) {
+	if inRanges(u.edgeRanges(ctx), ip) {
+		u.log.Warn("Detected IP is a Cloudflare edge address, the IP provider is likely behind Cloudflare; use the cloudflare provider, which reads /cdn-cgi/trace, instead", "ip", ip)
+	}
+}
+
+// edgeRanges returns Cloudflare's edge ranges, fetched from
+// Config.EdgeRangesURL on first use when it is set. A failed fetch is
+// retried on the next call and falls back to the built-in list meanwhile.
+func (u *Updater) edgeRanges(ctx context.Context) []*net.IPNet {
+	if u.cfg.EdgeRangesURL == "" {
+		return cloudflareEdgeRanges
+	}
+	u.edgeMu.Lock()
+	defer u.edgeMu.Unlock()
+	if u.edge != nil {
+		return u.edge
+	}
+	ranges, err := u.fetchEdgeRanges(ctx)
+	if err != nil {
+		u.log.Warn("Failed to fetch the Cloudflare edge ranges, using the built-in list", "url", u.cfg.EdgeRangesURL, "error", err)
+		return cloudflareEdgeRanges
+	}
+	u.edge = ranges
+	return ranges
+}
+
+// fetchEdgeRanges reads Config.EdgeRangesURL, either Cloudflare's /ips API
+// response or a plain list of CIDRs, one per line, like
+// https://www.cloudflare.com/ips-v4.
+func (u *Updater) fetchEdgeRanges(ctx context.Context) ([]*net.IPNet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.cfg.EdgeRangesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: u.cfg.CFHTTPTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var cidrs []string
+	var api struct {
+		Result struct {
+			IPv4 []string `json:"ipv4_cidrs"`
+			IPv6 []string `json:"ipv6_cidrs"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(body, &api) == nil {
+		cidrs = append(api.Result.IPv4, api.Result.IPv6...)
+	} else {
+		cidrs = strings.Fields(string(body))
+	}
+	if len(cidrs) == 0 {
+		return nil, errors.New("response does not list any ranges")
+	}
+	return parseCIDRs(cidrs)
+}
@@ -0,0 +1,81 @@
+package ddns
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInRangesBuiltIn(t *testing.T) {
+	for _, c := range []struct {
+		ip   string
+		want bool
+	}{
+		{"104.16.1.1", true},
+		{"172.67.200.3", true},
+		{"162.159.255.255", true},
+		{"131.0.75.255", true},
+		{"2606:4700::6810:1", true},
+		{"2a06:98c7:ffff::1", true},
+		{"162.160.0.1", false},
+		{"131.0.76.0", false},
+		{"203.0.113.10", false},
+		{"2001:db8::1", false},
+		{"2a06:98c8::1", false},
+		{"not-an-address", false},
+		{"", false},
+	} {
+		if got := inRanges(cloudflareEdgeRanges, c.ip); got != c.want {
+			t.Errorf("inRanges(%q) = %t, want %t", c.ip, got, c.want)
+		}
+	}
+}
+
+func TestEdgeRangesURL(t *testing.T) {
+	for _, c := range []struct {
+		name        string
+		contentType string
+		body        string
+		edge, other string
+	}{
+		{"API response", "application/json", `{"result": {"ipv4_cidrs": ["198.51.100.0/24"], "ipv6_cidrs": ["2001:db8:1::/48"]}, "success": true}`, "2001:db8:1::5", "2001:db8:2::5"},
+		{"plain list", "text/plain", "198.51.100.0/24\n2001:db8:1::/48\n", "198.51.100.7", "198.51.101.7"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			u := &Updater{cfg: Config{EdgeRangesURL: serveBody(t, c.contentType, c.body), CFHTTPTimeout: time.Second}, log: testLogger()}
+			ranges := u.edgeRanges(context.Background())
+			if !inRanges(ranges, c.edge) || !inRanges(ranges, "198.51.100.7") {
+				t.Errorf("fetched ranges %v miss %s", ranges, c.edge)
+			}
+			if inRanges(ranges, c.other) || inRanges(ranges, "104.16.1.1") {
+				t.Errorf("fetched ranges %v hold %s or the built-in list", ranges, c.other)
+			}
+		})
+	}
+}
+
+func TestEdgeRangesFallback(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first fetch fails; the second serves an empty list.
+		if calls.Add(1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"result": {"ipv4_cidrs": [], "ipv6_cidrs": []}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	u := &Updater{cfg: Config{EdgeRangesURL: srv.URL, CFHTTPTimeout: time.Second}, log: testLogger()}
+	for i := range 2 {
+		if ranges := u.edgeRanges(context.Background()); !inRanges(ranges, "104.16.1.1") {
+			t.Errorf("call %d: ranges %v, want the built-in list", i+1, ranges)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("%d fetches, want a failed fetch retried once", got)
+	}
+}
@@ -0,0 +1,94 @@
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// FailoverCheck turns the updater into a simple DNS failover: while
+// HealthURL answers, records get their usual content, the detected address
+// or Config.Content; while it does not, they point at the address of their
+// family in FailoverIPs instead.
+type FailoverCheck struct {
+	// HealthURL is fetched at the start of every run; a 2xx response
+	// within Config.IPHTTPTimeout means the primary is healthy.
+	HealthURL string
+	// FailoverIPs holds at most one IPv4 and one IPv6 address. Records of a
+	// family without one are skipped while the primary is down.
+	FailoverIPs []string
+}
+
+func (f *FailoverCheck) validate() error {
+	if _, err := parseWebhookURL(f.HealthURL, false); err != nil {
+		return fmt.Errorf("invalid failover health URL: %w", err)
+	}
+	if len(f.FailoverIPs) == 0 {
+		return fmt.Errorf("failover requires a failover address")
+	}
+	return validateFamilyAddresses("failover", f.FailoverIPs)
+}
+
+// primaryHealthy checks Failover.HealthURL and logs when the active target
+// changes, so a steady state stays quiet.
+func (u *Updater) primaryHealthy(ctx context.Context) bool {
+	err := u.checkHealth(ctx)
+	healthy := err == nil
+
+	u.failoverMu.Lock()
+	defer u.failoverMu.Unlock()
+	if u.failoverChecked && u.primaryUp == healthy {
+		return healthy
+	}
+	u.failoverChecked, u.primaryUp = true, healthy
+	if healthy {
+		u.log.Info("Primary is healthy, records point at the primary address", "health_url", u.cfg.Failover.HealthURL)
+	} else {
+		u.log.Warn("Primary is unhealthy, records point at the failover address", "health_url", u.cfg.Failover.HealthURL, "failover_ips", strings.Join(u.cfg.Failover.FailoverIPs, ","), "error", err)
+	}
+	return healthy
+}
+
+func (u *Updater) checkHealth(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.cfg.Failover.HealthURL, nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: u.cfg.IPHTTPTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// failoverContents is resolveContents while the primary is down: the
+// failover address of each family in use, without any detection.
+func (u *Updater) failoverContents(result *Result) map[string]desiredContent {
+	contents := make(map[string]desiredContent)
+	var values []string
+	for _, family := range u.families() {
+		recordType := "A"
+		if family == ipv6 {
+			recordType = "AAAA"
+		}
+		if !u.cfg.Stack.manages(recordType) {
+			continue
+		}
+		if ip := familyAddress(u.cfg.Failover.FailoverIPs, family); ip != "" {
+			contents[recordType] = desiredContent{value: ip}
+			values = append(values, ip)
+		} else {
+			contents[recordType] = desiredContent{skip: fmt.Sprintf("primary is unhealthy and there is no %s failover address", family)}
+		}
+	}
+	result.Content = strings.Join(values, ",")
+	return contents
+}
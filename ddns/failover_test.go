@@ -0,0 +1,81 @@
+package ddns
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRunFailoverTransitions(t *testing.T) {
+	var healthy atomic.Bool
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(health.Close)
+
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "AAAA", Content: "2001:db8::1", TTL: AutoTTL})
+	var logs bytes.Buffer
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Content:  "198.51.100.1,2001:db8::10",
+		Failover: &FailoverCheck{HealthURL: health.URL, FailoverIPs: []string{"203.0.113.99"}},
+		Records:  []Record{{Name: "home.example.com", Type: "A"}, {Name: "home.example.com", Type: "AAAA"}},
+		Logger:   slog.New(slog.NewTextHandler(&logs, nil)),
+	}, f)
+
+	contents := func() (a, aaaa string) {
+		for _, record := range f.recordsOf("zone-example.com") {
+			if record.Type == "A" {
+				a = record.Content
+			} else {
+				aaaa = record.Content
+			}
+		}
+		return a, aaaa
+	}
+	for i, s := range []struct {
+		healthy bool
+		// a and aaaa are the contents after the run, and actions what
+		// happened to the A and AAAA records.
+		a, aaaa string
+		actions [2]Action
+		// logged is the transition logged by the run, "" for none.
+		logged string
+	}{
+		{true, "198.51.100.1", "2001:db8::10", [2]Action{ActionUpdated, ActionUpdated}, "Primary is healthy"},
+		{true, "198.51.100.1", "2001:db8::10", [2]Action{ActionUnchanged, ActionUnchanged}, ""},
+		// AAAA has no failover address, so it is left as it was.
+		{false, "203.0.113.99", "2001:db8::10", [2]Action{ActionUpdated, ActionSkipped}, "Primary is unhealthy"},
+		{false, "203.0.113.99", "2001:db8::10", [2]Action{ActionUnchanged, ActionSkipped}, ""},
+		{true, "198.51.100.1", "2001:db8::10", [2]Action{ActionUpdated, ActionUnchanged}, "Primary is healthy"},
+	} {
+		healthy.Store(s.healthy)
+		logs.Reset()
+		result := run(t, u)
+
+		for j, rr := range result.Records {
+			if rr.Action != s.actions[j] {
+				t.Errorf("run %d: %s action %q, want %q", i+1, rr.Type, rr.Action, s.actions[j])
+			}
+		}
+		if a, aaaa := contents(); a != s.a || aaaa != s.aaaa {
+			t.Errorf("run %d: records A %s AAAA %s, want %s and %s", i+1, a, aaaa, s.a, s.aaaa)
+		}
+		transitions := strings.Count(logs.String(), "msg=\"Primary is")
+		switch {
+		case s.logged == "" && transitions != 0:
+			t.Errorf("run %d logged a transition in a steady state:\n%s", i+1, logs.String())
+		case s.logged != "" && (transitions != 1 || !strings.Contains(logs.String(), s.logged)):
+			t.Errorf("run %d: logs miss %q once:\n%s", i+1, s.logged, logs.String())
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memProvider is a Provider holding its record sets in memory. It logs the
+// values written and fails every call with err when set.
+type memProvider struct {
+	mu      sync.Mutex
+	records map[string]RRset
+	writes  []string
+	err     error
+}
+
+func (p *memProvider) Name() string { return "mem" }
+
+func (p *memProvider) Get(ctx context.Context, record Record) (*RRset, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return nil, p.err
+	}
+	rrset, ok := p.records[record.Name+" "+record.Type]
+	if !ok {
+		return nil, nil
+	}
+	return &rrset, nil
+}
+
+func (p *memProvider) Set(ctx context.Context, record Record, content string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.writes = append(p.writes, record.Name+" "+content)
+	p.records[record.Name+" "+record.Type] = RRset{Values: []string{content}, TTL: record.TTL}
+	return nil
+}
+
+func TestRunFallbackProvider(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		// status answers the primary's record listing, 0 for the fake's
+		// own answer.
+		status      int
+		fallbackErr error
+		action      Action
+		fallback    string
+		writes      []string
+		wantErr     string
+	}{
+		{"primary succeeds", 0, nil, ActionUpdated, "", nil, ""},
+		{"primary down", http.StatusBadGateway, nil, ActionUpdated, "mem", []string{"home.example.com 198.51.100.1"}, ""},
+		{"primary rate limited", http.StatusTooManyRequests, nil, ActionUpdated, "mem", []string{"home.example.com 198.51.100.1"}, ""},
+		{"primary refuses", http.StatusForbidden, nil, ActionFailed, "", nil, "403"},
+		{"both down", http.StatusBadGateway, errors.New("mem is down"), ActionFailed, "mem", nil, "mem is down"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+			if c.status != 0 {
+				f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+					if r.URL.Path != "/zones/zone-example.com/dns_records" {
+						return false
+					}
+					writeError(w, c.status, 10000, http.StatusText(c.status))
+					return true
+				}
+			}
+			mem := &memProvider{
+				records: map[string]RRset{"home.example.com A": {Values: []string{"192.0.2.1"}, TTL: 300}},
+				err:     c.fallbackErr,
+			}
+			u := newTestUpdater(t, Config{
+				ZoneName:         "example.com",
+				APIToken:         "token",
+				Content:          "198.51.100.1",
+				FallbackProvider: mem,
+				Records:          []Record{{Name: "home.example.com"}},
+			}, f)
+
+			result, _ := u.Run(context.Background())
+			rr := result.Records[0]
+			if rr.Action != c.action || rr.Fallback != c.fallback {
+				t.Errorf("action %q fallback %q (%v), want %q and %q", rr.Action, rr.Fallback, rr.Err, c.action, c.fallback)
+			}
+			if !slices.Equal(mem.writes, c.writes) {
+				t.Errorf("fallback writes %q, want %q", mem.writes, c.writes)
+			}
+			if c.wantErr != "" && (rr.Err == nil || !strings.Contains(rr.Err.Error(), c.wantErr)) {
+				t.Errorf("error %v, want one containing %q", rr.Err, c.wantErr)
+			}
+			if c.status == 0 {
+				if got := f.recordsOf("zone-example.com")[0].Content; got != "198.51.100.1" {
+					t.Errorf("primary content %q, want 198.51.100.1", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	gandiBaseURL = "https://api.gandi.net/v5/livedns"
+
+	// GandiMinTTL is the lowest TTL LiveDNS accepts. Lower TTLs are raised
+	// to it; AutoTTL leaves the TTL to Gandi's default.
+	GandiMinTTL = 300
+)
+
+type gandiProvider struct {
+	api restClient
+}
+
+type gandiRRset struct {
+	Values []string `json:"rrset_values"`
+	TTL    int      `json:"rrset_ttl,omitempty"`
+}
+
+// NewGandiProvider manages records on Gandi LiveDNS with a personal access
+// token.
+func NewGandiProvider(token string) (Provider, error) {
+	if token == "" {
+		return nil, errors.New("Gandi personal access token is required")
+	}
+	return &gandiProvider{api: restClient{
+		client:  &http.Client{Timeout: DefaultHTTPTimeout},
+		baseURL: gandiBaseURL,
+		auth:    "Bearer " + token,
+	}}, nil
+}
+
+func (p *gandiProvider) Name() string {
+	return "gandi"
+}
+
+// rrsetPath addresses an RRset; LiveDNS spells the apex name "@".
+func (p *gandiProvider) rrsetPath(record Record) (string, error) {
+	name, err := relativeName(record.Name, record.Zone)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = "@"
+	}
+	return fmt.Sprintf("/domains/%s/records/%s/%s", record.Zone, name, record.Type), nil
+}
+
+func (p *gandiProvider) Get(ctx context.Context, record Record) (*RRset, error) {
+	path, err := p.rrsetPath(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var rrset gandiRRset
+	err = p.api.do(ctx, "GET", path, nil, &rrset)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &RRset{Values: rrset.Values, TTL: rrset.TTL}, nil
+}
+
+// Set replaces the RRset with PUT, which LiveDNS treats as create or
+// replace.
+func (p *gandiProvider) Set(ctx context.Context, record Record, content string) error {
+	path, err := p.rrsetPath(record)
+	if err != nil {
+		return err
+	}
+	rrset := gandiRRset{Values: []string{content}}
+	if record.TTL != AutoTTL {
+		rrset.TTL = max(record.TTL, GandiMinTTL)
+	}
+	return p.api.do(ctx, "PUT", path, rrset, nil)
+}
@@ -0,0 +1,73 @@
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func newTestGandi(t *testing.T, handler http.HandlerFunc) (*gandiProvider, *providerServer) {
+	t.Helper()
+	srv := newProviderServer(t, handler)
+	p, err := NewGandiProvider("pat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*gandiProvider).api.baseURL = srv.URL
+	return p.(*gandiProvider), srv
+}
+
+func TestGandiGet(t *testing.T) {
+	p, srv := newTestGandi(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/domains/example.com/records/@/A" {
+			writeJSON(w, gandiRRset{Values: []string{"192.0.2.1", "192.0.2.2"}, TTL: 1800})
+			return
+		}
+		http.NotFound(w, r)
+	})
+	ctx := context.Background()
+
+	rrset, err := p.Get(ctx, Record{Name: "example.com", Zone: "example.com", Type: "A"})
+	if err != nil || rrset == nil || len(rrset.Values) != 2 || rrset.TTL != 1800 {
+		t.Errorf("Get apex = %+v, %v", rrset, err)
+	}
+	rrset, err = p.Get(ctx, Record{Name: "home.example.com", Zone: "example.com", Type: "A"})
+	if err != nil || rrset != nil {
+		t.Errorf("Get missing = %+v, %v; want nil, nil", rrset, err)
+	}
+	if got := srv.requests[0].Header.Get("Authorization"); got != "Bearer pat" {
+		t.Errorf("Authorization = %q, want Bearer pat", got)
+	}
+}
+
+func TestGandiSet(t *testing.T) {
+	for _, c := range []struct {
+		ttl     int
+		wantTTL any
+	}{
+		{AutoTTL, nil},
+		{60, float64(GandiMinTTL)},
+		{3600, 3600.0},
+	} {
+		p, srv := newTestGandi(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(w, map[string]string{"message": "DNS Record Created"})
+		})
+		if err := p.Set(context.Background(), Record{Name: "home.example.com", Zone: "example.com", Type: "A", TTL: c.ttl}, "198.51.100.7"); err != nil {
+			t.Fatal(err)
+		}
+		writes := srv.writes()
+		if len(writes) != 1 || writes[0].Method != "PUT" || writes[0].Path != "/domains/example.com/records/home/A" {
+			t.Fatalf("ttl %d: writes = %+v, want one PUT of the RRset", c.ttl, writes)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(writes[0].Body, &got); err != nil {
+			t.Fatal(err)
+		}
+		values, _ := got["rrset_values"].([]any)
+		if len(values) != 1 || values[0] != "198.51.100.7" || got["rrset_ttl"] != c.wantTTL {
+			t.Errorf("ttl %d: sent %s, want rrset_ttl %v", c.ttl, writes[0].Body, c.wantTTL)
+		}
+	}
+}
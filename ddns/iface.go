@@ -0,0 +1,171 @@
+package ddns
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IPv6Select picks among the IPv6 addresses of an interface provider.
+type IPv6Select string
+
+const (
+	// IPv6SelectFirst takes the first usable address. It is the default.
+	IPv6SelectFirst IPv6Select = "first"
+	// IPv6SelectPermanent takes the first address that is not a temporary
+	// privacy address, such as a stable EUI-64 or static one.
+	IPv6SelectPermanent IPv6Select = "permanent"
+	// IPv6SelectTemporary takes the first temporary privacy address.
+	IPv6SelectTemporary IPv6Select = "temporary"
+)
+
+// interfacePrefix marks IP providers that read the address of a local
+// network interface, e.g. "interface:eth0", instead of asking a server.
+const interfacePrefix = "interface:"
+
+func interfaceProvider(provider string) (string, bool) {
+	name, ok := strings.CutPrefix(provider, interfacePrefix)
+	return name, ok && name != ""
+}
+
+// ifInet6Path lists the IPv6 addresses of every interface with their
+// flags on Linux.
+var ifInet6Path = "/proc/net/if_inet6"
+
+// Address flags of ifInet6Path, from linux/if_addr.h.
+const (
+	ifaFlagTemporary  = 0x01
+	ifaFlagDeprecated = 0x20
+	ifaFlagTentative  = 0x40
+)
+
+type ifaceAddr struct {
+	ip        net.IP
+	temporary bool
+}
+
+// interfaceIP returns the address of family on the interface name. Only
+// global unicast addresses are considered: private ones, RFC 1918 or IPv6
+// unique local, are skipped, as are IPv4 addresses in the CGNAT range
+// 100.64.0.0/10 and deprecated or tentative IPv6 addresses. Config.IPv6Select
+// picks among the remaining IPv6 addresses.
+func (u *Updater) interfaceIP(family ipFamily, name string) (string, error) {
+	addrs, err := interfaceAddrs(name)
+	if err != nil {
+		return "", err
+	}
+	return pickInterfaceIP(addrs, family, u.cfg.IPv6Select, name)
+}
+
+// pickInterfaceIP returns the first of addrs that interfaceIP accepts.
+func pickInterfaceIP(addrs []ifaceAddr, family ipFamily, sel IPv6Select, name string) (string, error) {
+	for _, a := range addrs {
+		if familyOf(a.ip) != family || a.ip.IsPrivate() {
+			continue
+		}
+		if family == ipv4 && cgnatRange.Contains(a.ip) {
+			continue
+		}
+		if family == ipv6 {
+			switch sel {
+			case IPv6SelectPermanent:
+				if a.temporary {
+					continue
+				}
+			case IPv6SelectTemporary:
+				if !a.temporary {
+					continue
+				}
+			}
+		}
+		return a.ip.String(), nil
+	}
+	if family == ipv6 && sel != IPv6SelectFirst {
+		return "", fmt.Errorf("interface %s has no %s IPv6 address", name, sel)
+	}
+	return "", fmt.Errorf("interface %s has no global %s address", name, family)
+}
+
+// interfaceAddrs returns the global unicast addresses of the interface
+// name. IPv6 flags come from ifInet6Path where it exists; elsewhere every
+// address counts as permanent.
+func interfaceAddrs(name string) ([]ifaceAddr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("interface %s: %w", name, err)
+	}
+	flags, err := readIfInet6(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return filterIfaceAddrs(ips, flags), nil
+}
+
+// filterIfaceAddrs keeps the global unicast addresses among ips, dropping
+// IPv6 ones that flags, as read by parseIfInet6, mark deprecated or
+// tentative.
+func filterIfaceAddrs(ips []net.IP, flags map[string]int) []ifaceAddr {
+	var out []ifaceAddr
+	for _, ip := range ips {
+		if !ip.IsGlobalUnicast() {
+			continue
+		}
+		a := ifaceAddr{ip: ip}
+		if f, ok := flags[ip.String()]; ok {
+			if f&(ifaFlagDeprecated|ifaFlagTentative) != 0 {
+				continue
+			}
+			a.temporary = f&ifaFlagTemporary != 0
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// readIfInet6 returns the flags of the IPv6 addresses of the interface
+// name, keyed by address. Each line of ifInet6Path holds the address in
+// hex, the interface index, prefix length, scope, flags and name.
+func readIfInet6(name string) (map[string]int, error) {
+	f, err := os.Open(ifInet6Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseIfInet6(bufio.NewScanner(f), name), nil
+}
+
+func parseIfInet6(scanner *bufio.Scanner, name string) map[string]int {
+	flags := make(map[string]int)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 6 || fields[5] != name {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[0])
+		if err != nil || len(raw) != net.IPv6len {
+			continue
+		}
+		f, err := strconv.ParseInt(fields[4], 16, 0)
+		if err != nil {
+			continue
+		}
+		flags[net.IP(raw).String()] = int(f)
+	}
+	return flags
+}
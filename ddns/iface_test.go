@@ -0,0 +1,83 @@
+package ddns
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// ifInet6 is /proc/net/if_inet6 for a synthetic eth0 with a stable and a
+// temporary global address, a deprecated temporary one, a tentative one, a
+// unique local one and a link-local one, plus an address of another
+// interface.
+const ifInet6 = `20010db8000000000000000000000099 03 40 00 80     eth1
+fe800000000000000211223344556677 02 40 20 80     eth0
+fd000000000000000000000000000001 02 40 00 80     eth0
+20010db8000000000000000000000020 02 40 00 21     eth0
+20010db8000000000000000000000030 02 40 00 40     eth0
+20010db8000000000211223344556677 02 40 00 80     eth0
+20010db800000000a1b2c3d4e5f60718 02 40 00 01     eth0
+`
+
+func TestInterfaceIPv6Select(t *testing.T) {
+	flags := parseIfInet6(bufio.NewScanner(strings.NewReader(ifInet6)), "eth0")
+	if _, ok := flags["2001:db8::99"]; ok {
+		t.Fatal("flags include an address of eth1")
+	}
+	var ips []net.IP
+	for _, s := range []string{"fe80::211:2233:4455:6677", "fd00::1", "2001:db8::20", "2001:db8::30", "2001:db8::211:2233:4455:6677", "2001:db8::a1b2:c3d4:e5f6:718", "192.0.2.1"} {
+		ips = append(ips, net.ParseIP(s))
+	}
+	addrs := filterIfaceAddrs(ips, flags)
+
+	for _, c := range []struct {
+		sel  IPv6Select
+		want string
+	}{
+		{IPv6SelectFirst, "2001:db8::211:2233:4455:6677"},
+		{IPv6SelectPermanent, "2001:db8::211:2233:4455:6677"},
+		{IPv6SelectTemporary, "2001:db8::a1b2:c3d4:e5f6:718"},
+	} {
+		got, err := pickInterfaceIP(addrs, ipv6, c.sel, "eth0")
+		if err != nil || got != c.want {
+			t.Errorf("IPV6_SELECT=%s: got %q, %v; want %q", c.sel, got, err, c.want)
+		}
+	}
+
+	// With only the temporary address usable, permanent finds nothing.
+	temporaryOnly := filterIfaceAddrs([]net.IP{net.ParseIP("2001:db8::20"), net.ParseIP("2001:db8::a1b2:c3d4:e5f6:718")}, flags)
+	if got, err := pickInterfaceIP(temporaryOnly, ipv6, IPv6SelectPermanent, "eth0"); err == nil {
+		t.Errorf("IPV6_SELECT=permanent picked %q from temporary addresses only", got)
+	}
+	if got, err := pickInterfaceIP(temporaryOnly, ipv6, IPv6SelectFirst, "eth0"); err != nil || got != "2001:db8::a1b2:c3d4:e5f6:718" {
+		t.Errorf("IPV6_SELECT=first = %q, %v; want the temporary address", got, err)
+	}
+}
+
+func TestInterfaceIPv4SkipsNonPublic(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		ips     []string
+		want    string
+		wantErr bool
+	}{
+		{"public", []string{"198.51.100.7"}, "198.51.100.7", false},
+		{"private first", []string{"192.168.1.10", "10.0.0.2", "172.16.0.1", "198.51.100.7"}, "198.51.100.7", false},
+		{"CGNAT first", []string{"100.64.12.34", "198.51.100.7"}, "198.51.100.7", false},
+		{"only private and CGNAT", []string{"192.168.1.10", "100.127.255.1"}, "", true},
+		{"loopback and link-local", []string{"127.0.0.1", "169.254.0.5"}, "", true},
+		{"just outside CGNAT", []string{"100.128.0.1"}, "100.128.0.1", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var ips []net.IP
+			for _, s := range c.ips {
+				ips = append(ips, net.ParseIP(s))
+			}
+			got, err := pickInterfaceIP(filterIfaceAddrs(ips, nil), ipv4, IPv6SelectFirst, "eth0")
+			if (err != nil) != c.wantErr || got != c.want {
+				t.Errorf("got %q, %v; want %q, error %t", got, err, c.want, c.wantErr)
+			}
+		})
+	}
+}
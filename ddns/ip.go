@@ -0,0 +1,346 @@
+package ddns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// CloudflareTraceProvider and CloudflareTraceIPv6Provider are
+	// Cloudflare's trace endpoints, which report the client address as an
+	// ip= line. Both can be referred to as "cloudflare" in
+	// Config.IPProviders.
+	CloudflareTraceProvider     = "https://1.1.1.1/cdn-cgi/trace"
+	CloudflareTraceIPv6Provider = "https://[2606:4700:4700::1111]/cdn-cgi/trace"
+)
+
+type ipFamily string
+
+const (
+	ipv4 ipFamily = "IPv4"
+	ipv6 ipFamily = "IPv6"
+)
+
+// validateFamilyAddresses checks that values, the kind addresses of some
+// option, are IP addresses with at most one per family.
+func validateFamilyAddresses(kind string, values []string) error {
+	seen := make(map[ipFamily]bool)
+	for _, value := range values {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return fmt.Errorf("invalid %s address %q", kind, value)
+		}
+		if seen[familyOf(ip)] {
+			return fmt.Errorf("%s addresses hold more than one %s address", kind, familyOf(ip))
+		}
+		seen[familyOf(ip)] = true
+	}
+	return nil
+}
+
+// familyAddress returns the address of family among values, or "".
+func familyAddress(values []string, family ipFamily) string {
+	for _, value := range values {
+		if ip := net.ParseIP(value); ip != nil && familyOf(ip) == family {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+func familyForType(recordType string) ipFamily {
+	if recordType == "AAAA" {
+		return ipv6
+	}
+	return ipv4
+}
+
+var providerAliases = map[string]map[ipFamily]string{
+	"cloudflare": {ipv4: CloudflareTraceProvider, ipv6: CloudflareTraceIPv6Provider},
+	"ipify":      {ipv4: DefaultIPProvider, ipv6: DefaultIPv6Provider},
+}
+
+// singleFamilyHosts lists provider hosts that only answer over one family.
+var singleFamilyHosts = map[string]ipFamily{
+	"api.ipify.org":  ipv4,
+	"api6.ipify.org": ipv6,
+}
+
+func resolveProvider(provider string, family ipFamily) string {
+	if urls, ok := providerAliases[strings.ToLower(provider)]; ok {
+		return urls[family]
+	}
+	return provider
+}
+
+// providerFamily reports the only family a provider can return, if known.
+func providerFamily(provider string) (ipFamily, bool) {
+	u, err := url.Parse(provider)
+	if err != nil {
+		return "", false
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return familyOf(ip), true
+	}
+	family, ok := singleFamilyHosts[host]
+	return family, ok
+}
+
+func familyOf(ip net.IP) ipFamily {
+	if ip.To4() != nil {
+		return ipv4
+	}
+	return ipv6
+}
+
+// newIPClient returns a client that only dials over the given network, so
+// dual-stack providers answer with an address of the family we asked for.
+func newIPClient(timeout time.Duration, network string) *http.Client {
+	dialer := &net.Dialer{}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, _, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// getPublicIP asks each provider in turn and returns the first valid address
+// of the requested family. When every provider fails, the whole chain is
+// retried according to the retry policy.
+func (u *Updater) getPublicIP(ctx context.Context, family ipFamily, providers []string) (string, error) {
+	if len(providers) == 0 {
+		providers = []string{resolveProvider("ipify", family)}
+	}
+
+	for attempt := 1; ; attempt++ {
+		ip, err := u.tryProviders(ctx, family, providers)
+		if err == nil || attempt >= u.cfg.Retry.Attempts {
+			return ip, err
+		}
+		if !u.budget.take() {
+			u.log.Warn("Retry budget spent, not retrying IP providers", "family", family, "budget", u.cfg.Retry.Budget)
+			return ip, err
+		}
+
+		delay := u.cfg.Retry.delay(attempt)
+		u.log.Warn("All IP providers failed, retrying", "family", family, "attempt", attempt, "delay", delay)
+		if err := sleep(ctx, delay); err != nil {
+			return "", err
+		}
+	}
+}
+
+// tryProviders returns the first valid address from the chain. A provider is
+// skipped when it can only serve the other family, the request fails, the
+// status is not 200 or the body is not an address of the requested family.
+func (u *Updater) tryProviders(ctx context.Context, family ipFamily, providers []string) (string, error) {
+	for _, provider := range providers {
+		provider = resolveProvider(provider, family)
+		if only, ok := providerFamily(provider); ok && only != family {
+			u.log.Debug("Skipping IP provider for other address family", "provider", provider, "family", family)
+			continue
+		}
+
+		ip, err := u.fetchIP(ctx, family, provider)
+		if err != nil {
+			u.log.Warn("Skipping IP provider", "provider", provider, "family", family, "error", err)
+			continue
+		}
+
+		u.log.Info("Public IP address detected", "ip", ip, "family", family, "provider", provider)
+		return ip, nil
+	}
+
+	return "", fmt.Errorf("failed to fetch public %s address: all providers failed", family)
+}
+
+func (u *Updater) fetchIP(ctx context.Context, family ipFamily, provider string) (string, error) {
+	if name, ok := interfaceProvider(provider); ok {
+		return u.interfaceIP(family, name)
+	}
+	if provider == commandProvider {
+		return u.commandIP(ctx, family)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", provider, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := u.ipClients[family].Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var ip string
+	isJSON := isJSONDocument(body)
+	switch {
+	case isTraceProvider(provider):
+		ip, err = parseTrace(string(body))
+	case u.cfg.IPProviderContentType == ContentTypeJSON && !isJSON:
+		err = fmt.Errorf("expected a JSON response, got %s: %q", describeContentType(resp), truncate(strings.TrimSpace(string(body)), 64))
+	case u.cfg.IPProviderContentType == ContentTypeText && isJSON:
+		err = fmt.Errorf("expected a text response, got JSON (%s): %q", describeContentType(resp), truncate(strings.TrimSpace(string(body)), 64))
+	case u.cfg.IPProviderRegex != nil:
+		ip, err = extractIP(u.cfg.IPProviderRegex, string(body))
+	case u.cfg.IPProviderJSONPath != "" && isJSON:
+		ip, err = extractJSONIP(u.cfg.IPProviderJSONPath, body)
+	default:
+		ip, err = parseIP(string(body))
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if got := familyOf(net.ParseIP(ip)); got != family {
+		return "", fmt.Errorf("returned %s address %s, want %s", got, ip, family)
+	}
+	return ip, nil
+}
+
+func isTraceProvider(provider string) bool {
+	return strings.HasSuffix(strings.TrimRight(provider, "/"), "/cdn-cgi/trace")
+}
+
+// parseTrace extracts the ip= field from a Cloudflare trace body, which is a
+// list of key=value lines.
+func parseTrace(body string) (string, error) {
+	for _, line := range strings.Split(body, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if ok && key == "ip" {
+			return parseIP(value)
+		}
+	}
+	return "", errors.New("trace response does not contain an ip field")
+}
+
+// isJSONDocument reports whether body is a JSON object or array. Bare JSON
+// scalars are left out, since a plain-text address can be one.
+func isJSONDocument(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed)
+}
+
+// describeContentType names the Content-Type header of resp for errors.
+func describeContentType(resp *http.Response) string {
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		return "Content-Type " + ct
+	}
+	return "no Content-Type"
+}
+
+// extractIP parses the first capture group of re's match in body.
+func extractIP(re *regexp.Regexp, body string) (string, error) {
+	m := re.FindStringSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("response does not match %q: %q", re, truncate(strings.TrimSpace(body), 64))
+	}
+	return parseIP(m[1])
+}
+
+// splitJSONPath splits a dot path, with or without a leading "$", into its
+// segments.
+func splitJSONPath(path string) ([]string, error) {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if trimmed == "" {
+		return nil, fmt.Errorf("invalid JSON path %q: no field given", path)
+	}
+	segments := strings.Split(trimmed, ".")
+	if slices.Contains(segments, "") {
+		return nil, fmt.Errorf("invalid JSON path %q: empty segment", path)
+	}
+	return segments, nil
+}
+
+// extractJSONIP parses the string at path in the JSON document body.
+func extractJSONIP(path string, body []byte) (string, error) {
+	segments, err := splitJSONPath(path)
+	if err != nil {
+		return "", err
+	}
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", fmt.Errorf("failed to decode JSON response: %w", err)
+	}
+	for _, segment := range segments {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[segment]
+		case []any:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= len(v) {
+				return "", fmt.Errorf("JSON path %s: no element %q", path, segment)
+			}
+			value = v[i]
+		default:
+			value = nil
+		}
+		if value == nil {
+			return "", fmt.Errorf("JSON path %s not found in response: %q", path, truncate(string(body), 64))
+		}
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("JSON path %s is not a string", path)
+	}
+	return parseIP(s)
+}
+
+func parseIP(body string) (string, error) {
+	value := strings.TrimSpace(body)
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return "", fmt.Errorf("response is not an IP address: %q", truncate(value, 64))
+	}
+	return ip.String(), nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func shuffleProviders(providers []string) []string {
+	shuffled := append([]string(nil), providers...)
+	rand.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	return shuffled
+}
+
+// sameContent reports whether two record contents are equal. Addresses
+// compare by value, so "2001:db8::1" matches "2001:0db8:0:0:0:0:0:1".
+func sameContent(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	return ipA != nil && ipB != nil && ipA.Equal(ipB)
+}
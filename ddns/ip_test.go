@@ -0,0 +1,219 @@
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTrace(t *testing.T) {
+	for _, c := range []struct {
+		name, body, want string
+		wantErr          bool
+	}{
+		{"ipv4", "fl=29f1\nh=1.1.1.1\nip=203.0.113.7\nts=1700000000.1\nvisit_scheme=https\n", "203.0.113.7", false},
+		{"ipv6", "h=[2606:4700:4700::1111]\nip=2001:db8::7\n", "2001:db8::7", false},
+		{"crlf and spaces", "h=1.1.1.1\r\n  ip=203.0.113.7  \r\n", "203.0.113.7", false},
+		{"missing", "fl=29f1\nh=1.1.1.1\nts=1700000000.1\n", "", true},
+		{"similar key", "client_ip=203.0.113.7\nipaddr=203.0.113.8\n", "", true},
+		{"malformed", "ip=203.0.113\n", "", true},
+		{"empty value", "ip=\n", "", true},
+		{"html", "<html><body>ip=not-an-ip</body></html>", "", true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseTrace(c.body)
+			if (err != nil) != c.wantErr || got != c.want {
+				t.Errorf("parseTrace(%q) = %q, %v; want %q, error %t", c.body, got, err, c.want, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchIPTraceProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "fl=29f1\nh=example\nip=198.51.100.20\nloc=ES\n")
+	}))
+	defer srv.Close()
+	u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", Records: []Record{{Name: "home.example.com"}}, IPProviders: []string{srv.URL + "/cdn-cgi/trace"}}, newFakeCloudflare(t))
+	got, err := u.fetchIP(context.Background(), ipv4, srv.URL+"/cdn-cgi/trace")
+	if err != nil || got != "198.51.100.20" {
+		t.Errorf("fetchIP = %q, %v; want 198.51.100.20", got, err)
+	}
+}
+
+func TestProvidersForFamilyLists(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		cfg        Config
+		ipv4, ipv6 []string
+	}{
+		{"shared only", Config{IPProviders: []string{"shared"}}, []string{"shared"}, []string{"shared"}},
+		{"IPv4 list wins", Config{IPProviders: []string{"shared"}, IPv4Providers: []string{"v4"}}, []string{"v4"}, []string{"shared"}},
+		{"IPv6 list wins", Config{IPProviders: []string{"shared"}, IPv6Providers: []string{"v6"}}, []string{"shared"}, []string{"v6"}},
+		{"both lists win", Config{IPProviders: []string{"shared"}, IPv4Providers: []string{"v4"}, IPv6Providers: []string{"v6"}}, []string{"v4"}, []string{"v6"}},
+		{"command first", Config{IPCommand: "true", IPv4Providers: []string{"v4"}}, []string{commandProvider, "v4"}, []string{commandProvider, "ipify"}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			u := &Updater{cfg: c.cfg}
+			if got := u.providersFor(ipv4); !slices.Equal(got, c.ipv4) {
+				t.Errorf("IPv4 providers = %q, want %q", got, c.ipv4)
+			}
+			if got := u.providersFor(ipv6); !slices.Equal(got, c.ipv6) {
+				t.Errorf("IPv6 providers = %q, want %q", got, c.ipv6)
+			}
+		})
+	}
+}
+
+func TestIPv4ProvidersReplaceIPProviders(t *testing.T) {
+	shared := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("IPProviders queried although IPv4Providers is set")
+		fmt.Fprint(w, "198.51.100.1")
+	}))
+	defer shared.Close()
+	v4 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "198.51.100.4")
+	}))
+	defer v4.Close()
+
+	f := newFakeCloudflare(t, "example.com")
+	u := newTestUpdater(t, Config{
+		ZoneName:      "example.com",
+		APIToken:      "token",
+		IPProviders:   []string{shared.URL},
+		IPv4Providers: []string{v4.URL},
+		Records:       []Record{{Name: "home.example.com"}},
+	}, f)
+	if result := run(t, u); result.IPv4 != "198.51.100.4" {
+		t.Errorf("detected %q, want the IPv4 provider's 198.51.100.4", result.IPv4)
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		want bool
+	}{
+		{"2001:db8::1", "2001:0db8:0000:0000:0000:0000:0000:0001", true},
+		{"2001:DB8::1", "2001:db8::1", true},
+		{"2001:db8:0:0:1::1", "2001:db8::1:0:0:1", true},
+		{"::ffff:192.0.2.1", "192.0.2.1", true},
+		{"2001:db8::1", "2001:db8::2", false},
+		{"192.0.2.1", "192.0.2.1", true},
+		{"192.0.2.1", "192.0.2.10", false},
+		{"example.net", "Example.net", false},
+		{"", "2001:db8::1", false},
+	} {
+		if got := sameContent(c.a, c.b); got != c.want {
+			t.Errorf("sameContent(%q, %q) = %t, want %t", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestRunIPv6TextualFormUnchanged(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "AAAA", Content: "2001:0db8:0000::0001", TTL: AutoTTL})
+	u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", Content: "2001:db8::1", Records: []Record{{Name: "home.example.com", Type: "AAAA"}}}, f)
+	if rr := run(t, u).Records[0]; rr.Action != ActionUnchanged {
+		t.Errorf("action %q, want %q for the same address written differently", rr.Action, ActionUnchanged)
+	}
+	if puts := f.requestsFor("PUT"); len(puts) != 0 {
+		t.Errorf("sent %d updates, want none", len(puts))
+	}
+}
+
+// serveBody starts a provider answering every request with body as
+// contentType.
+func serveBody(t *testing.T, contentType, body string) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestFetchIPRegex(t *testing.T) {
+	for _, c := range []struct {
+		name, contentType, body, pattern, want string
+	}{
+		{"json", "application/json", `{"query":"198.51.100.3","status":"success"}`, `"query":"([^"]+)"`, "198.51.100.3"},
+		{"html", "text/html", "<html><body><h1>Your IP</h1><p id=\"ip\">198.51.100.4</p></body></html>", `id="ip">([0-9.]+)<`, "198.51.100.4"},
+		{"text with label", "text/plain", "Current IP Address: 198.51.100.5\n", `Address: (\S+)`, "198.51.100.5"},
+		{"no match", "text/html", "<html><body>rate limited</body></html>", `id="ip">([0-9.]+)<`, ""},
+		{"match is not an address", "application/json", `{"query":"unknown"}`, `"query":"([^"]+)"`, ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			provider := serveBody(t, c.contentType, c.body)
+			u := newTestUpdater(t, Config{
+				ZoneName:        "example.com",
+				APIToken:        "token",
+				IPProviders:     []string{provider},
+				IPProviderRegex: regexp.MustCompile(c.pattern),
+				Records:         []Record{{Name: "home.example.com"}},
+			}, newFakeCloudflare(t))
+			got, err := u.fetchIP(context.Background(), ipv4, provider)
+			if c.want == "" {
+				if err == nil {
+					t.Errorf("fetchIP = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != c.want {
+				t.Errorf("fetchIP = %q, %v; want %s", got, err, c.want)
+			}
+		})
+	}
+}
+
+func TestFetchIPContentType(t *testing.T) {
+	for _, c := range []struct {
+		name         string
+		contentType  ProviderContentType
+		jsonPath     string
+		header, body string
+		want         string
+		wantErr      string
+	}{
+		{"auto text", ContentTypeAuto, "", "text/plain", "198.51.100.3\n", "198.51.100.3", ""},
+		{"auto JSON", ContentTypeAuto, "ip", "application/json", `{"ip": "198.51.100.3"}`, "198.51.100.3", ""},
+		{"auto JSON declared as text", ContentTypeAuto, "ip", "text/plain", `{"ip": "198.51.100.3"}`, "198.51.100.3", ""},
+		{"text", ContentTypeText, "", "text/plain", "198.51.100.3", "198.51.100.3", ""},
+		{"text with a JSON body declared as text", ContentTypeText, "", "text/plain; charset=utf-8", `{"ip": "198.51.100.3"}`, "", `expected a text response, got JSON (Content-Type text/plain; charset=utf-8)`},
+		{"text with a JSON array body", ContentTypeText, "", "text/plain", `["198.51.100.3"]`, "", "expected a text response, got JSON"},
+		{"json", ContentTypeJSON, "ip", "application/json", `{"ip": "198.51.100.3"}`, "198.51.100.3", ""},
+		{"json with a JSON body declared as text", ContentTypeJSON, "ip", "text/plain", `{"ip": "198.51.100.3"}`, "198.51.100.3", ""},
+		{"json with a text body declared as JSON", ContentTypeJSON, "ip", "application/json", "198.51.100.3", "", `expected a JSON response, got Content-Type application/json: "198.51.100.3"`},
+		{"json with an HTML error page", ContentTypeJSON, "ip", "text/html", "<html>rate limited</html>", "", "expected a JSON response, got Content-Type text/html"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			provider := serveBody(t, c.header, c.body)
+			u := newTestUpdater(t, Config{
+				ZoneName:              "example.com",
+				APIToken:              "token",
+				IPProviders:           []string{provider},
+				IPProviderJSONPath:    c.jsonPath,
+				IPProviderContentType: c.contentType,
+				Records:               []Record{{Name: "home.example.com"}},
+			}, newFakeCloudflare(t))
+			got, err := u.fetchIP(context.Background(), ipv4, provider)
+			if c.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+					t.Errorf("fetchIP = %q, %v; want an error containing %q", got, err, c.wantErr)
+				}
+				return
+			}
+			if err != nil || got != c.want {
+				t.Errorf("fetchIP = %q, %v; want %s", got, err, c.want)
+			}
+		})
+	}
+}
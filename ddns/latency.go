@@ -0,0 +1,39 @@
+package ddns
+
+import (
+	"context"
+	"time"
+)
+
+// requestTiming adds up the time Cloudflare requests made with its context
+// spend in flight, leaving out rate limiting and the backoff between
+// retries.
+type requestTiming struct {
+	attempts time.Duration
+}
+
+type requestTimingKey struct{}
+
+func withRequestTiming(ctx context.Context) (context.Context, *requestTiming) {
+	t := &requestTiming{}
+	return context.WithValue(ctx, requestTimingKey{}, t), t
+}
+
+// observeAttempt adds the time since start to the requestTiming of ctx, if
+// any.
+func observeAttempt(ctx context.Context, start time.Time) {
+	if t, ok := ctx.Value(requestTimingKey{}).(*requestTiming); ok {
+		t.attempts += time.Since(start)
+	}
+}
+
+// timeChange runs change, a call that creates, updates or deletes a record,
+// and adds its latency to rr.
+func timeChange(ctx context.Context, rr *RecordResult, change func(context.Context) error) error {
+	ctx, timing := withRequestTiming(ctx)
+	start := time.Now()
+	err := change(ctx)
+	rr.Latency += timing.attempts
+	rr.LatencyTotal += time.Since(start)
+	return err
+}
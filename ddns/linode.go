@@ -0,0 +1,168 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// linodeBaseURL is Linode's API. TTLs are rounded by Linode to the nearest
+// value it supports; AutoTTL leaves the TTL to the domain default.
+const linodeBaseURL = "https://api.linode.com/v4"
+
+type linodeProvider struct {
+	api restClient
+
+	// domainIDs caches domain name to ID lookups.
+	mu        sync.Mutex
+	domainIDs map[string]int
+}
+
+type linodePage[T any] struct {
+	Data  []T `json:"data"`
+	Page  int `json:"page"`
+	Pages int `json:"pages"`
+}
+
+type linodeDomain struct {
+	ID     int    `json:"id"`
+	Domain string `json:"domain"`
+}
+
+type linodeRecord struct {
+	ID     int    `json:"id,omitempty"`
+	Type   string `json:"type,omitempty"`
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	TTL    int    `json:"ttl_sec,omitempty"`
+}
+
+// NewLinodeProvider manages records on Linode DNS with a personal access
+// token.
+func NewLinodeProvider(token string) (Provider, error) {
+	if token == "" {
+		return nil, errors.New("Linode personal access token is required")
+	}
+	return &linodeProvider{
+		api: restClient{
+			client:  &http.Client{Timeout: DefaultHTTPTimeout},
+			baseURL: linodeBaseURL,
+			auth:    "Bearer " + token,
+		},
+		domainIDs: make(map[string]int),
+	}, nil
+}
+
+func (p *linodeProvider) Name() string {
+	return "linode"
+}
+
+// linodeList fetches every page of a Linode list endpoint.
+func linodeList[T any](ctx context.Context, api *restClient, path string) ([]T, error) {
+	var items []T
+	for page := 1; ; page++ {
+		var resp linodePage[T]
+		if err := api.do(ctx, "GET", fmt.Sprintf("%s?page=%d&page_size=500", path, page), nil, &resp); err != nil {
+			return nil, err
+		}
+		items = append(items, resp.Data...)
+		if resp.Page >= resp.Pages {
+			return items, nil
+		}
+	}
+}
+
+// domainID resolves a domain name to the ID the records API is keyed by.
+func (p *linodeProvider) domainID(ctx context.Context, zone string) (int, error) {
+	p.mu.Lock()
+	id, ok := p.domainIDs[zone]
+	p.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	domains, err := linodeList[linodeDomain](ctx, &p.api, "/domains")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list domains: %w", err)
+	}
+	for _, d := range domains {
+		if sameName(d.Domain, zone) {
+			p.mu.Lock()
+			p.domainIDs[zone] = d.ID
+			p.mu.Unlock()
+			return d.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("domain %s not found", zone)
+}
+
+// records returns the domain ID and the records of record's name and type.
+// Linode names records relative to the domain, "" being the apex.
+func (p *linodeProvider) records(ctx context.Context, record Record) (int, []linodeRecord, error) {
+	name, err := relativeName(record.Name, record.Zone)
+	if err != nil {
+		return 0, nil, err
+	}
+	domainID, err := p.domainID(ctx, record.Zone)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	all, err := linodeList[linodeRecord](ctx, &p.api, fmt.Sprintf("/domains/%d/records", domainID))
+	if err != nil {
+		return 0, nil, err
+	}
+	var matches []linodeRecord
+	for _, r := range all {
+		if r.Type == record.Type && sameName(r.Name, name) {
+			matches = append(matches, r)
+		}
+	}
+	return domainID, matches, nil
+}
+
+func (p *linodeProvider) Get(ctx context.Context, record Record) (*RRset, error) {
+	_, records, err := p.records(ctx, record)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	rrset := &RRset{TTL: records[0].TTL}
+	for _, r := range records {
+		rrset.Values = append(rrset.Values, r.Target)
+	}
+	return rrset, nil
+}
+
+// Set creates the record, or updates the first existing one and deletes
+// any others, since Linode keeps one record per value.
+func (p *linodeProvider) Set(ctx context.Context, record Record, content string) error {
+	domainID, records, err := p.records(ctx, record)
+	if err != nil {
+		return err
+	}
+	name, err := relativeName(record.Name, record.Zone)
+	if err != nil {
+		return err
+	}
+	payload := linodeRecord{Name: name, Target: content}
+	if record.TTL != AutoTTL {
+		payload.TTL = record.TTL
+	}
+
+	base := fmt.Sprintf("/domains/%d/records", domainID)
+	if len(records) == 0 {
+		payload.Type = record.Type
+		return p.api.do(ctx, "POST", base, payload, nil)
+	}
+	if err := p.api.do(ctx, "PUT", fmt.Sprintf("%s/%d", base, records[0].ID), payload, nil); err != nil {
+		return err
+	}
+	for _, r := range records[1:] {
+		if err := p.api.do(ctx, "DELETE", fmt.Sprintf("%s/%d", base, r.ID), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func newTestLinode(t *testing.T, records []linodeRecord) (*linodeProvider, *providerServer) {
+	t.Helper()
+	srv := newProviderServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != "GET":
+			writeJSON(w, map[string]any{})
+		case r.URL.Path == "/domains":
+			// The domain list spans two pages.
+			if r.URL.Query().Get("page") == "1" {
+				writeJSON(w, linodePage[linodeDomain]{Data: []linodeDomain{{ID: 11, Domain: "example.org"}}, Page: 1, Pages: 2})
+			} else {
+				writeJSON(w, linodePage[linodeDomain]{Data: []linodeDomain{{ID: 42, Domain: "Example.com"}}, Page: 2, Pages: 2})
+			}
+		case r.URL.Path == "/domains/42/records":
+			writeJSON(w, linodePage[linodeRecord]{Data: records, Page: 1, Pages: 1})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	p, err := NewLinodeProvider("pat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*linodeProvider).api.baseURL = srv.URL
+	return p.(*linodeProvider), srv
+}
+
+func TestLinodeDomainThenRecord(t *testing.T) {
+	p, srv := newTestLinode(t, []linodeRecord{
+		{ID: 7, Type: "A", Name: "home", Target: "192.0.2.1", TTL: 300},
+		{ID: 8, Type: "AAAA", Name: "home", Target: "2001:db8::1"},
+	})
+	ctx := context.Background()
+	record := Record{Name: "home.example.com", Zone: "example.com", Type: "A", TTL: 600}
+
+	rrset, err := p.Get(ctx, record)
+	if err != nil || rrset == nil || len(rrset.Values) != 1 || rrset.Values[0] != "192.0.2.1" || rrset.TTL != 300 {
+		t.Fatalf("Get = %+v, %v", rrset, err)
+	}
+	if err := p.Set(ctx, record, "198.51.100.7"); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, req := range srv.requests {
+		paths = append(paths, req.Method+" "+req.Path)
+	}
+	// The domain ID is looked up once, then cached for the Set.
+	want := []string{"GET /domains", "GET /domains", "GET /domains/42/records", "GET /domains/42/records", "PUT /domains/42/records/7"}
+	if len(paths) != len(want) {
+		t.Fatalf("requests %q, want %q", paths, want)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Errorf("request %d = %s, want %s", i, paths[i], want[i])
+		}
+	}
+	var got linodeRecord
+	json.Unmarshal(srv.requests[4].Body, &got)
+	if want := (linodeRecord{Name: "home", Target: "198.51.100.7", TTL: 600}); got != want {
+		t.Errorf("updated with %+v, want %+v", got, want)
+	}
+}
+
+func TestLinodeCreate(t *testing.T) {
+	p, srv := newTestLinode(t, nil)
+	if err := p.Set(context.Background(), Record{Name: "example.com", Zone: "example.com", Type: "AAAA", TTL: AutoTTL}, "2001:db8::7"); err != nil {
+		t.Fatal(err)
+	}
+	writes := srv.writes()
+	if len(writes) != 1 || writes[0].Method != "POST" || writes[0].Path != "/domains/42/records" {
+		t.Fatalf("writes = %+v, want one POST", writes)
+	}
+	var got linodeRecord
+	json.Unmarshal(writes[0].Body, &got)
+	if want := (linodeRecord{Type: "AAAA", Name: "", Target: "2001:db8::7"}); got != want {
+		t.Errorf("created %+v, want %+v at the apex with the default TTL", got, want)
+	}
+}
+
+func TestLinodeUnknownDomain(t *testing.T) {
+	p, _ := newTestLinode(t, nil)
+	if _, err := p.Get(context.Background(), Record{Name: "home.example.net", Zone: "example.net", Type: "A"}); err == nil {
+		t.Error("Get on an unknown domain succeeded, want an error")
+	}
+}
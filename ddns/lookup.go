@@ -0,0 +1,82 @@
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// RecordState is what the DNS host currently holds for a configured record.
+type RecordState struct {
+	Name    string
+	Type    string
+	Exists  bool
+	Content string
+}
+
+// Lookup fetches every configured record that Config.Stack manages without
+// changing anything. A record that cannot be fetched fails the whole lookup.
+func (u *Updater) Lookup(ctx context.Context) ([]RecordState, error) {
+	if u.cfg.SelectBy != SelectByName {
+		return nil, fmt.Errorf("lookup does not support selecting records by %s", u.cfg.SelectBy)
+	}
+	zoneIDs := make(map[zoneKey]string)
+	var states []RecordState
+	for _, record := range u.cfg.Records {
+		if !u.cfg.Stack.manages(record.Type) {
+			continue
+		}
+		state, err := u.lookupRecord(ctx, zoneIDs, record)
+		if err != nil {
+			return states, fmt.Errorf("record %s: %w", record.Name, err)
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+func (u *Updater) lookupRecord(ctx context.Context, zoneIDs map[zoneKey]string, record Record) (RecordState, error) {
+	state := RecordState{Name: record.Name, Type: record.Type}
+
+	if p := u.cfg.providerFor(record); p != nil {
+		rrset, err := p.Get(ctx, record)
+		if err != nil || rrset == nil {
+			return state, err
+		}
+		state.Exists = true
+		state.Content = strings.Join(rrset.Values, ",")
+		return state, nil
+	}
+
+	cf := u.cf[record.Credential]
+	key := zoneKey{record.Credential, record.Zone}
+	zoneID, ok := zoneIDs[key]
+	if !ok {
+		var err error
+		if zoneID, err = cf.getZoneID(ctx, record.Zone); err != nil {
+			return state, err
+		}
+		zoneIDs[key] = zoneID
+	}
+
+	if u.cfg.SaaS {
+		ch, err := cf.getCustomHostname(ctx, zoneID, record.Name)
+		if err != nil || ch == nil {
+			return state, err
+		}
+		state.Exists = true
+		state.Content = ch.CustomOriginServer
+		return state, nil
+	}
+
+	existing, err := cf.getRecordData(ctx, zoneID, record.Name, record.Type)
+	if err != nil || existing == nil {
+		return state, err
+	}
+	state.Exists = true
+	state.Content = existing.Content
+	if record.Type == "SRV" && existing.Data != nil {
+		state.Content = formatSRVData(*existing.Data)
+	}
+	return state, nil
+}
@@ -0,0 +1,62 @@
+package ddns
+
+import (
+	"net"
+)
+
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// placeholderRange is the IPv6 discard prefix, which Cloudflare suggests as
+// the AAAA content of records that only exist to be proxied.
+var placeholderRange = &net.IPNet{IP: net.ParseIP("100::"), Mask: net.CIDRMask(64, 128)}
+
+// isPlaceholder reports whether record, as read back from Cloudflare, holds a
+// placeholder rather than the address of a real origin. Only proxied records
+// can: an unproxied record in these ranges is a real, if private, origin.
+func isPlaceholder(record *DNSRecord) bool {
+	ip := net.ParseIP(record.Content)
+	return record.Proxied && ip != nil && (cgnatRange.Contains(ip) || placeholderRange.Contains(ip))
+}
+
+// checkCGNAT warns when the detected public IP or the address of the outbound
+// interface suggests the host sits behind carrier-grade NAT, in which case the
+// DNS record will not make it reachable from the internet.
+func (u *Updater) checkCGNAT(publicIP string) {
+	ip := net.ParseIP(publicIP)
+	if ip != nil && cgnatRange.Contains(ip) {
+		u.log.Warn("Public IP is in the CGNAT range 100.64.0.0/10, the record will not be reachable from the internet", "ip", publicIP)
+		return
+	}
+
+	localIP, err := outboundIP()
+	if err != nil {
+		u.log.Warn("CGNAT check could not determine the outbound interface address", "error", err)
+		return
+	}
+
+	switch {
+	case cgnatRange.Contains(localIP):
+		u.log.Warn("Outbound interface address is in the CGNAT range 100.64.0.0/10, your ISP is likely using carrier-grade NAT and the public IP is shared with other customers", "interface_ip", localIP, "ip", publicIP)
+	case !localIP.IsPrivate() && !localIP.IsLoopback() && !localIP.Equal(ip):
+		u.log.Warn("Outbound interface address is public but differs from the detected public IP, traffic is being translated by an upstream NAT", "interface_ip", localIP, "ip", publicIP)
+	default:
+		u.log.Info("CGNAT check found no carrier-grade NAT", "interface_ip", localIP)
+	}
+}
+
+// outboundIP returns the local IPv4 address the kernel would use to reach
+// the internet. Dialing UDP does not send any packets.
+func outboundIP() (net.IP, error) {
+	return outboundAddr("udp4", "1.1.1.1:80")
+}
+
+// outboundAddr returns the local address used to reach target over network.
+func outboundAddr(network, target string) (net.IP, error) {
+	conn, err := net.Dial(network, target)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
@@ -0,0 +1,315 @@
+package ddns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is sent to notifiers when a run changes a record or fails.
+type Event struct {
+	Time    time.Time
+	Summary string
+	Result  *Result
+	Err     error
+}
+
+// Notifier delivers events to an external service.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+type webhookNotifier struct {
+	name   string
+	url    string
+	client *http.Client
+	body   func(Event) any
+}
+
+func (n *webhookNotifier) Name() string {
+	return n.name
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, event Event) error {
+	jsonData, err := json.Marshal(n.body(event))
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(jsonData))
+	if err != nil {
+		return errors.New("failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// NewDiscordNotifier posts events to a Discord webhook.
+func NewDiscordNotifier(webhookURL string) (Notifier, error) {
+	u, err := parseWebhookURL(webhookURL, true)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Host != "discord.com" && u.Host != "discordapp.com") || !strings.HasPrefix(u.Path, "/api/webhooks/") {
+		return nil, errors.New("not a Discord webhook URL: expected https://discord.com/api/webhooks/...")
+	}
+	return &webhookNotifier{
+		name:   "discord",
+		url:    webhookURL,
+		client: &http.Client{Timeout: DefaultHTTPTimeout},
+		body: func(e Event) any {
+			return map[string]string{"content": e.Summary}
+		},
+	}, nil
+}
+
+// NewSlackNotifier posts events to a Slack incoming webhook.
+func NewSlackNotifier(webhookURL string) (Notifier, error) {
+	u, err := parseWebhookURL(webhookURL, true)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host != "hooks.slack.com" {
+		return nil, errors.New("not a Slack webhook URL: expected https://hooks.slack.com/...")
+	}
+	return &webhookNotifier{
+		name:   "slack",
+		url:    webhookURL,
+		client: &http.Client{Timeout: DefaultHTTPTimeout},
+		body: func(e Event) any {
+			return map[string]string{"text": e.Summary}
+		},
+	}, nil
+}
+
+// NewWebhookNotifier posts events as JSON to an arbitrary HTTP endpoint.
+func NewWebhookNotifier(endpoint string) (Notifier, error) {
+	if _, err := parseWebhookURL(endpoint, false); err != nil {
+		return nil, err
+	}
+	return &webhookNotifier{
+		name:   "webhook",
+		url:    endpoint,
+		client: &http.Client{Timeout: DefaultHTTPTimeout},
+		body:   webhookBody,
+	}, nil
+}
+
+type webhookRecord struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Action   Action `json:"action"`
+	Previous string `json:"previous,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func webhookBody(e Event) any {
+	body := struct {
+		Time    time.Time       `json:"time"`
+		Summary string          `json:"summary"`
+		Error   string          `json:"error,omitempty"`
+		Records []webhookRecord `json:"records,omitempty"`
+	}{Time: e.Time, Summary: e.Summary}
+	if e.Err != nil {
+		body.Error = e.Err.Error()
+	}
+	if e.Result != nil {
+		for _, r := range e.Result.Records {
+			wr := webhookRecord{Name: r.Name, Type: r.Type, Action: r.Action, Previous: r.Previous, Content: r.Content}
+			if r.Err != nil {
+				wr.Error = r.Err.Error()
+			}
+			body.Records = append(body.Records, wr)
+		}
+	}
+	return body
+}
+
+func parseWebhookURL(raw string, httpsOnly bool) (*url.URL, error) {
+	// Webhook URLs embed credentials, so errors never include them.
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.New("invalid URL")
+	}
+	if u.Host == "" {
+		return nil, errors.New("invalid URL: missing host")
+	}
+	if u.Scheme != "https" && (httpsOnly || u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid URL: unsupported scheme %q", u.Scheme)
+	}
+	return u, nil
+}
+
+// notify sends an event when the run failed or changed at least one record.
+// Notifiers run in parallel, at most Config.NotifyConcurrency at a time, and
+// each is abandoned after Config.NotifyTimeout. Notifier failures are logged
+// and never affect the run.
+func (u *Updater) notify(ctx context.Context, result *Result, runErr error) {
+	if len(u.cfg.Notifiers) == 0 {
+		return
+	}
+
+	now := time.Now()
+	summary, ok := summarize(result, runErr)
+	summary, ok = u.dedupe(now, summary, ok, result, runErr)
+	if !ok {
+		return
+	}
+	event := Event{Time: now, Summary: summary, Result: result, Err: runErr}
+
+	limit := u.cfg.NotifyConcurrency
+	if limit == 0 {
+		limit = len(u.cfg.Notifiers)
+	}
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for _, n := range u.cfg.Notifiers {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := u.notifyOne(ctx, n, event); err != nil {
+				u.log.Warn("Notification failed", "notifier", n.Name(), "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// notifyOne sends event to n, giving up after Config.NotifyTimeout even if
+// n does not honor its context.
+func (u *Updater) notifyOne(ctx context.Context, n Notifier, event Event) error {
+	ctx, cancel := context.WithTimeout(ctx, u.cfg.NotifyTimeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- n.Notify(ctx, event) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("no answer within %s: %w", u.cfg.NotifyTimeout, ctx.Err())
+	}
+}
+
+// dedupe suppresses a notification that only repeats the failure of the
+// previous one, until Config.NotifyRepeatInterval passes, and turns the
+// first run after a failure into a "resolved" notification. It returns
+// the summary to send and whether to send it.
+func (u *Updater) dedupe(now time.Time, summary string, ok bool, result *Result, runErr error) (string, bool) {
+	failure := failureKey(result, runErr)
+	u.notifyMu.Lock()
+	defer u.notifyMu.Unlock()
+
+	switch {
+	case failure == "" && u.failure != "":
+		u.failure = ""
+		resolved := "Resolved: the previous failure cleared"
+		if !ok {
+			return "DDNS update\n" + resolved, true
+		}
+		return summary + "\n" + resolved, true
+	case failure == "":
+		return summary, ok
+	case failure == u.failure && !changedRecords(result) &&
+		(u.cfg.NotifyRepeatInterval == 0 || now.Sub(u.failureSent) < u.cfg.NotifyRepeatInterval):
+		u.log.Debug("Notification suppressed, same failure as last time", "since", u.failureSent)
+		return "", false
+	}
+	u.failure, u.failureSent = failure, now
+	return summary, ok
+}
+
+// failureKey identifies what failed in a run, "" when nothing did. A
+// detected address rejected by Config.AllowedIPRanges counts as a failure.
+func failureKey(result *Result, runErr error) string {
+	var lines []string
+	if result != nil {
+		for _, r := range result.Records {
+			switch {
+			case r.Action == ActionFailed:
+				lines = append(lines, fmt.Sprintf("%s %s: %v", r.Type, r.Name, r.Err))
+			case notAllowed(r):
+				lines = append(lines, fmt.Sprintf("%s %s: %s", r.Type, r.Name, r.Reason))
+			}
+		}
+	}
+	if len(lines) == 0 && runErr != nil {
+		return runErr.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// notAllowed reports whether r was skipped because Config.AllowedIPRanges
+// rejected the detected address. Other skips are configured or expected
+// and not worth notifying about.
+func notAllowed(r RecordResult) bool {
+	return r.Action == ActionSkipped && r.Reason == reasonNotAllowed
+}
+
+func changedRecords(result *Result) bool {
+	if result == nil {
+		return false
+	}
+	for _, r := range result.Records {
+		if r.Action == ActionCreated || r.Action == ActionUpdated {
+			return true
+		}
+	}
+	return false
+}
+
+// summarize describes a run for humans. ok is false when there is nothing
+// worth notifying about.
+func summarize(result *Result, runErr error) (summary string, ok bool) {
+	var lines []string
+	if result != nil {
+		for _, r := range result.Records {
+			switch r.Action {
+			case ActionCreated:
+				lines = append(lines, fmt.Sprintf("%s %s created: %s", r.Type, r.Name, r.Content))
+			case ActionUpdated:
+				lines = append(lines, fmt.Sprintf("%s %s updated: %s -> %s", r.Type, r.Name, r.Previous, r.Content))
+			case ActionSkipped:
+				if notAllowed(r) {
+					lines = append(lines, fmt.Sprintf("%s %s skipped: %s", r.Type, r.Name, r.Reason))
+				}
+			case ActionFailed:
+				lines = append(lines, fmt.Sprintf("%s %s failed: %v", r.Type, r.Name, r.Err))
+			}
+		}
+	}
+	if runErr != nil && len(lines) == 0 {
+		lines = append(lines, fmt.Sprintf("Update failed: %v", runErr))
+	}
+	if len(lines) == 0 {
+		return "", false
+	}
+	return "DDNS update\n" + strings.Join(lines, "\n"), true
+}
@@ -0,0 +1,251 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fanout is shared by mockNotifiers to count how many notify at once.
+type fanout struct {
+	mu        sync.Mutex
+	active    int
+	maxActive int
+	// full is closed once want notifiers are active together.
+	want int
+	full chan struct{}
+}
+
+// mockNotifier records its events and holds each one until every notifier
+// of its fanout is active, or hold passes.
+type mockNotifier struct {
+	name   string
+	fanout *fanout
+	hold   time.Duration
+
+	mu     sync.Mutex
+	events []Event
+}
+
+func (n *mockNotifier) Name() string { return n.name }
+
+func (n *mockNotifier) Notify(ctx context.Context, event Event) error {
+	n.mu.Lock()
+	n.events = append(n.events, event)
+	n.mu.Unlock()
+	if n.fanout == nil {
+		return nil
+	}
+
+	f := n.fanout
+	f.mu.Lock()
+	f.active++
+	f.maxActive = max(f.maxActive, f.active)
+	if f.active == f.want {
+		close(f.full)
+	}
+	f.mu.Unlock()
+	select {
+	case <-f.full:
+	case <-time.After(n.hold):
+	}
+	f.mu.Lock()
+	f.active--
+	f.mu.Unlock()
+	return nil
+}
+
+func (n *mockNotifier) sent() []Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.events
+}
+
+func TestNotifyConcurrency(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"capped", 2, 2},
+		{"uncapped", 0, 5},
+		{"cap above the notifier count", 10, 5},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := &fanout{want: 5, full: make(chan struct{})}
+			var notifiers []Notifier
+			var mocks []*mockNotifier
+			for i := range 5 {
+				n := &mockNotifier{name: fmt.Sprintf("mock%d", i), fanout: f, hold: 50 * time.Millisecond}
+				notifiers = append(notifiers, n)
+				mocks = append(mocks, n)
+			}
+			cf := newFakeCloudflare(t, "example.com")
+			cf.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+			u := newTestUpdater(t, Config{
+				ZoneName:          "example.com",
+				APIToken:          "token",
+				Records:           []Record{{Name: "home.example.com"}},
+				Notifiers:         notifiers,
+				NotifyConcurrency: c.limit,
+			}, cf)
+			run(t, u)
+
+			for _, n := range mocks {
+				if got := len(n.sent()); got != 1 {
+					t.Errorf("%s got %d events, want 1", n.name, got)
+				}
+			}
+			if f.maxActive != c.want {
+				t.Errorf("%d notifiers active at once, want %d", f.maxActive, c.want)
+			}
+		})
+	}
+}
+
+func TestNotifyDedupe(t *testing.T) {
+	failed := func(msg string) (*Result, error) {
+		err := errors.New(msg)
+		return &Result{Records: []RecordResult{{Name: "home.example.com", Type: "A", Action: ActionFailed, Err: err}}}, err
+	}
+	unchanged := &Result{Records: []RecordResult{{Name: "home.example.com", Type: "A", Action: ActionUnchanged}}}
+	updated := &Result{Records: []RecordResult{{Name: "home.example.com", Type: "A", Action: ActionUpdated, Previous: "192.0.2.1", Content: "198.51.100.1"}}}
+	timeout, timeoutErr := failed("timeout")
+	forbidden, forbiddenErr := failed("forbidden")
+	withChange := &Result{Records: append(slices.Clone(timeout.Records), RecordResult{Name: "vpn.example.com", Type: "A", Action: ActionCreated, Content: "198.51.100.1"})}
+	rejected := &Result{Records: []RecordResult{{Name: "home.example.com", Type: "A", Action: ActionSkipped, Reason: reasonNotAllowed}}}
+	deferred := &Result{Records: []RecordResult{{Name: "home.example.com", Type: "A", Action: ActionSkipped, Reason: reasonOutsideWindow}}}
+
+	start := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	type step struct {
+		at     time.Duration
+		result *Result
+		err    error
+		// want is the notification sent, "" when none is.
+		want string
+	}
+	for _, c := range []struct {
+		name     string
+		interval time.Duration
+		steps    []step
+	}{
+		{"repeat after the interval", time.Hour, []step{
+			{0, timeout, timeoutErr, "DDNS update\nA home.example.com failed: timeout"},
+			{time.Minute, timeout, timeoutErr, ""},
+			{59 * time.Minute, timeout, timeoutErr, ""},
+			{61 * time.Minute, timeout, timeoutErr, "DDNS update\nA home.example.com failed: timeout"},
+			{62 * time.Minute, timeout, timeoutErr, ""},
+		}},
+		{"quiet until cleared", 0, []step{
+			{0, timeout, timeoutErr, "DDNS update\nA home.example.com failed: timeout"},
+			{24 * time.Hour, timeout, timeoutErr, ""},
+			{25 * time.Hour, unchanged, nil, "DDNS update\nResolved: the previous failure cleared"},
+			{26 * time.Hour, unchanged, nil, ""},
+			{27 * time.Hour, timeout, timeoutErr, "DDNS update\nA home.example.com failed: timeout"},
+		}},
+		{"another failure is sent", 0, []step{
+			{0, timeout, timeoutErr, "DDNS update\nA home.example.com failed: timeout"},
+			{time.Minute, forbidden, forbiddenErr, "DDNS update\nA home.example.com failed: forbidden"},
+			{2 * time.Minute, forbidden, forbiddenErr, ""},
+		}},
+		{"changes are always sent", 0, []step{
+			{0, timeout, timeoutErr, "DDNS update\nA home.example.com failed: timeout"},
+			{time.Minute, withChange, timeoutErr, "DDNS update\nA home.example.com failed: timeout\nA vpn.example.com created: 198.51.100.1"},
+		}},
+		{"resolved with a change", 0, []step{
+			{0, timeout, timeoutErr, "DDNS update\nA home.example.com failed: timeout"},
+			{time.Minute, updated, nil, "DDNS update\nA home.example.com updated: 192.0.2.1 -> 198.51.100.1\nResolved: the previous failure cleared"},
+			{2 * time.Minute, unchanged, nil, ""},
+		}},
+		{"rejected addresses are deduped", 0, []step{
+			{0, rejected, nil, "DDNS update\nA home.example.com skipped: " + reasonNotAllowed},
+			{time.Minute, rejected, nil, ""},
+			{2 * time.Minute, unchanged, nil, "DDNS update\nResolved: the previous failure cleared"},
+		}},
+		{"expected skips are not sent", 0, []step{
+			{0, deferred, nil, ""},
+			{time.Minute, deferred, nil, ""},
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			u := &Updater{cfg: Config{NotifyRepeatInterval: c.interval}, log: testLogger()}
+			for i, s := range c.steps {
+				summary, ok := summarize(s.result, s.err)
+				got, ok := u.dedupe(start.Add(s.at), summary, ok, s.result, s.err)
+				if !ok {
+					got = ""
+				}
+				if got != s.want {
+					t.Errorf("step %d at %s: sent %q, want %q", i+1, s.at, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRunNotifiesResolved(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: testIP, TTL: AutoTTL})
+	var down atomic.Bool
+	down.Store(true)
+	f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		if !down.Load() || r.URL.Path != "/zones/zone-example.com/dns_records" {
+			return false
+		}
+		writeError(w, http.StatusForbidden, 10000, "Authentication error")
+		return true
+	}
+	n := &mockNotifier{name: "mock"}
+	u := newTestUpdater(t, Config{
+		ZoneName:  "example.com",
+		APIToken:  "token",
+		Records:   []Record{{Name: "home.example.com"}},
+		Notifiers: []Notifier{n},
+	}, f)
+
+	for range 3 {
+		if _, err := u.Run(context.Background()); err == nil {
+			t.Fatal("Run succeeded, want the listing to fail")
+		}
+	}
+	if got := len(n.sent()); got != 1 {
+		t.Fatalf("%d notifications for a repeated failure, want 1", got)
+	}
+	down.Store(false)
+	for range 2 {
+		run(t, u)
+	}
+	events := n.sent()
+	if len(events) != 2 || !strings.Contains(events[1].Summary, "Resolved") {
+		t.Errorf("notifications %+v, want one failure then one resolved", events)
+	}
+}
+
+func TestRunStackSkipNotNotified(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "198.51.100.1", TTL: AutoTTL})
+	n := &mockNotifier{name: "mock"}
+	u := newTestUpdater(t, Config{
+		ZoneName:    "example.com",
+		APIToken:    "token",
+		Stack:       StackV4,
+		OverrideIPs: []string{"198.51.100.1", "2001:db8::1"},
+		Records:     []Record{{Name: "home.example.com", Type: "A"}, {Name: "home.example.com", Type: "AAAA"}},
+		Notifiers:   []Notifier{n},
+	}, f)
+
+	result := run(t, u)
+	if rr := result.Records[1]; rr.Action != ActionSkipped {
+		t.Fatalf("AAAA action %q, want %q", rr.Action, ActionSkipped)
+	}
+	if events := n.sent(); len(events) != 0 {
+		t.Errorf("sent %q, want no notification", events[0].Summary)
+	}
+}
@@ -0,0 +1,158 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// preflightTimeout bounds each DNS lookup and TCP dial of Preflight.
+const preflightTimeout = 3 * time.Second
+
+// endpointer is implemented by providers that can report their API base
+// URL for Preflight.
+type endpointer interface {
+	endpoint() string
+}
+
+func (c *restClient) endpoint() string {
+	return c.baseURL
+}
+
+func (p *desecProvider) endpoint() string  { return p.api.endpoint() }
+func (p *gandiProvider) endpoint() string  { return p.api.endpoint() }
+func (p *vultrProvider) endpoint() string  { return p.api.endpoint() }
+func (p *linodeProvider) endpoint() string { return p.api.endpoint() }
+
+// Preflight checks that the DNS API and the IP providers in use resolve and
+// accept TCP connections, so an unreachable endpoint is reported up front
+// instead of midway through a run. An unreachable IP provider is only an
+// error when no provider of its family is reachable.
+func (u *Updater) Preflight(ctx context.Context) error {
+	var errs []error
+
+	if api := u.apiEndpoint(); api != "" {
+		if err := checkEndpoint(ctx, "tcp", api); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if u.cfg.Content == "" {
+		for _, family := range u.families() {
+			network := "tcp4"
+			if family == ipv6 {
+				network = "tcp6"
+			}
+			providers := u.providersFor(family)
+			if len(providers) == 0 {
+				providers = []string{"ipify"}
+			}
+
+			reachable := false
+			var familyErrs []error
+			for _, provider := range providers {
+				provider = resolveProvider(provider, family)
+				if only, ok := providerFamily(provider); ok && only != family {
+					continue
+				}
+				if _, ok := interfaceProvider(provider); ok || provider == commandProvider {
+					reachable = true
+					continue
+				}
+				if err := checkEndpoint(ctx, network, provider); err != nil {
+					u.log.Warn("IP provider failed preflight", "provider", provider, "family", family, "error", err)
+					familyErrs = append(familyErrs, err)
+					continue
+				}
+				reachable = true
+			}
+			if !reachable {
+				errs = append(errs, fmt.Errorf("no %s IP provider is reachable: %w", family, errors.Join(familyErrs...)))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("preflight failed: %w", errors.Join(errs...))
+	}
+	u.log.Info("Preflight passed")
+	return nil
+}
+
+// WaitForNetwork blocks until the DNS API resolves and accepts a TCP
+// connection, retrying with the backoff of the retry policy, so a run
+// started before the network is up does not fail straight away. It gives up
+// after timeout, returning the last error.
+func (u *Updater) WaitForNetwork(ctx context.Context, timeout time.Duration) error {
+	api := u.apiEndpoint()
+	if api == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := checkEndpoint(ctx, "tcp", api)
+		if err == nil {
+			if attempt > 1 {
+				u.log.Info("Network is up", "waited", time.Since(start).Round(time.Millisecond))
+			}
+			return nil
+		}
+
+		delay := u.cfg.Retry.delay(attempt)
+		u.log.Warn("Network is not up yet, waiting", "endpoint", api, "attempt", attempt, "delay", delay, "error", err)
+		if sleep(ctx, delay) != nil {
+			return fmt.Errorf("network not up after %s: %w", timeout, err)
+		}
+	}
+}
+
+// apiEndpoint returns the base URL of the DNS API in use, or "" when the
+// provider does not report one.
+func (u *Updater) apiEndpoint() string {
+	if u.cfg.Provider == nil {
+		return cloudflareBaseURL
+	}
+	if e, ok := u.cfg.Provider.(endpointer); ok {
+		return e.endpoint()
+	}
+	return ""
+}
+
+// checkEndpoint resolves the host of rawURL and opens a TCP connection to
+// it over network.
+func checkEndpoint(ctx context.Context, network, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || target.Host == "" {
+		return fmt.Errorf("invalid URL %q", rawURL)
+	}
+	host, port := target.Hostname(), target.Port()
+	if port == "" {
+		port = "443"
+		if target.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+
+	if net.ParseIP(host) == nil {
+		if _, err := net.DefaultResolver.LookupHost(ctx, host); err != nil {
+			return fmt.Errorf("cannot resolve %s: check DNS settings: %w", host, err)
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(host, port))
+	if err != nil {
+		return fmt.Errorf("cannot reach %s: check network and firewall: %w", net.JoinHostPort(host, port), err)
+	}
+	conn.Close()
+	return nil
+}
@@ -0,0 +1,99 @@
+package ddns
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// newPreflightUpdater returns an updater whose DNS API is the plain HTTP
+// endpoint addr.
+func newPreflightUpdater(t *testing.T, addr string) *Updater {
+	t.Helper()
+	provider, err := NewLinodeProvider("pat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.(*linodeProvider).api.baseURL = "http://" + addr
+	u, err := New(Config{
+		ZoneName: "example.com",
+		Provider: provider,
+		Content:  "192.0.2.1",
+		Records:  []Record{{Name: "home.example.com"}},
+		Retry:    RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond},
+		Logger:   testLogger(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// freeAddr returns a loopback address nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return addr
+}
+
+func TestWaitForNetworkDelayedReachability(t *testing.T) {
+	addr := freeAddr(t)
+	u := newPreflightUpdater(t, addr)
+
+	// The endpoint starts listening only after a few failed attempts.
+	listening := make(chan net.Listener, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			close(listening)
+			return
+		}
+		listening <- l
+	}()
+
+	start := time.Now()
+	err := u.WaitForNetwork(context.Background(), 5*time.Second)
+	l, ok := <-listening
+	if !ok {
+		t.Skip("cannot listen on the reserved address again")
+	}
+	defer l.Close()
+	if err != nil {
+		t.Fatalf("WaitForNetwork: %v", err)
+	}
+	if waited := time.Since(start); waited < 100*time.Millisecond {
+		t.Errorf("WaitForNetwork returned after %s, before the endpoint was up", waited)
+	}
+}
+
+func TestWaitForNetworkTimeout(t *testing.T) {
+	u := newPreflightUpdater(t, freeAddr(t))
+
+	start := time.Now()
+	if err := u.WaitForNetwork(context.Background(), 150*time.Millisecond); err == nil {
+		t.Fatal("WaitForNetwork succeeded with nothing listening, want an error")
+	}
+	if waited := time.Since(start); waited > 2*time.Second {
+		t.Errorf("WaitForNetwork gave up after %s, want about the 150ms timeout", waited)
+	}
+}
+
+func TestWaitForNetworkReachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	u := newPreflightUpdater(t, l.Addr().String())
+
+	if err := u.WaitForNetwork(context.Background(), time.Second); err != nil {
+		t.Errorf("WaitForNetwork: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package ddns
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultPropagationResolvers are queried when PropagationCheck.Resolvers
+// is empty.
+var DefaultPropagationResolvers = []string{"1.1.1.1", "8.8.8.8"}
+
+// PropagationCheck makes the updater wait, after creating or updating an A
+// or AAAA record, until public resolvers return the new address.
+type PropagationCheck struct {
+	// Resolvers are DNS server addresses, with or without a port.
+	Resolvers []string
+	// Timeout bounds the wait for each record. Defaults to 2 minutes.
+	Timeout time.Duration
+	// Interval is the delay between queries. Defaults to 5 seconds.
+	Interval time.Duration
+}
+
+func (p *PropagationCheck) setDefaults() {
+	if len(p.Resolvers) == 0 {
+		p.Resolvers = DefaultPropagationResolvers
+	}
+	if p.Timeout == 0 {
+		p.Timeout = 2 * time.Minute
+	}
+	if p.Interval == 0 {
+		p.Interval = 5 * time.Second
+	}
+}
+
+// waitForPropagation polls every resolver until it returns ip for the
+// record or the timeout expires. It reports whether all resolvers agreed.
+func (u *Updater) waitForPropagation(ctx context.Context, record Record, ip string) bool {
+	check := u.cfg.Propagation
+	if !isIPType(record.Type) {
+		return true
+	}
+	if record.Proxied {
+		u.log.Info("Skipping propagation check for proxied record, resolvers return Cloudflare addresses", "record", record.Name)
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	results := make([]bool, len(check.Resolvers))
+	for i, server := range check.Resolvers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = u.pollResolver(ctx, server, record, ip)
+		}()
+	}
+	wg.Wait()
+
+	return !slices.Contains(results, false)
+}
+
+func (u *Updater) pollResolver(ctx context.Context, server string, record Record, ip string) bool {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+	network := "ip4"
+	if record.Type == "AAAA" {
+		network = "ip6"
+	}
+	want := net.ParseIP(ip)
+
+	ticker := time.NewTicker(u.cfg.Propagation.Interval)
+	defer ticker.Stop()
+	for {
+		addrs, err := resolver.LookupIP(ctx, network, record.Name)
+		if err == nil && slices.ContainsFunc(addrs, want.Equal) {
+			u.log.Info("Record propagated", "record", record.Name, "resolver", server, "ip", ip)
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			u.log.Warn("Record did not propagate before the timeout", "record", record.Name, "resolver", server, "want", ip, "got", addrs, "error", err)
+			return false
+		case <-ticker.C:
+			u.log.Debug("Waiting for propagation", "record", record.Name, "resolver", server, "got", addrs, "error", err)
+		}
+	}
+}
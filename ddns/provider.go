@@ -0,0 +1,190 @@
+package ddns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Provider keeps address records up to date on a DNS host other than
+// Cloudflare. Record.Zone is the provider's domain and Record.Name the full
+// record name within it.
+type Provider interface {
+	Name() string
+	// Get returns the record set of record's name and type, or nil when it
+	// does not exist.
+	Get(ctx context.Context, record Record) (*RRset, error)
+	// Set replaces the record set with the single value content, creating
+	// it when needed. A record.TTL of AutoTTL selects the provider default.
+	Set(ctx context.Context, record Record, content string) error
+}
+
+// RRset is every value a provider holds for one name and type.
+type RRset struct {
+	Values []string
+	TTL    int
+}
+
+var errNotFound = errors.New("not found")
+
+// restClient is the HTTP plumbing shared by the providers.
+type restClient struct {
+	client  *http.Client
+	baseURL string
+	// auth is the Authorization header value.
+	auth string
+}
+
+// do sends body as JSON and decodes a successful response into out, which
+// may be nil. A 404 response returns errNotFound.
+func (c *restClient) do(ctx context.Context, method, path string, body, out any) error {
+	var bodyReader io.Reader
+	if body != nil {
+		jsonData, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", c.auth)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// relativeName returns name relative to zone, "" for the zone apex.
+func relativeName(name, zone string) (string, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	zone = strings.TrimSuffix(strings.ToLower(zone), ".")
+	if name == zone {
+		return "", nil
+	}
+	sub, ok := strings.CutSuffix(name, "."+zone)
+	if !ok || sub == "" {
+		return "", fmt.Errorf("record %s is not in zone %s", name, zone)
+	}
+	return sub, nil
+}
+
+func (u *Updater) syncProviderRecord(ctx context.Context, p Provider, record Record, content string) RecordResult {
+	rr := RecordResult{Name: record.Name, Type: record.Type, Content: content}
+	fail := func(err error) RecordResult {
+		rr.Action = ActionFailed
+		rr.Err = err
+		return rr
+	}
+
+	existing, err := p.Get(ctx, record)
+	if err != nil {
+		return fail(fmt.Errorf("%s: failed to fetch record: %w", p.Name(), err))
+	}
+	if u.cfg.DryRun {
+		rr.Diff = rrsetDiff(record, existing, content)
+	}
+
+	if existing == nil {
+		if u.cfg.OnMissing != MissingCreate {
+			return u.missingRecord(rr)
+		}
+		if u.cfg.DryRun {
+			u.log.Info("Dry run, record would be created", "record", record.Name, "type", record.Type)
+			rr.Action = ActionCreated
+			return rr
+		}
+		if u.deferChange(&rr) {
+			return rr
+		}
+		u.log.Info("Record does not exist, creating", "record", record.Name, "type", record.Type, "provider", p.Name())
+		err := p.Set(ctx, record, content)
+		u.audit(AuditEntry{Actor: "provider:" + p.Name(), Op: "create", Record: record.Name, Type: record.Type, New: content}, err)
+		if err != nil {
+			return fail(fmt.Errorf("%s: failed to create record: %w", p.Name(), err))
+		}
+		rr.Action = ActionCreated
+		u.checkPropagation(ctx, record, &rr)
+		return rr
+	}
+	rr.Previous = strings.Join(existing.Values, ",")
+
+	if len(existing.Values) == 1 && sameContent(existing.Values[0], content) {
+		u.log.Info("Record not changed", "record", record.Name, "content", content)
+		rr.Action = ActionUnchanged
+		return rr
+	}
+	rr.Changes = []string{fmt.Sprintf("content %s -> %s", rr.Previous, content)}
+	if !u.confirmStable(ctx, record, content) {
+		rr.Action = ActionSkipped
+		rr.Reason = reasonUnstable
+		return rr
+	}
+
+	if u.cfg.DryRun {
+		u.log.Info("Dry run, record would be updated", "record", record.Name, "changes", rr.Changes[0])
+		rr.Action = ActionUpdated
+		return rr
+	}
+	if u.deferChange(&rr) {
+		return rr
+	}
+	if record.TTL == AutoTTL {
+		record.TTL = existing.TTL
+	}
+	u.log.Info("Record changed, updating", "record", record.Name, "changes", rr.Changes[0], "provider", p.Name())
+	err = p.Set(ctx, record, content)
+	u.audit(AuditEntry{Actor: "provider:" + p.Name(), Op: "update", Record: record.Name, Type: record.Type, Old: rr.Previous, New: content}, err)
+	if err != nil {
+		return fail(fmt.Errorf("%s: failed to update record: %w", p.Name(), err))
+	}
+	rr.Action = ActionUpdated
+	u.checkPropagation(ctx, record, &rr)
+	return rr
+}
+
+func rrsetDiff(record Record, existing *RRset, content string) []FieldDiff {
+	diff := []FieldDiff{
+		{Field: "type", Desired: record.Type},
+		{Field: "content", Desired: content},
+		{Field: "ttl", Desired: formatTTL(record.TTL)},
+	}
+	if existing != nil {
+		diff[0].Current = record.Type
+		diff[1].Current = strings.Join(existing.Values, ",")
+		diff[2].Current = formatTTL(existing.TTL)
+		if record.TTL == AutoTTL {
+			diff[2].Desired = diff[2].Current
+		}
+	}
+	return diff
+}
@@ -0,0 +1,70 @@
+package ddns
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// providerServer is a DNS host API answering with handler and recording
+// the requests it received.
+type providerServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []fakeRequest
+}
+
+func newProviderServer(t *testing.T, handler http.HandlerFunc) *providerServer {
+	t.Helper()
+	s := &providerServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, fakeRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone(), Body: body})
+		s.mu.Unlock()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// writes returns the requests other than GETs.
+func (s *providerServer) writes() []fakeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []fakeRequest
+	for _, req := range s.requests {
+		if req.Method != "GET" {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestRelativeName(t *testing.T) {
+	for _, c := range []struct {
+		name, zone, want string
+		wantErr          bool
+	}{
+		{"example.com", "example.com", "", false},
+		{"Home.Example.com.", "example.com", "home", false},
+		{"a.b.example.com", "example.com.", "a.b", false},
+		{"example.org", "example.com", "", true},
+		{"badexample.com", "example.com", "", true},
+	} {
+		got, err := relativeName(c.name, c.zone)
+		if (err != nil) != c.wantErr || got != c.want {
+			t.Errorf("relativeName(%q, %q) = %q, %v; want %q, error %t", c.name, c.zone, got, err, c.want, c.wantErr)
+		}
+	}
+}
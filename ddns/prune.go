@@ -0,0 +1,119 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// PrunedRecord is a zone record selected for deletion by Prune.
+type PrunedRecord struct {
+	Name    string
+	Type    string
+	Content string
+	// Deleted reports whether the record was removed. It is always false
+	// on a dry run.
+	Deleted bool
+	Err     error
+}
+
+// Prune deletes records in the configured zones whose name matches pattern
+// but is not a configured record name. Only records of the configured types
+// that Config.Stack manages are considered. pattern uses path.Match syntax,
+// e.g. "*.dyn.example.com", and the literal text after its last wildcard must
+// name a subdomain of a configured zone, so "*.com" or "*.example.com" for
+// zone example.com are rejected. Only zones holding that subdomain are
+// pruned. Unless confirm is set nothing is deleted and the records that
+// would be are returned.
+func (u *Updater) Prune(ctx context.Context, pattern string, confirm bool) ([]PrunedRecord, error) {
+	if u.cfg.SaaS {
+		return nil, errors.New("prune does not support custom hostnames")
+	}
+	if u.cfg.SelectBy != SelectByName {
+		return nil, fmt.Errorf("prune does not support selecting records by %s", u.cfg.SelectBy)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid prune pattern %q: %w", pattern, err)
+	}
+
+	keep := make(map[string]bool)
+	types := make(map[string]bool)
+	var zones []zoneKey
+	for _, record := range u.cfg.Records {
+		if p := u.cfg.providerFor(record); p != nil {
+			return nil, fmt.Errorf("prune is not supported by provider %s", p.Name())
+		}
+		keep[strings.ToLower(record.Name)] = true
+		types[record.Type] = u.cfg.Stack.manages(record.Type)
+		key := zoneKey{record.Credential, record.Zone}
+		if !prunesWithin(pattern, record.Zone) {
+			continue
+		}
+		if !slices.Contains(zones, key) {
+			zones = append(zones, key)
+		}
+	}
+
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("prune pattern %q must end in a subdomain of a configured zone, e.g. *.dyn.example.com", pattern)
+	}
+
+	var pruned []PrunedRecord
+	for _, key := range zones {
+		cf := u.cf[key.credential]
+		zoneID, err := cf.getZoneID(ctx, key.zone)
+		if err != nil {
+			return pruned, fmt.Errorf("zone %s: %w", key.zone, err)
+		}
+		records, err := cf.listDNSRecords(ctx, zoneID)
+		if err != nil {
+			return pruned, fmt.Errorf("zone %s: %w", key.zone, err)
+		}
+
+		for _, r := range records {
+			name := strings.ToLower(r.Name)
+			if keep[name] || !types[r.Type] {
+				continue
+			}
+			if ok, _ := path.Match(strings.ToLower(pattern), name); !ok {
+				continue
+			}
+
+			pr := PrunedRecord{Name: r.Name, Type: r.Type, Content: r.Content}
+			if !confirm {
+				u.log.Info("Would delete DNS record", "record", r.Name, "type", r.Type, "content", r.Content)
+			} else {
+				u.log.Warn("Deleting DNS record", "record", r.Name, "type", r.Type, "content", r.Content, "record_id", r.ID)
+				pr.Err = cf.deleteDNSRecord(ctx, zoneID, r.ID)
+				u.audit(AuditEntry{Actor: cf.actor, Op: "delete", Record: r.Name, Type: r.Type, Old: r.Content}, pr.Err)
+				pr.Deleted = pr.Err == nil
+			}
+			pruned = append(pruned, pr)
+		}
+	}
+
+	failed := 0
+	for _, pr := range pruned {
+		if pr.Err != nil {
+			u.log.Error("Record delete failed", "record", pr.Name, "error", pr.Err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return pruned, fmt.Errorf("%d of %d records failed to delete", failed, len(pruned))
+	}
+	return pruned, nil
+}
+
+// prunesWithin reports whether the literal suffix of pattern, the text after
+// its last wildcard, lies inside zone at least one label below the apex.
+func prunesWithin(pattern, zone string) bool {
+	suffix := strings.ToLower(pattern[strings.LastIndexAny(pattern, "*?[]\\")+1:])
+	suffix = strings.TrimSuffix(strings.TrimLeft(suffix, "."), ".")
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	label, ok := strings.CutSuffix(suffix, "."+zone)
+	return ok && label != ""
+}
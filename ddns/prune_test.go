@@ -0,0 +1,56 @@
+package ddns
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPrunePattern(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		pruned  []string
+		wantErr bool
+	}{
+		{"*.dyn.example.com", []string{"a.dyn.example.com", "b.dyn.example.com"}, false},
+		{"*.DYN.example.com", []string{"a.dyn.example.com", "b.dyn.example.com"}, false},
+		{"a*.dyn.example.com", []string{"a.dyn.example.com"}, false},
+		{"*", nil, true},
+		{"*.*", nil, true},
+		{"*.com", nil, true},
+		{"*.example.com", nil, true},
+		{"*example.com", nil, true},
+		{"*.dyn.example.org", nil, true},
+	} {
+		t.Run(c.pattern, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			for _, name := range []string{"home.example.com", "www.example.com", "a.dyn.example.com", "b.dyn.example.com"} {
+				f.addRecord("zone-example.com", DNSRecord{Name: name, Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+			}
+			u := newTestUpdater(t, Config{
+				ZoneName: "example.com",
+				APIToken: "token",
+				Content:  "192.0.2.1",
+				Records:  []Record{{Name: "home.example.com"}},
+			}, f)
+
+			pruned, err := u.Prune(t.Context(), c.pattern, true)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Prune error %v, want error %t", err, c.wantErr)
+			}
+			var names []string
+			for _, pr := range pruned {
+				if !pr.Deleted {
+					t.Errorf("%s was not deleted: %v", pr.Name, pr.Err)
+				}
+				names = append(names, pr.Name)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, c.pruned) {
+				t.Errorf("pruned %q, want %q", names, c.pruned)
+			}
+			if left := len(f.recordsOf("zone-example.com")); left != 4-len(c.pruned) {
+				t.Errorf("%d records left, want %d", left, 4-len(c.pruned))
+			}
+		})
+	}
+}
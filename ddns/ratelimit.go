@@ -0,0 +1,58 @@
+package ddns
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket pacing Cloudflare requests ahead of the
+// API's own limit of 1200 requests per 5 minutes, instead of reacting to
+// 429 responses. A nil limiter never waits.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter allows rate requests per second on average, in bursts of
+// up to rate requests, but at least one.
+func newRateLimiter(rate float64) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	burst := math.Max(1, math.Floor(rate))
+	return &rateLimiter{rate: rate, burst: burst, tokens: burst}
+}
+
+// wait blocks until a request may be sent.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if d := l.reserve(time.Now()); d > 0 {
+		return sleep(ctx, d)
+	}
+	return nil
+}
+
+// reserve takes a token and returns how long to wait before it is due.
+// Tokens go negative while requests are queued, so concurrent callers are
+// spaced out rather than released together.
+func (l *rateLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
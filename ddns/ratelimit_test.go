@@ -0,0 +1,90 @@
+package ddns
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// The tests drive the limiter with synthetic times through reserve, so
+// pacing is checked without sleeping.
+func TestRateLimiterBurstThenPace(t *testing.T) {
+	l := newRateLimiter(2)
+	start := time.Unix(1700000000, 0)
+	want := []time.Duration{0, 0, 500 * time.Millisecond, time.Second, 1500 * time.Millisecond}
+	for i, w := range want {
+		if got := l.reserve(start); got != w {
+			t.Errorf("request %d at the same instant: wait %s, want %s", i, got, w)
+		}
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	l := newRateLimiter(2)
+	now := time.Unix(1700000000, 0)
+	l.reserve(now)
+	l.reserve(now)
+
+	// Half a second refills one token.
+	now = now.Add(500 * time.Millisecond)
+	if got := l.reserve(now); got != 0 {
+		t.Errorf("after a refill: wait %s, want 0", got)
+	}
+	if got := l.reserve(now); got != 500*time.Millisecond {
+		t.Errorf("with the bucket empty: wait %s, want 500ms", got)
+	}
+
+	// A long pause refills no more than the burst.
+	now = now.Add(time.Hour)
+	for i := range 2 {
+		if got := l.reserve(now); got != 0 {
+			t.Errorf("burst request %d after a pause: wait %s, want 0", i, got)
+		}
+	}
+	if got := l.reserve(now); got != 500*time.Millisecond {
+		t.Errorf("past the burst: wait %s, want 500ms", got)
+	}
+}
+
+func TestRateLimiterSpacing(t *testing.T) {
+	// Requests arriving every 100ms under a limit of 5 per second drain the
+	// burst, then settle to one every 200ms.
+	l := newRateLimiter(5)
+	now := time.Unix(1700000000, 0)
+	var sent []time.Time
+	for range 20 {
+		sent = append(sent, now.Add(l.reserve(now)))
+		now = now.Add(100 * time.Millisecond)
+	}
+	for i := 15; i < len(sent); i++ {
+		if gap := sent[i].Sub(sent[i-1]); gap < 199*time.Millisecond || gap > 201*time.Millisecond {
+			t.Errorf("request %d sent %s after the previous one, want 200ms", i, gap)
+		}
+	}
+	if window := sent[len(sent)-1].Sub(sent[0]); window < 2*time.Second {
+		t.Errorf("20 requests sent within %s, want at least 2s at 5/s with a burst of 5", window)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		if l := newRateLimiter(rate); l != nil {
+			t.Errorf("newRateLimiter(%g) = %+v, want nil", rate, l)
+		}
+	}
+	var l *rateLimiter
+	if err := l.wait(context.Background()); err != nil {
+		t.Errorf("nil limiter wait = %v", err)
+	}
+}
+
+func TestRateLimiterFractionalRate(t *testing.T) {
+	l := newRateLimiter(0.5)
+	now := time.Unix(1700000000, 0)
+	if got := l.reserve(now); got != 0 {
+		t.Errorf("first request: wait %s, want 0", got)
+	}
+	if got := l.reserve(now); got != 2*time.Second {
+		t.Errorf("second request: wait %s, want 2s", got)
+	}
+}
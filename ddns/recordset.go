@@ -0,0 +1,229 @@
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// splitContent splits a comma-separated content list, dropping blanks.
+func splitContent(content string) []string {
+	var values []string
+	for _, value := range strings.Split(content, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// isAddressList reports whether content lists more than one IP address and
+// nothing else. A list of other values, like TXT content with commas, is
+// not one.
+func isAddressList(content string) bool {
+	values := splitContent(content)
+	for _, value := range values {
+		if net.ParseIP(value) == nil {
+			return false
+		}
+	}
+	return len(values) > 1
+}
+
+// contentsFor returns the addresses in content that belong to record's
+// address family, in canonical form.
+func contentsFor(record Record, content string) []string {
+	family := familyForType(record.Type)
+	var values []string
+	for _, value := range splitContent(content) {
+		if ip := net.ParseIP(value); ip != nil && familyOf(ip) == family {
+			values = append(values, ip.String())
+		}
+	}
+	return values
+}
+
+// recordSetPlan lists the calls that turn the existing records under a name
+// into the desired set.
+type recordSetPlan struct {
+	create  []string
+	update  []recordSetUpdate
+	remove  []DNSRecord
+	changes []string
+}
+
+type recordSetUpdate struct {
+	existing DNSRecord
+	content  string
+}
+
+// planRecordSet keeps existing records whose content is desired, updating
+// their other fields if they differ, then rewrites leftover records with
+// leftover addresses. Whatever remains is created or deleted.
+func planRecordSet(record Record, existing []DNSRecord, desired []string, fields []string) recordSetPlan {
+	var plan recordSetPlan
+	claimed := make([]bool, len(desired))
+	var leftover []DNSRecord
+	for _, e := range existing {
+		matched := false
+		for i, content := range desired {
+			if claimed[i] || !sameContent(e.Content, content) {
+				continue
+			}
+			claimed[i], matched = true, true
+			if changes := diffRecord(record, &e, content, fields); len(changes) > 0 {
+				plan.update = append(plan.update, recordSetUpdate{existing: e, content: content})
+				plan.changes = append(plan.changes, fmt.Sprintf("%s: %s", content, strings.Join(changes, ", ")))
+			}
+			break
+		}
+		if !matched {
+			leftover = append(leftover, e)
+		}
+	}
+
+	for i, content := range desired {
+		if claimed[i] {
+			continue
+		}
+		if len(leftover) > 0 {
+			e := leftover[0]
+			leftover = leftover[1:]
+			plan.update = append(plan.update, recordSetUpdate{existing: e, content: content})
+			plan.changes = append(plan.changes, fmt.Sprintf("content %s -> %s", e.Content, content))
+			continue
+		}
+		plan.create = append(plan.create, content)
+		plan.changes = append(plan.changes, "add "+content)
+	}
+	for _, e := range leftover {
+		plan.remove = append(plan.remove, e)
+		plan.changes = append(plan.changes, "remove "+e.Content)
+	}
+	return plan
+}
+
+// locked reports whether the plan would modify a locked record.
+func (p recordSetPlan) locked() bool {
+	for _, up := range p.update {
+		if up.existing.Locked {
+			return true
+		}
+	}
+	for _, e := range p.remove {
+		if e.Locked {
+			return true
+		}
+	}
+	return false
+}
+
+// syncRecordSet reconciles every record under record's name and type with
+// the addresses in content, for MultiRecordAll. Records are created before
+// others are deleted so the name never resolves to nothing. No propagation
+// check is run.
+func (u *Updater) syncRecordSet(ctx context.Context, zoneID string, record Record, content string) RecordResult {
+	cf := u.cf[record.Credential]
+	desired := contentsFor(record, content)
+	rr := RecordResult{Name: record.Name, Type: record.Type, Content: strings.Join(desired, ",")}
+	fail := func(err error) RecordResult {
+		rr.Action = ActionFailed
+		rr.Err = err
+		return rr
+	}
+	if len(desired) == 0 {
+		return fail(fmt.Errorf("record content has no address for %s records", record.Type))
+	}
+
+	existing, err := cf.getRecordSet(ctx, zoneID, record.Name, record.Type)
+	if err != nil {
+		return fail(err)
+	}
+	previous := make([]string, len(existing))
+	for i, e := range existing {
+		previous[i] = e.Content
+		if u.cfg.CommentTimestamp {
+			existing[i].Comment = stripCommentStamp(e.Comment)
+		}
+	}
+	rr.Previous = strings.Join(previous, ",")
+	if u.cfg.DryRun {
+		rr.Diff = []FieldDiff{{Field: "content", Current: rr.Previous, Desired: rr.Content}}
+	}
+	if len(existing) == 0 && u.cfg.OnMissing != MissingCreate {
+		return u.missingRecord(rr)
+	}
+
+	plan := planRecordSet(record, existing, desired, u.cfg.UpdateFields)
+	if len(plan.changes) == 0 {
+		u.log.Info("Record set not changed", "record", record.Name, "content", rr.Content)
+		rr.Action = ActionUnchanged
+		return rr
+	}
+	rr.Changes = plan.changes
+
+	if plan.locked() {
+		if u.cfg.OnLocked == LockedError {
+			return fail(fmt.Errorf("record set has a locked record on Cloudflare and cannot be updated (%s)", strings.Join(plan.changes, ", ")))
+		}
+		u.log.Warn("Skipping record set with a locked record", "record", record.Name, "changes", strings.Join(plan.changes, ", "))
+		rr.Action = ActionSkipped
+		rr.Reason = "record set has a locked record on Cloudflare"
+		return rr
+	}
+
+	action := ActionUpdated
+	if len(existing) == 0 {
+		action = ActionCreated
+	}
+	if u.cfg.DryRun {
+		u.log.Info("Dry run, record set would be changed", "record", record.Name, "changes", strings.Join(plan.changes, ", "))
+		rr.Action = action
+		return rr
+	}
+	if u.deferChange(&rr) {
+		return rr
+	}
+
+	u.log.Info("Record set changed, reconciling", "record", record.Name, "changes", strings.Join(plan.changes, ", "))
+	for _, c := range plan.create {
+		u.log.Info("Adding record to set", "record", record.Name, "content", c)
+		err := timeChange(ctx, &rr, func(ctx context.Context) error {
+			return cf.createDNSRecord(ctx, zoneID, buildPayload(u.stampRecord(record), c))
+		})
+		u.audit(AuditEntry{Actor: cf.actor, Op: "create", Record: record.Name, Type: record.Type, New: c}, err)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	for _, up := range plan.update {
+		r := record
+		if r.Tags == nil {
+			r.Tags = up.existing.Tags
+		}
+		if r.Comment == "" {
+			r.Comment = up.existing.Comment
+		}
+		u.log.Info("Updating record in set", "record", record.Name, "old", up.existing.Content, "content", up.content)
+		err := timeChange(ctx, &rr, func(ctx context.Context) error {
+			return cf.updateDNSRecord(ctx, zoneID, up.existing.ID, up.existing.etag, buildPayload(u.stampRecord(r), up.content))
+		})
+		u.audit(AuditEntry{Actor: cf.actor, Op: "update", Record: record.Name, Type: record.Type, Old: up.existing.Content, New: up.content}, err)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	for _, e := range plan.remove {
+		u.log.Info("Removing record from set", "record", record.Name, "content", e.Content)
+		err := timeChange(ctx, &rr, func(ctx context.Context) error {
+			return cf.deleteDNSRecord(ctx, zoneID, e.ID)
+		})
+		u.audit(AuditEntry{Actor: cf.actor, Op: "delete", Record: record.Name, Type: record.Type, Old: e.Content}, err)
+		if err != nil {
+			return fail(err)
+		}
+	}
+	rr.Action = action
+	return rr
+}
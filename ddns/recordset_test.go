@@ -0,0 +1,188 @@
+package ddns
+
+import (
+	"bytes"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPlanRecordSet(t *testing.T) {
+	record := Record{Name: "home.example.com", Type: "A", TTL: AutoTTL}
+	existing := func(contents ...string) []DNSRecord {
+		var records []DNSRecord
+		for i, c := range contents {
+			records = append(records, DNSRecord{ID: string(rune('a' + i)), Content: c, TTL: AutoTTL})
+		}
+		return records
+	}
+	for _, c := range []struct {
+		name     string
+		existing []DNSRecord
+		desired  []string
+		create   []string
+		update   []string
+		remove   []string
+	}{
+		{"keep", existing("192.0.2.1", "192.0.2.2"), []string{"192.0.2.2", "192.0.2.1"}, nil, nil, nil},
+		{"add", existing("192.0.2.1"), []string{"192.0.2.1", "192.0.2.2"}, []string{"192.0.2.2"}, nil, nil},
+		{"remove", existing("192.0.2.1", "192.0.2.2"), []string{"192.0.2.2"}, nil, nil, []string{"192.0.2.1"}},
+		{"rewrite leftover", existing("192.0.2.1", "192.0.2.9"), []string{"192.0.2.1", "192.0.2.2"}, nil, []string{"192.0.2.9 -> 192.0.2.2"}, nil},
+		{"all at once", existing("192.0.2.1", "192.0.2.8", "192.0.2.9"), []string{"192.0.2.1", "192.0.2.2"}, nil, []string{"192.0.2.8 -> 192.0.2.2"}, []string{"192.0.2.9"}},
+		{"create from empty", nil, []string{"192.0.2.1", "192.0.2.2"}, []string{"192.0.2.1", "192.0.2.2"}, nil, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			plan := planRecordSet(record, c.existing, c.desired, updateFields)
+			var update, remove []string
+			for _, up := range plan.update {
+				update = append(update, up.existing.Content+" -> "+up.content)
+			}
+			for _, e := range plan.remove {
+				remove = append(remove, e.Content)
+			}
+			if !slices.Equal(plan.create, c.create) || !slices.Equal(update, c.update) || !slices.Equal(remove, c.remove) {
+				t.Errorf("plan create %q update %q remove %q, want %q %q %q", plan.create, update, remove, c.create, c.update, c.remove)
+			}
+			if wantChanges := len(c.create) + len(c.update) + len(c.remove); len(plan.changes) != wantChanges {
+				t.Errorf("changes %q, want %d", plan.changes, wantChanges)
+			}
+		})
+	}
+}
+
+func TestRunRecordSetReconciles(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	for _, content := range []string{"192.0.2.1", "192.0.2.8", "192.0.2.9"} {
+		f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: content, TTL: AutoTTL})
+	}
+	u := newTestUpdater(t, Config{
+		ZoneName:            "example.com",
+		APIToken:            "token",
+		Content:             "192.0.2.1, 192.0.2.2, 192.0.2.3, 192.0.2.4",
+		MultiRecordStrategy: MultiRecordAll,
+		Records:             []Record{{Name: "home.example.com"}},
+	}, f)
+	if rr := run(t, u).Records[0]; rr.Action != ActionUpdated {
+		t.Fatalf("action %q, want %q", rr.Action, ActionUpdated)
+	}
+
+	var got []string
+	for _, record := range f.recordsOf("zone-example.com") {
+		got = append(got, record.Content)
+	}
+	slices.Sort(got)
+	if want := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4"}; !slices.Equal(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+	if puts, posts := len(f.requestsFor("PUT")), len(f.requestsFor("POST")); puts != 2 || posts != 1 {
+		t.Errorf("sent %d updates and %d creates, want 2 and 1", puts, posts)
+	}
+	if deletes := f.requestsFor("DELETE"); len(deletes) != 0 {
+		t.Errorf("sent %d deletes, want none", len(deletes))
+	}
+
+	// Shrinking the set deletes the records no longer listed.
+	u = newTestUpdater(t, Config{
+		ZoneName:            "example.com",
+		APIToken:            "token",
+		Content:             "192.0.2.4",
+		MultiRecordStrategy: MultiRecordAll,
+		Records:             []Record{{Name: "home.example.com"}},
+	}, f)
+	run(t, u)
+	if records := f.recordsOf("zone-example.com"); len(records) != 1 || records[0].Content != "192.0.2.4" {
+		t.Errorf("records = %+v, want only 192.0.2.4", records)
+	}
+	if deletes := f.requestsFor("DELETE"); len(deletes) != 3 || !strings.HasPrefix(deletes[0].Path, "/zones/zone-example.com/dns_records/") {
+		t.Errorf("sent %d deletes, want 3", len(deletes))
+	}
+}
+
+func TestIsAddressList(t *testing.T) {
+	for _, c := range []struct {
+		content string
+		want    bool
+	}{
+		{"192.0.2.1,192.0.2.2", true},
+		{"192.0.2.1, 2001:db8::1", true},
+		{" 192.0.2.1 ,192.0.2.2 , 192.0.2.3", true},
+		{"192.0.2.1", false},
+		{"192.0.2.1,", false},
+		{"", false},
+		{"192.0.2.1,not-an-address", false},
+		{"v=spf1 a,mx -all", false},
+	} {
+		if got := isAddressList(c.content); got != c.want {
+			t.Errorf("isAddressList(%q) = %t, want %t", c.content, got, c.want)
+		}
+	}
+}
+
+func TestRunCommaListReconcilesByDefault(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	for _, content := range []string{"192.0.2.1", "192.0.2.8", "192.0.2.9"} {
+		f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: content, TTL: AutoTTL})
+	}
+	// The set of another name is left alone.
+	f.addRecord("zone-example.com", DNSRecord{Name: "vpn.example.com", Type: "A", Content: "192.0.2.9", TTL: AutoTTL})
+	var logs bytes.Buffer
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Content:  "192.0.2.1,192.0.2.2",
+		Records:  []Record{{Name: "home.example.com"}},
+		Logger:   slog.New(slog.NewTextHandler(&logs, nil)),
+	}, f)
+	if got := u.Config().MultiRecordStrategy; got != MultiRecordAll {
+		t.Fatalf("multi-record strategy %q, want %q for an address list", got, MultiRecordAll)
+	}
+	if rr := run(t, u).Records[0]; rr.Action != ActionUpdated {
+		t.Fatalf("action %q, want %q", rr.Action, ActionUpdated)
+	}
+
+	got := map[string][]string{}
+	for _, record := range f.recordsOf("zone-example.com") {
+		got[record.Name] = append(got[record.Name], record.Content)
+	}
+	slices.Sort(got["home.example.com"])
+	if want := []string{"192.0.2.1", "192.0.2.2"}; !slices.Equal(got["home.example.com"], want) {
+		t.Errorf("home records = %q, want %q", got["home.example.com"], want)
+	}
+	if want := []string{"192.0.2.9"}; !slices.Equal(got["vpn.example.com"], want) {
+		t.Errorf("vpn records = %q, want %q", got["vpn.example.com"], want)
+	}
+	for _, want := range []string{
+		`msg="Updating record in set" record=home.example.com old=192.0.2.8 content=192.0.2.2`,
+		`msg="Removing record from set" record=home.example.com content=192.0.2.9`,
+	} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("logs miss %q:\n%s", want, logs.String())
+		}
+	}
+
+	// A second run finds the set in sync.
+	u = newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", Content: "192.0.2.2, 192.0.2.1", Records: []Record{{Name: "home.example.com"}}}, f)
+	if rr := run(t, u).Records[0]; rr.Action != ActionUnchanged {
+		t.Errorf("second run: action %q, want %q", rr.Action, ActionUnchanged)
+	}
+}
+
+func TestRunCommaListAddsMissing(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	var logs bytes.Buffer
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Content:  "192.0.2.1,192.0.2.2",
+		Records:  []Record{{Name: "home.example.com"}},
+		Logger:   slog.New(slog.NewTextHandler(&logs, nil)),
+	}, f)
+	run(t, u)
+	if records := f.recordsOf("zone-example.com"); len(records) != 2 {
+		t.Errorf("records = %+v, want two", records)
+	}
+	if n := strings.Count(logs.String(), `msg="Adding record to set"`); n != 2 {
+		t.Errorf("logged %d additions, want 2:\n%s", n, logs.String())
+	}
+}
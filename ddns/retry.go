@@ -0,0 +1,105 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy controls how failed Cloudflare calls and IP provider lookups
+// are retried. The delay before retry n is BaseDelay*2^(n-1), capped at
+// MaxDelay.
+type RetryPolicy struct {
+	// Attempts is the total number of tries, including the first.
+	// Defaults to 3.
+	Attempts int
+	// BaseDelay defaults to 1 second.
+	BaseDelay time.Duration
+	// MaxDelay defaults to 30 seconds.
+	MaxDelay time.Duration
+	// Budget, when positive, caps the retries of a whole run, summed over
+	// every Cloudflare call and IP provider round, so many records cannot
+	// multiply Attempts into an overlong run. Once spent, failures are
+	// returned without retrying.
+	Budget int
+}
+
+func (p *RetryPolicy) setDefaults() {
+	if p.Attempts == 0 {
+		p.Attempts = 3
+	}
+	if p.BaseDelay == 0 {
+		p.BaseDelay = time.Second
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = 30 * time.Second
+	}
+}
+
+func (p RetryPolicy) validate() error {
+	if p.Attempts < 1 {
+		return errors.New("retry attempts must be at least 1")
+	}
+	if p.BaseDelay <= 0 || p.MaxDelay <= 0 {
+		return errors.New("retry delays must be positive")
+	}
+	if p.Budget < 0 {
+		return errors.New("retry budget must not be negative")
+	}
+	if p.BaseDelay > p.MaxDelay {
+		return errors.New("retry base delay must not exceed the max delay")
+	}
+	return nil
+}
+
+// delay returns how long to wait after the given failed attempt, counting
+// from 1.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryBudget counts the retries left in a run. A nil budget is unlimited.
+type retryBudget struct {
+	size int64
+	left atomic.Int64
+}
+
+func newRetryBudget(size int) *retryBudget {
+	if size <= 0 {
+		return nil
+	}
+	b := &retryBudget{size: int64(size)}
+	b.reset()
+	return b
+}
+
+// reset refills the budget at the start of a run.
+func (b *retryBudget) reset() {
+	if b != nil {
+		b.left.Store(b.size)
+	}
+}
+
+// take spends one retry, reporting false when none are left.
+func (b *retryBudget) take() bool {
+	return b == nil || b.left.Add(-1) >= 0
+}
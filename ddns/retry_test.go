@@ -0,0 +1,154 @@
+package ddns
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"first retry is the base", RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, 1, time.Second},
+		{"doubles", RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, 2, 2 * time.Second},
+		{"doubles again", RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, 5, 16 * time.Second},
+		{"capped", RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, 6, 30 * time.Second},
+		{"stays capped", RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}, 100, 30 * time.Second},
+		{"cap not a power of two", RetryPolicy{BaseDelay: 300 * time.Millisecond, MaxDelay: time.Second}, 3, time.Second},
+		{"base equals cap", RetryPolicy{BaseDelay: 5 * time.Second, MaxDelay: 5 * time.Second}, 1, 5 * time.Second},
+		{"base equals cap later", RetryPolicy{BaseDelay: 5 * time.Second, MaxDelay: 5 * time.Second}, 4, 5 * time.Second},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.policy.delay(c.attempt); got != c.want {
+				t.Errorf("delay(%d) = %s, want %s", c.attempt, got, c.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelayWithinBounds(t *testing.T) {
+	for _, policy := range []RetryPolicy{
+		{BaseDelay: time.Millisecond, MaxDelay: time.Hour},
+		{BaseDelay: 750 * time.Millisecond, MaxDelay: 10 * time.Second},
+		{BaseDelay: time.Minute, MaxDelay: time.Minute},
+	} {
+		prev := time.Duration(0)
+		for attempt := 1; attempt <= 64; attempt++ {
+			d := policy.delay(attempt)
+			if d < policy.BaseDelay || d > policy.MaxDelay {
+				t.Fatalf("%+v: delay(%d) = %s, outside [%s, %s]", policy, attempt, d, policy.BaseDelay, policy.MaxDelay)
+			}
+			if d < prev {
+				t.Fatalf("%+v: delay(%d) = %s, shorter than the previous %s", policy, attempt, d, prev)
+			}
+			prev = d
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(2)
+	if !b.take() || !b.take() || b.take() {
+		t.Error("a budget of 2 allowed other than two retries")
+	}
+	b.reset()
+	if !b.take() {
+		t.Error("reset did not refill the budget")
+	}
+	var unlimited *retryBudget
+	for range 100 {
+		if !unlimited.take() {
+			t.Fatal("nil budget ran out")
+		}
+	}
+	if newRetryBudget(0) != nil {
+		t.Error("newRetryBudget(0) is not unlimited")
+	}
+}
+
+func TestRunRetryBudgetCapsRetries(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		if r.Method != "POST" {
+			return false
+		}
+		writeError(w, http.StatusInternalServerError, 10001, "Service unavailable")
+		return true
+	}
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Retry:    RetryPolicy{Attempts: 4, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Budget: 3},
+		Records:  []Record{{Name: "a.example.com"}, {Name: "b.example.com"}, {Name: "c.example.com"}},
+	}, f)
+
+	// Without the budget, three records at four attempts each would send
+	// twelve creates; the budget leaves one try each plus three retries.
+	for run := 1; run <= 2; run++ {
+		before := len(f.requestsFor("POST"))
+		if _, err := u.Run(context.Background()); err == nil {
+			t.Fatalf("run %d succeeded, want the creates to fail", run)
+		}
+		if got := len(f.requestsFor("POST")) - before; got != 6 {
+			t.Errorf("run %d sent %d creates, want 6", run, got)
+		}
+	}
+}
+
+func TestRunRequestTimeoutRetriesSlowAttempt(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		stall func(w http.ResponseWriter)
+	}{
+		{"no response", func(w http.ResponseWriter) {}},
+		{"stalled body", func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"success": true, "result": [`))
+			w.(http.Flusher).Flush()
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+			var lists atomic.Int32
+			f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+				if r.Method != "GET" || r.URL.Path != "/zones/zone-example.com/dns_records" || lists.Add(1) > 1 {
+					return false
+				}
+				// The first listing hangs until the client gives up.
+				c.stall(w)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+				return true
+			}
+			u := newTestUpdater(t, Config{
+				ZoneName:       "example.com",
+				APIToken:       "token",
+				Content:        "198.51.100.1",
+				RequestTimeout: 100 * time.Millisecond,
+				Retry:          RetryPolicy{Attempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+				Records:        []Record{{Name: "home.example.com"}},
+			}, f)
+
+			start := time.Now()
+			if rr := run(t, u).Records[0]; rr.Action != ActionUpdated {
+				t.Errorf("action %q, want %q", rr.Action, ActionUpdated)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("run took %s, want the slow attempt cut at 100ms", elapsed)
+			}
+			if got := lists.Load(); got != 2 {
+				t.Errorf("%d record listings, want the slow one retried once", got)
+			}
+		})
+	}
+}
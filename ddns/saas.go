@@ -0,0 +1,154 @@
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// CustomHostname is a Cloudflare for SaaS custom hostname as returned by
+// the custom_hostnames endpoint.
+type CustomHostname struct {
+	ID                 string             `json:"id"`
+	Hostname           string             `json:"hostname"`
+	CustomOriginServer string             `json:"custom_origin_server"`
+	SSL                *CustomHostnameSSL `json:"ssl"`
+}
+
+// CustomHostnameSSL selects how the certificate of a custom hostname is
+// validated and issued.
+type CustomHostnameSSL struct {
+	Method string `json:"method"`
+	Type   string `json:"type"`
+}
+
+// CustomHostnamePayload is the request body for creating or updating a
+// custom hostname. Hostname and SSL are only sent on creation.
+type CustomHostnamePayload struct {
+	Hostname           string             `json:"hostname,omitempty"`
+	CustomOriginServer string             `json:"custom_origin_server"`
+	SSL                *CustomHostnameSSL `json:"ssl,omitempty"`
+}
+
+func (cf *cloudflare) getCustomHostname(ctx context.Context, zoneID, hostname string) (*CustomHostname, error) {
+	endpoint := fmt.Sprintf("/zones/%s/custom_hostnames?hostname=%s", zoneID, url.QueryEscape(hostname))
+	resp, err := cf.cfRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch custom hostname: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var cfResp CloudflareResponse[CustomHostname]
+	if err := json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
+		return nil, fmt.Errorf("failed to decode custom hostname response: %w", err)
+	}
+
+	for _, ch := range cfResp.Result {
+		if ch.Hostname == hostname {
+			cf.log.Info("Custom hostname found", "hostname", ch.Hostname, "custom_hostname_id", ch.ID, "origin", ch.CustomOriginServer)
+			return &ch, nil
+		}
+	}
+	return nil, nil
+}
+
+func (cf *cloudflare) createCustomHostname(ctx context.Context, zoneID string, payload CustomHostnamePayload) error {
+	endpoint := fmt.Sprintf("/zones/%s/custom_hostnames", zoneID)
+	resp, err := cf.cfRequest(ctx, "POST", endpoint, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create custom hostname: %w", err)
+	}
+	defer resp.Body.Close()
+
+	cf.log.Info("Custom hostname created", "hostname", payload.Hostname)
+	return nil
+}
+
+func (cf *cloudflare) updateCustomHostname(ctx context.Context, zoneID, id string, payload CustomHostnamePayload) error {
+	endpoint := fmt.Sprintf("/zones/%s/custom_hostnames/%s", zoneID, id)
+	resp, err := cf.cfRequest(ctx, "PATCH", endpoint, payload)
+	if err != nil {
+		return fmt.Errorf("failed to update custom hostname: %w", err)
+	}
+	defer resp.Body.Close()
+
+	cf.log.Info("Custom hostname updated", "custom_hostname_id", id)
+	return nil
+}
+
+// syncCustomHostname points the custom hostname record.Name at origin,
+// creating it with HTTP DCV when it does not exist yet.
+func (u *Updater) syncCustomHostname(ctx context.Context, zoneID string, record Record, origin string) RecordResult {
+	cf := u.cf[record.Credential]
+	rr := RecordResult{Name: record.Name, Type: record.Type, Content: origin}
+	fail := func(err error) RecordResult {
+		rr.Action = ActionFailed
+		rr.Err = err
+		return rr
+	}
+
+	existing, err := cf.getCustomHostname(ctx, zoneID, record.Name)
+	if err != nil {
+		return fail(err)
+	}
+	if u.cfg.DryRun {
+		diff := FieldDiff{Field: "custom_origin_server", Desired: origin}
+		if existing != nil {
+			diff.Current = existing.CustomOriginServer
+		}
+		rr.Diff = []FieldDiff{diff}
+	}
+
+	if existing == nil {
+		if u.cfg.OnMissing != MissingCreate {
+			return u.missingRecord(rr)
+		}
+		if u.cfg.DryRun {
+			u.log.Info("Dry run, custom hostname would be created", "hostname", record.Name)
+			rr.Action = ActionCreated
+			return rr
+		}
+		if u.deferChange(&rr) {
+			return rr
+		}
+		u.log.Info("Custom hostname does not exist, creating", "hostname", record.Name)
+		payload := CustomHostnamePayload{
+			Hostname:           record.Name,
+			CustomOriginServer: origin,
+			SSL:                &CustomHostnameSSL{Method: "http", Type: "dv"},
+		}
+		err := cf.createCustomHostname(ctx, zoneID, payload)
+		u.audit(AuditEntry{Actor: cf.actor, Op: "create", Record: record.Name, Type: "custom_hostname", New: origin}, err)
+		if err != nil {
+			return fail(err)
+		}
+		rr.Action = ActionCreated
+		return rr
+	}
+	rr.Previous = existing.CustomOriginServer
+
+	if existing.CustomOriginServer == origin {
+		u.log.Info("Custom hostname not changed", "hostname", record.Name, "origin", origin)
+		rr.Action = ActionUnchanged
+		return rr
+	}
+	rr.Changes = []string{fmt.Sprintf("custom_origin_server %s -> %s", existing.CustomOriginServer, origin)}
+
+	if u.cfg.DryRun {
+		u.log.Info("Dry run, custom hostname would be updated", "hostname", record.Name, "changes", rr.Changes[0])
+		rr.Action = ActionUpdated
+		return rr
+	}
+	if u.deferChange(&rr) {
+		return rr
+	}
+	u.log.Info("Custom hostname changed, updating", "hostname", record.Name, "changes", rr.Changes[0])
+	err = cf.updateCustomHostname(ctx, zoneID, existing.ID, CustomHostnamePayload{CustomOriginServer: origin})
+	u.audit(AuditEntry{Actor: cf.actor, Op: "update", Record: record.Name, Type: "custom_hostname", Old: existing.CustomOriginServer, New: origin}, err)
+	if err != nil {
+		return fail(err)
+	}
+	rr.Action = ActionUpdated
+	return rr
+}
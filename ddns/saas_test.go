@@ -0,0 +1,106 @@
+package ddns
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// serveCustomHostnames makes f answer the custom_hostnames endpoints from
+// hostnames, keyed by hostname.
+func serveCustomHostnames(f *fakeCloudflare, hostnames map[string]CustomHostname) {
+	f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		if !strings.Contains(r.URL.Path, "/custom_hostnames") {
+			return false
+		}
+		switch r.Method {
+		case "GET":
+			result := []CustomHostname{}
+			if ch, ok := hostnames[r.URL.Query().Get("hostname")]; ok {
+				result = append(result, ch)
+			}
+			writeResult(w, result, nil)
+		case "POST", "PATCH":
+			var payload CustomHostnamePayload
+			json.Unmarshal(body, &payload)
+			writeResult(w, CustomHostname{ID: "ch-new", Hostname: payload.Hostname, CustomOriginServer: payload.CustomOriginServer}, nil)
+		default:
+			return false
+		}
+		return true
+	}
+}
+
+func TestSyncCustomHostname(t *testing.T) {
+	const origin = "origin.example.net"
+	tests := []struct {
+		name     string
+		existing map[string]CustomHostname
+		action   Action
+		method   string
+		path     string
+		want     CustomHostnamePayload
+	}{
+		{
+			name:   "missing is created with HTTP DCV",
+			action: ActionCreated,
+			method: "POST",
+			path:   "/zones/zone-example.com/custom_hostnames",
+			want:   CustomHostnamePayload{Hostname: "shop.example.com", CustomOriginServer: origin, SSL: &CustomHostnameSSL{Method: "http", Type: "dv"}},
+		},
+		{
+			name:     "other origin is updated",
+			existing: map[string]CustomHostname{"shop.example.com": {ID: "ch-1", Hostname: "shop.example.com", CustomOriginServer: "old.example.net"}},
+			action:   ActionUpdated,
+			method:   "PATCH",
+			path:     "/zones/zone-example.com/custom_hostnames/ch-1",
+			want:     CustomHostnamePayload{CustomOriginServer: origin},
+		},
+		{
+			name:     "same origin is unchanged",
+			existing: map[string]CustomHostname{"shop.example.com": {ID: "ch-1", Hostname: "shop.example.com", CustomOriginServer: origin}},
+			action:   ActionUnchanged,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			serveCustomHostnames(f, tt.existing)
+			u := newTestUpdater(t, Config{
+				ZoneName: "example.com",
+				APIToken: "token",
+				Content:  origin,
+				SaaS:     true,
+				Records:  []Record{{Name: "shop.example.com"}},
+			}, f)
+
+			result := run(t, u)
+			if got := result.Records[0].Action; got != tt.action {
+				t.Fatalf("action %q, want %q", got, tt.action)
+			}
+			var writes []fakeRequest
+			for _, req := range f.requestsFor("") {
+				if req.Method != "GET" {
+					writes = append(writes, req)
+				}
+			}
+			if tt.method == "" {
+				if len(writes) != 0 {
+					t.Fatalf("sent %d writes, want none", len(writes))
+				}
+				return
+			}
+			if len(writes) != 1 || writes[0].Method != tt.method || writes[0].Path != tt.path {
+				t.Fatalf("writes = %+v, want one %s %s", writes, tt.method, tt.path)
+			}
+			var got CustomHostnamePayload
+			if err := json.Unmarshal(writes[0].Body, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Hostname != tt.want.Hostname || got.CustomOriginServer != tt.want.CustomOriginServer || (got.SSL == nil) != (tt.want.SSL == nil) || got.SSL != nil && *got.SSL != *tt.want.SSL {
+				t.Errorf("payload = %s, want %+v", writes[0].Body, tt.want)
+			}
+		})
+	}
+}
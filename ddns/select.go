@@ -0,0 +1,134 @@
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// SelectBy decides how a configured record finds the DNS records it
+// updates.
+type SelectBy string
+
+const (
+	// SelectByName updates the record of the configured name. It is the
+	// default.
+	SelectByName SelectBy = "name"
+	// SelectByComment updates the records of the zone, whatever their name,
+	// whose comment equals the configured record's Comment.
+	SelectByComment SelectBy = "comment"
+	// SelectByTag updates the records of the zone, whatever their name,
+	// that carry every tag of the configured record's Tags.
+	SelectByTag SelectBy = "tag"
+	// SelectByGlob updates the existing records of the zone whose name
+	// matches the configured record's Name as a path.Match pattern, e.g.
+	// "*.dyn.example.com" for every concrete record under dyn.example.com,
+	// at any depth since * also matches dots. Unlike the other modes, Name
+	// is required and nothing is created.
+	SelectByGlob SelectBy = "glob"
+)
+
+// selectRecords returns the records to sync for the configured record:
+// itself when selecting by name, otherwise one copy per name of the matching
+// records of its type in the zone. When nothing is left to sync, ok is
+// false and rr reports why: the zone or listing failed, nothing matched or,
+// under MultiRecordSingle, more than one record did.
+func (u *Updater) selectRecords(ctx context.Context, zoneID string, zoneErr error, record Record) (records []Record, rr RecordResult, ok bool) {
+	if u.cfg.SelectBy == SelectByName {
+		return []Record{record}, RecordResult{}, true
+	}
+	glob := u.cfg.SelectBy == SelectByGlob
+	rr = RecordResult{Name: record.Name, Type: record.Type}
+	if rr.Name == "" {
+		rr.Name = u.selector(record)
+	}
+	if zoneErr != nil {
+		rr.Action, rr.Err = ActionFailed, zoneErr
+		return nil, rr, false
+	}
+
+	all, err := u.cf[record.Credential].listDNSRecords(ctx, zoneID)
+	if err != nil {
+		rr.Action, rr.Err = ActionFailed, err
+		return nil, rr, false
+	}
+	var matches []DNSRecord
+	var names []string
+	for _, existing := range all {
+		if existing.Type != record.Type || !u.selects(record, existing) {
+			continue
+		}
+		matches = append(matches, existing)
+		if !slices.ContainsFunc(names, func(name string) bool { return sameName(name, existing.Name) }) {
+			names = append(names, existing.Name)
+		}
+	}
+
+	switch {
+	case len(matches) == 0 && record.Name != "" && !glob:
+		u.log.Info("No record matches, using the configured name", "selector", u.selector(record), "record", record.Name)
+		return []Record{record}, RecordResult{}, true
+	case len(matches) == 0:
+		return nil, u.missingRecord(rr), false
+	case glob && len(matches) > len(names) && u.cfg.MultiRecordStrategy == MultiRecordSingle:
+		// A pattern is meant to match many names, but each still maps to
+		// a single record.
+		rr.Action = ActionFailed
+		rr.Err = fmt.Errorf("%d %s records match %s under %d names: set the multi-record strategy to %s to update them all", len(matches), record.Type, u.selector(record), len(names), MultiRecordAll)
+		return nil, rr, false
+	case !glob && len(matches) > 1 && u.cfg.MultiRecordStrategy == MultiRecordSingle:
+		rr.Action = ActionFailed
+		rr.Err = fmt.Errorf("%d %s records match %s: set the multi-record strategy to %s to update them all", len(matches), record.Type, u.selector(record), MultiRecordAll)
+		return nil, rr, false
+	}
+	if glob {
+		if err := u.cfg.checkRecordLimit(len(names)); err != nil {
+			rr.Action, rr.Err = ActionFailed, fmt.Errorf("%s: %w", u.selector(record), err)
+			return nil, rr, false
+		}
+		u.log.Info("Record pattern expanded", "pattern", record.Name, "type", record.Type, "count", len(names), "records", strings.Join(names, ","))
+	}
+
+	for _, name := range names {
+		u.log.Info("Record selected", "selector", u.selector(record), "record", name)
+		target := record
+		target.Name = name
+		if u.cfg.SelectBy == SelectByTag {
+			// Keep the record's own tags, which may be more than the
+			// selecting ones.
+			target.Tags = nil
+		}
+		records = append(records, target)
+	}
+	return records, RecordResult{}, true
+}
+
+// selects reports whether existing matches the selector of record.
+func (u *Updater) selects(record Record, existing DNSRecord) bool {
+	switch u.cfg.SelectBy {
+	case SelectByComment:
+		return stripCommentStamp(existing.Comment) == record.Comment
+	case SelectByGlob:
+		ok, _ := path.Match(strings.ToLower(record.Name), strings.ToLower(strings.TrimSuffix(existing.Name, ".")))
+		return ok
+	}
+	for _, tag := range record.Tags {
+		if !slices.Contains(existing.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// selector describes what record is selected by, e.g. comment "home".
+func (u *Updater) selector(record Record) string {
+	switch u.cfg.SelectBy {
+	case SelectByComment:
+		return fmt.Sprintf("comment %q", record.Comment)
+	case SelectByGlob:
+		return fmt.Sprintf("pattern %q", record.Name)
+	}
+	return fmt.Sprintf("tags %q", record.Tags)
+}
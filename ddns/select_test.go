@@ -0,0 +1,211 @@
+package ddns
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// selectionZone returns a fake zone whose A records carry comments and
+// tags to select by.
+func selectionZone(t *testing.T) *fakeCloudflare {
+	t.Helper()
+	f := newFakeCloudflare(t, "example.com")
+	for _, r := range []DNSRecord{
+		{Name: "home.example.com", Comment: "home", Tags: []string{"env:prod", "site:home"}},
+		{Name: "nas.example.com", Comment: "home", Tags: []string{"site:home"}},
+		{Name: "office.example.com", Comment: "office", Tags: []string{"env:prod", "site:office"}},
+		{Name: "old.example.com", Comment: "home-old"},
+	} {
+		r.Type, r.Content, r.TTL = "A", "192.0.2.1", AutoTTL
+		f.addRecord("zone-example.com", r)
+	}
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "TXT", Content: "home", Comment: "home"})
+	return f
+}
+
+func TestRunSelectByComment(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		record  Record
+		multi   MultiRecordStrategy
+		updated []string
+		action  Action
+		wantErr bool
+	}{
+		{"one match", Record{Comment: "office"}, "", []string{"office.example.com"}, ActionUpdated, false},
+		{"exact comment only", Record{Comment: "home-old"}, "", []string{"old.example.com"}, ActionUpdated, false},
+		{"several matches refused", Record{Comment: "home"}, "", nil, ActionFailed, true},
+		{"several matches updated", Record{Comment: "home"}, MultiRecordAll, []string{"home.example.com", "nas.example.com"}, ActionUpdated, false},
+		{"no match", Record{Comment: "garage"}, "", nil, ActionSkipped, false},
+		{"no match falls back to the name", Record{Name: "garage.example.com", Comment: "garage"}, "", nil, ActionCreated, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := selectionZone(t)
+			u := newTestUpdater(t, Config{
+				ZoneName:            "example.com",
+				APIToken:            "token",
+				Content:             "198.51.100.1",
+				SelectBy:            SelectByComment,
+				MultiRecordStrategy: c.multi,
+				Records:             []Record{c.record},
+			}, f)
+
+			result, err := u.Run(context.Background())
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Run error %v, want error %t", err, c.wantErr)
+			}
+			for _, rr := range result.Records {
+				if rr.Action != c.action {
+					t.Errorf("%s: action %q, want %q", rr.Name, rr.Action, c.action)
+				}
+			}
+			var updated []string
+			for _, record := range f.recordsOf("zone-example.com") {
+				if record.Content == "198.51.100.1" && record.Name != "garage.example.com" {
+					updated = append(updated, record.Name)
+				}
+			}
+			slices.Sort(updated)
+			if !slices.Equal(updated, c.updated) {
+				t.Errorf("updated %q, want %q", updated, c.updated)
+			}
+		})
+	}
+}
+
+func TestRunSelectByTag(t *testing.T) {
+	f := selectionZone(t)
+	u := newTestUpdater(t, Config{
+		ZoneName:            "example.com",
+		APIToken:            "token",
+		Content:             "198.51.100.1",
+		SelectBy:            SelectByTag,
+		MultiRecordStrategy: MultiRecordAll,
+		Records:             []Record{{Tags: []string{"env:prod"}}},
+	}, f)
+	run(t, u)
+
+	var updated []string
+	for _, record := range f.recordsOf("zone-example.com") {
+		if record.Content == "198.51.100.1" {
+			updated = append(updated, record.Name)
+			if !slices.Contains(record.Tags, "env:prod") || len(record.Tags) != 2 {
+				t.Errorf("%s: tags %q, want its own tags kept", record.Name, record.Tags)
+			}
+		}
+	}
+	slices.Sort(updated)
+	if want := []string{"home.example.com", "office.example.com"}; !slices.Equal(updated, want) {
+		t.Errorf("updated %q, want %q", updated, want)
+	}
+}
+
+func TestRunSelectByStampedComment(t *testing.T) {
+	f := selectionZone(t)
+	u := newTestUpdater(t, Config{
+		ZoneName:         "example.com",
+		APIToken:         "token",
+		Content:          "198.51.100.1",
+		SelectBy:         SelectByComment,
+		CommentTimestamp: true,
+		Records:          []Record{{Comment: "office"}},
+	}, f)
+
+	for i, want := range []Action{ActionUpdated, ActionUnchanged} {
+		rr := run(t, u).Records[0]
+		if rr.Name != "office.example.com" || rr.Action != want {
+			t.Errorf("run %d: %s %q (%s), want office.example.com %q", i+1, rr.Name, rr.Action, rr.Reason, want)
+		}
+	}
+	if puts := f.requestsFor("PUT"); len(puts) != 1 {
+		t.Errorf("sent %d updates, want the stamped record selected again and left alone", len(puts))
+	}
+}
+
+func TestRunSelectByGlob(t *testing.T) {
+	zone := func(t *testing.T) *fakeCloudflare {
+		f := newFakeCloudflare(t, "example.com")
+		for _, name := range []string{"home.dyn.example.com", "nas.dyn.example.com", "a.b.dyn.example.com", "Office.DYN.example.com", "dyn.example.com", "www.example.com", "x.dyn.example.com"} {
+			f.addRecord("zone-example.com", DNSRecord{Name: name, Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+		}
+		f.addRecord("zone-example.com", DNSRecord{Name: "home.dyn.example.com", Type: "TXT", Content: "hello"})
+		return f
+	}
+	for _, c := range []struct {
+		name    string
+		pattern string
+		extra   []DNSRecord
+		multi   MultiRecordStrategy
+		want    []string
+		action  Action
+		wantErr bool
+	}{
+		{"subdomains at any depth", "*.dyn.example.com", nil, "", []string{"Office.DYN.example.com", "a.b.dyn.example.com", "home.dyn.example.com", "nas.dyn.example.com", "x.dyn.example.com"}, ActionUpdated, false},
+		{"single character", "?.dyn.example.com", nil, "", []string{"x.dyn.example.com"}, ActionUpdated, false},
+		{"character class", "[hn]*.dyn.example.com", nil, "", []string{"home.dyn.example.com", "nas.dyn.example.com"}, ActionUpdated, false},
+		{"mixed case pattern", "OFFICE.dyn.*", nil, "", []string{"Office.DYN.example.com"}, ActionUpdated, false},
+		{"no match creates nothing", "*.static.example.com", nil, "", nil, ActionSkipped, false},
+		{"two records under one name", "*.dyn.example.com", []DNSRecord{{Name: "nas.dyn.example.com", Content: "192.0.2.2"}}, "", nil, ActionFailed, true},
+		// The set of the name is reconciled to the one address.
+		{"two records under one name reconciled", "nas.dyn.example.com", []DNSRecord{{Name: "nas.dyn.example.com", Content: "192.0.2.2"}}, MultiRecordAll, []string{"nas.dyn.example.com"}, ActionUpdated, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := zone(t)
+			for _, r := range c.extra {
+				r.Type, r.TTL = "A", AutoTTL
+				f.addRecord("zone-example.com", r)
+			}
+			u := newTestUpdater(t, Config{
+				ZoneName:            "example.com",
+				APIToken:            "token",
+				Content:             "198.51.100.1",
+				SelectBy:            SelectByGlob,
+				MultiRecordStrategy: c.multi,
+				Records:             []Record{{Name: c.pattern}},
+			}, f)
+
+			result, err := u.Run(context.Background())
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Run error %v, want error %t", err, c.wantErr)
+			}
+			for _, rr := range result.Records {
+				if rr.Action != c.action {
+					t.Errorf("%s: action %q, want %q", rr.Name, rr.Action, c.action)
+				}
+			}
+			var updated []string
+			for _, record := range f.recordsOf("zone-example.com") {
+				if record.Content == "198.51.100.1" {
+					if record.Type != "A" {
+						t.Errorf("%s %s updated, want only A records", record.Name, record.Type)
+					}
+					updated = append(updated, record.Name)
+				}
+			}
+			slices.Sort(updated)
+			if !slices.Equal(updated, c.want) {
+				t.Errorf("updated %q, want %q", updated, c.want)
+			}
+			if posts := f.requestsFor("POST"); len(posts) != 0 {
+				t.Errorf("sent %d creates, want none", len(posts))
+			}
+		})
+	}
+}
+
+func TestSelectByGlobPatternValidation(t *testing.T) {
+	for _, pattern := range []string{"", "*", "*.*", "[.example.com"} {
+		_, err := New(Config{
+			ZoneName: "example.com",
+			APIToken: "token",
+			Content:  "198.51.100.1",
+			SelectBy: SelectByGlob,
+			Records:  []Record{{Name: pattern}},
+			Logger:   testLogger(),
+		})
+		if err == nil {
+			t.Errorf("New with pattern %q succeeded, want an error", pattern)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ddns
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+)
+
+func TestRunStack(t *testing.T) {
+	for _, c := range []struct {
+		stack Stack
+		// want lists the name and type of each record written, and skipped
+		// the records left alone.
+		want    []string
+		skipped []string
+	}{
+		{"", []string{"home A", "v4 A", "v6 AAAA"}, nil},
+		{StackV4, []string{"home A", "v4 A"}, []string{"v6 AAAA"}},
+		{StackV6, []string{"home AAAA", "v6 AAAA"}, []string{"v4 A"}},
+		{StackBoth, []string{"home A", "home AAAA", "v4 A", "v6 AAAA"}, nil},
+	} {
+		t.Run(cmp.Or(string(c.stack), "unset"), func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			u := newTestUpdater(t, Config{
+				ZoneName:    "example.com",
+				APIToken:    "token",
+				Stack:       c.stack,
+				OverrideIPs: []string{"198.51.100.1", "2001:db8::1"},
+				Records: []Record{
+					{Name: "home.example.com"},
+					{Name: "v4.example.com", Type: "A"},
+					{Name: "v6.example.com", Type: "AAAA"},
+				},
+			}, f)
+			result := run(t, u)
+
+			var skipped []string
+			for _, rr := range result.Records {
+				if rr.Action == ActionSkipped {
+					skipped = append(skipped, short(rr.Name)+" "+rr.Type)
+				}
+			}
+			if !slices.Equal(skipped, c.skipped) {
+				t.Errorf("skipped %q, want %q", skipped, c.skipped)
+			}
+			var written []string
+			for _, record := range f.recordsOf("zone-example.com") {
+				written = append(written, short(record.Name)+" "+record.Type)
+				if want := map[string]string{"A": "198.51.100.1", "AAAA": "2001:db8::1"}[record.Type]; record.Content != want {
+					t.Errorf("%s %s: content %q, want %q", record.Name, record.Type, record.Content, want)
+				}
+			}
+			slices.Sort(written)
+			if !slices.Equal(written, c.want) {
+				t.Errorf("written %q, want %q", written, c.want)
+			}
+		})
+	}
+}
+
+// short trims the test zone from name.
+func short(name string) string {
+	return name[:len(name)-len(".example.com")]
+}
@@ -0,0 +1,66 @@
+package ddns
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/casantosmu/ddns-updater/internal/atomicfile"
+)
+
+// stateStore keeps the content last written to each record in
+// Config.StateFile, keyed by type and name. A nil store keeps nothing.
+type stateStore struct {
+	path    string
+	mu      sync.Mutex
+	written map[string]string
+}
+
+func loadState(path string) (*stateStore, error) {
+	s := &stateStore{path: path, written: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.written); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func stateKey(record Record) string {
+	return record.Type + " " + strings.ToLower(record.Name)
+}
+
+// get returns the content last written to record.
+func (s *stateStore) get(record Record) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.written[stateKey(record)]
+	return content, ok
+}
+
+// set records that content was written to record and saves the file.
+func (s *stateStore) set(record Record, content string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written[stateKey(record)] = content
+	data, err := json.MarshalIndent(s.written, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(s.path, append(data, '\n'), 0o600)
+}
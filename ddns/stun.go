@@ -0,0 +1,118 @@
+package ddns
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultSTUNServer answers the STUN binding requests of Diagnose.
+const DefaultSTUNServer = "stun.cloudflare.com:3478"
+
+const (
+	stunMagicCookie     = 0x2112A442
+	stunBindingRequest  = 0x0001
+	stunBindingResponse = 0x0101
+	stunMappedAddress   = 0x0001
+	stunXORMappedAddr   = 0x0020
+	stunTimeout         = 3 * time.Second
+)
+
+// stunAddress sends a STUN binding request (RFC 5389) to server over
+// network, "udp4" or "udp6", and returns the address the server saw.
+func stunAddress(ctx context.Context, network, server string) (net.IP, error) {
+	ctx, cancel := context.WithTimeout(ctx, stunTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	req := make([]byte, 20)
+	binary.BigEndian.PutUint16(req[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(req[4:], stunMagicCookie)
+	txID := req[8:20]
+	if _, err := rand.Read(txID); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return parseSTUNResponse(buf[:n], txID)
+}
+
+// parseSTUNResponse extracts the mapped address of a binding response,
+// preferring XOR-MAPPED-ADDRESS.
+func parseSTUNResponse(msg, txID []byte) (net.IP, error) {
+	if len(msg) < 20 || binary.BigEndian.Uint16(msg[0:]) != stunBindingResponse ||
+		binary.BigEndian.Uint32(msg[4:]) != stunMagicCookie || string(msg[8:20]) != string(txID) {
+		return nil, errors.New("not a STUN binding response to our request")
+	}
+	length := int(binary.BigEndian.Uint16(msg[2:]))
+	if 20+length > len(msg) {
+		return nil, errors.New("truncated STUN response")
+	}
+
+	var mapped net.IP
+	attrs := msg[20 : 20+length]
+	for len(attrs) >= 4 {
+		attrType := binary.BigEndian.Uint16(attrs[0:])
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:]))
+		if 4+attrLen > len(attrs) {
+			break
+		}
+		value := attrs[4 : 4+attrLen]
+		switch attrType {
+		case stunXORMappedAddr:
+			if ip := stunIP(value, msg[4:20]); ip != nil {
+				return ip, nil
+			}
+		case stunMappedAddress:
+			mapped = stunIP(value, nil)
+		}
+		// Attributes are padded to a multiple of 4 bytes.
+		attrs = attrs[min(len(attrs), 4+(attrLen+3)&^3):]
+	}
+	if mapped != nil {
+		return mapped, nil
+	}
+	return nil, fmt.Errorf("STUN response has no mapped address")
+}
+
+// stunIP decodes an address attribute, XORed with key (the magic cookie
+// and transaction ID) when key is not nil.
+func stunIP(value, key []byte) net.IP {
+	if len(value) < 4 {
+		return nil
+	}
+	size := net.IPv4len
+	if value[1] == 0x02 {
+		size = net.IPv6len
+	}
+	if len(value) < 4+size {
+		return nil
+	}
+	ip := make(net.IP, size)
+	copy(ip, value[4:4+size])
+	for i := range ip {
+		if key != nil {
+			ip[i] ^= key[i]
+		}
+	}
+	return ip
+}
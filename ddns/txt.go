@@ -0,0 +1,166 @@
+package ddns
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// TXTStrategy decides how Config.Content is written to TXT records.
+type TXTStrategy string
+
+const (
+	// TXTReplace writes Content as the whole record content. It is the
+	// default.
+	TXTReplace TXTStrategy = "replace"
+	// TXTAppend merges the terms of Content into the existing content,
+	// skipping those already present, and creates the record with Content
+	// when it does not exist.
+	TXTAppend TXTStrategy = "append"
+	// TXTRemove drops the terms of Content from the existing content.
+	TXTRemove TXTStrategy = "remove"
+)
+
+// maxTXTString is the longest character-string a TXT record holds; longer
+// content is split into several.
+const maxTXTString = 255
+
+// txtValue is TXT record content read as its text and whitespace-separated
+// terms, like the mechanisms and modifiers of an SPF record.
+type txtValue struct {
+	terms []string
+	// quoted records content written as one or more quoted strings, which
+	// the merged content keeps.
+	quoted bool
+}
+
+// parseTXT reads TXT content either as plain text or as quoted strings,
+// which are concatenated without a separator as RFC 7208 does for SPF.
+func parseTXT(content string) (txtValue, error) {
+	s := strings.TrimSpace(content)
+	if !strings.HasPrefix(s, `"`) {
+		return txtValue{terms: strings.Fields(s)}, nil
+	}
+	var b strings.Builder
+	for s != "" {
+		if s[0] != '"' {
+			return txtValue{}, fmt.Errorf("invalid TXT content %q: expected a quoted string", content)
+		}
+		i := 1
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' {
+				i++
+				if i == len(s) {
+					break
+				}
+			}
+			b.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return txtValue{}, fmt.Errorf("invalid TXT content %q: unterminated string", content)
+		}
+		s = strings.TrimLeft(s[i+1:], " \t")
+	}
+	return txtValue{terms: strings.Fields(b.String()), quoted: true}, nil
+}
+
+// String formats v back into TXT content, as quoted strings of at most
+// maxTXTString bytes when it was read from quoted content.
+func (v txtValue) String() string {
+	text := strings.Join(v.terms, " ")
+	if !v.quoted {
+		return text
+	}
+	var parts []string
+	for text != "" {
+		n := min(len(text), maxTXTString)
+		chunk := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text[:n])
+		parts = append(parts, `"`+chunk+`"`)
+		text = text[n:]
+	}
+	if len(parts) == 0 {
+		return `""`
+	}
+	return strings.Join(parts, " ")
+}
+
+// isAllTerm reports a term that must stay last in an SPF record: the all
+// mechanism, with any qualifier, or the redirect modifier.
+func isAllTerm(term string) bool {
+	term = strings.ToLower(term)
+	return strings.TrimLeft(term, "+-~?") == "all" || strings.HasPrefix(term, "redirect=")
+}
+
+func containsTerm(terms []string, term string) bool {
+	return slices.ContainsFunc(terms, func(t string) bool { return strings.EqualFold(t, term) })
+}
+
+// mergeTXT applies strategy to current, the existing record content, with
+// the terms of content. It returns current unchanged when no term is added
+// or removed, so the record compares as in sync.
+func mergeTXT(strategy TXTStrategy, current, content string) (string, error) {
+	existing, err := parseTXT(current)
+	if err != nil {
+		return "", err
+	}
+	value, err := parseTXT(content)
+	if err != nil {
+		return "", err
+	}
+
+	terms := slices.Clone(existing.terms)
+	switch strategy {
+	case TXTAppend:
+		for _, term := range value.terms {
+			if containsTerm(terms, term) || isAllTerm(term) && slices.ContainsFunc(terms, isAllTerm) {
+				continue
+			}
+			// New mechanisms go before all or redirect, which would
+			// otherwise shadow them.
+			at := slices.IndexFunc(terms, isAllTerm)
+			if at < 0 || isAllTerm(term) {
+				at = len(terms)
+			}
+			terms = slices.Insert(terms, at, term)
+		}
+	case TXTRemove:
+		terms = slices.DeleteFunc(terms, func(t string) bool { return containsTerm(value.terms, t) })
+		if len(terms) == 0 {
+			return "", fmt.Errorf("removing %q would leave the record empty", content)
+		}
+	}
+	if slices.Equal(terms, existing.terms) {
+		return current, nil
+	}
+	existing.terms = terms
+	return existing.String(), nil
+}
+
+func (cfg *Config) validateTXTStrategy() error {
+	if cfg.Content == "" {
+		return fmt.Errorf("TXT strategy %s requires record content", cfg.TXTStrategy)
+	}
+	value, err := parseTXT(cfg.Content)
+	if err != nil {
+		return err
+	}
+	if len(value.terms) == 0 {
+		return fmt.Errorf("TXT strategy %s requires non-empty record content", cfg.TXTStrategy)
+	}
+	if cfg.TXTStrategy == TXTRemove && slices.ContainsFunc(value.terms, func(t string) bool { return strings.HasPrefix(strings.ToLower(t), "v=") }) {
+		return errors.New("TXT strategy remove cannot remove the version term")
+	}
+	if cfg.SaaS {
+		return fmt.Errorf("TXT strategy %s does not apply to custom hostnames", cfg.TXTStrategy)
+	}
+	if cfg.MultiRecordStrategy == MultiRecordAll {
+		return fmt.Errorf("TXT strategy %s does not support multi-record strategy %s", cfg.TXTStrategy, MultiRecordAll)
+	}
+	for _, record := range cfg.Records {
+		if record.Type != "TXT" {
+			return fmt.Errorf("record %s: TXT strategy %s only applies to TXT records", record.Name, cfg.TXTStrategy)
+		}
+	}
+	return nil
+}
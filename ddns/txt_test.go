@@ -0,0 +1,84 @@
+package ddns
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeTXT(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		strategy TXTStrategy
+		current  string
+		content  string
+		want     string
+		wantErr  bool
+	}{
+		{"append before all", TXTAppend, "v=spf1 mx -all", "include:_spf.example.net", "v=spf1 mx include:_spf.example.net -all", false},
+		{"append before redirect", TXTAppend, "v=spf1 mx redirect=_spf.example.net", "a", "v=spf1 mx a redirect=_spf.example.net", false},
+		{"append at the end without all", TXTAppend, "v=spf1 mx", "a ip4:192.0.2.0/24", "v=spf1 mx a ip4:192.0.2.0/24", false},
+		{"append dedups case-insensitively", TXTAppend, "v=spf1 MX -all", "mx", "v=spf1 MX -all", false},
+		{"append dedups within content", TXTAppend, "v=spf1 -all", "a a", "v=spf1 a -all", false},
+		{"append keeps a single all", TXTAppend, "v=spf1 mx -all", "~all", "v=spf1 mx -all", false},
+		{"append all when missing", TXTAppend, "v=spf1 mx", "-all", "v=spf1 mx -all", false},
+		{"append keeps quoting", TXTAppend, `"v=spf1 mx -all"`, "a", `"v=spf1 mx a -all"`, false},
+		{"append joins split strings", TXTAppend, `"v=spf1 mx " "-all"`, "a", `"v=spf1 mx a -all"`, false},
+		{"append escapes quotes", TXTAppend, `"a=\"b\""`, "c", `"a=\"b\" c"`, false},
+		{"append to empty", TXTAppend, "", "v=spf1 -all", "v=spf1 -all", false},
+		{"remove", TXTRemove, "v=spf1 mx include:_spf.example.net -all", "include:_spf.example.net", "v=spf1 mx -all", false},
+		{"remove case-insensitively", TXTRemove, "v=spf1 MX a -all", "mx", "v=spf1 a -all", false},
+		{"remove absent term", TXTRemove, "v=spf1  mx -all", "a", "v=spf1  mx -all", false},
+		{"remove keeps quoting", TXTRemove, `"v=spf1 mx a -all"`, "a", `"v=spf1 mx -all"`, false},
+		{"remove every term", TXTRemove, "mx a", "a mx", "", true},
+		{"unterminated current", TXTAppend, `"v=spf1 mx`, "a", "", true},
+		{"text after a string", TXTAppend, `"v=spf1" mx`, "a", "", true},
+		{"replace keeps current", TXTReplace, "v=spf1 mx", "v=spf1 mx", "v=spf1 mx", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := mergeTXT(c.strategy, c.current, c.content)
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("mergeTXT = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != c.want {
+				t.Errorf("mergeTXT(%s, %q, %q) = %q, %v; want %q", c.strategy, c.current, c.content, got, err, c.want)
+			}
+		})
+	}
+}
+
+func TestTXTValueSplitsLongStrings(t *testing.T) {
+	long := strings.Repeat("x", 300)
+	got := txtValue{terms: []string{long}, quoted: true}.String()
+	if want := `"` + long[:maxTXTString] + `" "` + long[maxTXTString:] + `"`; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+	v, err := parseTXT(got)
+	if err != nil || len(v.terms) != 1 || v.terms[0] != long {
+		t.Errorf("parseTXT of the split value = %+v, %v; want the long term back", v, err)
+	}
+}
+
+func TestRunTXTAppendIdempotent(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "example.com", Type: "TXT", Content: `"v=spf1 mx -all"`, TTL: AutoTTL})
+	u := newTestUpdater(t, Config{
+		ZoneName:    "example.com",
+		APIToken:    "token",
+		Content:     "include:_spf.example.net",
+		TXTStrategy: TXTAppend,
+		Records:     []Record{{Name: "example.com", Type: "TXT"}},
+	}, f)
+
+	if rr := run(t, u).Records[0]; rr.Action != ActionUpdated {
+		t.Fatalf("first run: action %q, want %q", rr.Action, ActionUpdated)
+	}
+	if got := f.recordsOf("zone-example.com")[0].Content; got != `"v=spf1 mx include:_spf.example.net -all"` {
+		t.Errorf("content %q, want the term added before -all", got)
+	}
+	if rr := run(t, u).Records[0]; rr.Action != ActionUnchanged {
+		t.Errorf("second run: action %q, want %q", rr.Action, ActionUnchanged)
+	}
+}
@@ -0,0 +1,773 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Action is the outcome of syncing a single record.
+type Action string
+
+const (
+	ActionUnchanged Action = "unchanged"
+	ActionCreated   Action = "created"
+	ActionUpdated   Action = "updated"
+	ActionSkipped   Action = "skipped"
+	ActionFailed    Action = "failed"
+)
+
+// RecordResult reports what happened to one record during a run.
+type RecordResult struct {
+	Name     string
+	Type     string
+	Action   Action
+	Previous string
+	Content  string
+	Changes  []string
+	// Reason explains why a record was skipped.
+	Reason string
+	// Propagated reports whether public resolvers returned the new content
+	// in time. Only set when a propagation check ran.
+	Propagated *bool
+	// Diff compares the existing record with the desired one. Only set on
+	// dry runs.
+	Diff []FieldDiff
+	// Fallback names the Config.FallbackProvider the record was synced
+	// with because its primary DNS host failed.
+	Fallback string
+	// Latency is the time the Cloudflare calls that changed the record
+	// spent in flight; LatencyTotal adds the backoff between their retries.
+	// Both are zero when nothing was written.
+	Latency      time.Duration
+	LatencyTotal time.Duration
+	Err          error
+}
+
+// Result reports the outcome of a run.
+type Result struct {
+	// IPv4 and IPv6 are the detected public addresses. Each is only set when
+	// a record of the matching type needed it.
+	IPv4 string
+	IPv6 string
+	// Content is the configured content, when set.
+	Content string
+	// DryRun reports that the actions in Records were not carried out.
+	DryRun bool
+	// DeferredUntil, when set, is when the update window next opens for
+	// changes that were held back.
+	DeferredUntil time.Time
+	Records       []RecordResult
+}
+
+// Updater syncs the configured records with Cloudflare.
+type Updater struct {
+	cfg       Config
+	log       *slog.Logger
+	ipClients map[ipFamily]*http.Client
+	// cf holds one API client per credential name, "" being Config.APIToken.
+	cf map[string]*cloudflare
+
+	// zones caches zone IDs across runs; see zoneID.
+	zonesMu sync.Mutex
+	zones   map[zoneKey]string
+
+	// budget is shared by every retry of a run and refilled by Run.
+	budget *retryBudget
+
+	// stable holds the address of each family re-detected by confirmStable
+	// during the current run, and detected whether that run's contents came
+	// from public IP detection.
+	stableMu sync.Mutex
+	stable   map[ipFamily]string
+	detected bool
+
+	auditMu sync.Mutex
+
+	// state is nil unless Config.StateFile is set.
+	state *stateStore
+
+	// failure is the last notified failure and when it was sent; see
+	// dedupe.
+	notifyMu    sync.Mutex
+	failure     string
+	failureSent time.Time
+
+	// edge caches the ranges fetched from Config.EdgeRangesURL.
+	edgeMu sync.Mutex
+	edge   []*net.IPNet
+
+	// primaryUp is the last result of the failover health check, valid
+	// once failoverChecked is set.
+	failoverMu      sync.Mutex
+	failoverChecked bool
+	primaryUp       bool
+}
+
+type zoneKey struct {
+	credential string
+	zone       string
+}
+
+// New validates cfg, fills in defaults and returns an Updater ready to Run.
+func New(cfg Config) (*Updater, error) {
+	cfg.setDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	u := &Updater{
+		cfg: cfg,
+		log: cfg.Logger,
+		ipClients: map[ipFamily]*http.Client{
+			ipv4: newIPClient(cfg.IPHTTPTimeout, "tcp4"),
+			ipv6: newIPClient(cfg.IPHTTPTimeout, "tcp6"),
+		},
+		cf:     make(map[string]*cloudflare),
+		zones:  make(map[zoneKey]string),
+		budget: newRetryBudget(cfg.Retry.Budget),
+	}
+
+	cfClient := &http.Client{Timeout: cfg.CFHTTPTimeout}
+	limiter := newRateLimiter(cfg.CFRateLimit)
+	newCloudflare := func(token, accountID string) *cloudflare {
+		return &cloudflare{
+			client:     cfClient,
+			log:        cfg.Logger,
+			baseURL:    cloudflareBaseURL,
+			token:      token,
+			accountID:  accountID,
+			zoneSelect: cfg.ZoneSelect,
+			actor:      tokenActor(token),
+			debugDump:  cfg.DebugDump,
+			retry:      cfg.Retry,
+			timeout:    cfg.RequestTimeout,
+			limiter:    limiter,
+			budget:     u.budget,
+		}
+	}
+	if cfg.APIToken != "" {
+		u.cf[""] = newCloudflare(cfg.APIToken, cfg.AccountID)
+	}
+	if cfg.StateFile != "" {
+		state, err := loadState(cfg.StateFile)
+		if err != nil {
+			return nil, err
+		}
+		u.state = state
+	}
+	for name, cred := range cfg.Credentials {
+		if cred.Provider == nil {
+			u.cf[name] = newCloudflare(cred.APIToken, cred.AccountID)
+		}
+	}
+	return u, nil
+}
+
+// Config returns the configuration in use, with defaults filled in.
+func (u *Updater) Config() Config {
+	return u.cfg
+}
+
+// Run is a shorthand for New followed by Updater.Run.
+func Run(ctx context.Context, cfg Config) (*Result, error) {
+	u, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return u.Run(ctx)
+}
+
+// Run performs a single update cycle: it resolves the desired content,
+// then creates or updates every configured record that differs from it.
+// A failing record does not stop the others; the returned error reports
+// how many failed and each RecordResult carries its own error.
+func (u *Updater) Run(ctx context.Context) (*Result, error) {
+	u.budget.reset()
+	u.stableMu.Lock()
+	u.stable = make(map[ipFamily]string)
+	u.stableMu.Unlock()
+	result, err := u.run(ctx)
+	if !u.cfg.DryRun {
+		u.notify(ctx, result, err)
+	}
+	return result, err
+}
+
+func (u *Updater) run(ctx context.Context) (*Result, error) {
+	result := &Result{DryRun: u.cfg.DryRun}
+	contents, detected, err := u.resolveContents(ctx, result)
+	if err != nil {
+		return result, err
+	}
+	u.stableMu.Lock()
+	u.detected = detected
+	u.stableMu.Unlock()
+
+	zoneIDs := make(map[zoneKey]string)
+	zoneErrs := make(map[zoneKey]error)
+	// cached marks zone IDs taken from an earlier run, which are
+	// re-resolved once if Cloudflare no longer knows them.
+	cached := make(map[zoneKey]bool)
+	retryStale := func(key zoneKey, err error) bool {
+		if err == nil || !cached[key] || !isStaleZone(err) {
+			return false
+		}
+		stale := zoneIDs[key]
+		u.forgetZoneID(key)
+		cached[key] = false
+		zoneIDs[key], _, zoneErrs[key] = u.zoneID(ctx, key)
+		if zoneErrs[key] == nil {
+			u.log.Warn("Cached zone ID is no longer valid, using the re-resolved one", "zone", key.zone, "old_zone_id", stale, "zone_id", zoneIDs[key])
+		}
+		return true
+	}
+	failed := 0
+	for _, configured := range u.cfg.Records {
+		key := zoneKey{configured.Credential, configured.Zone}
+		if _, ok := zoneIDs[key]; u.cfg.providerFor(configured) == nil && !ok && zoneErrs[key] == nil {
+			zoneIDs[key], cached[key], zoneErrs[key] = u.zoneID(ctx, key)
+		}
+
+		// ok is false when no record was selected and rr says why.
+		records, rr, ok := u.selectRecords(ctx, zoneIDs[key], zoneErrs[key], configured)
+		if !ok && retryStale(key, rr.Err) {
+			records, rr, ok = u.selectRecords(ctx, zoneIDs[key], zoneErrs[key], configured)
+		}
+		if !ok {
+			if rr.Err != nil {
+				u.log.Error("Record selection failed", "select_by", u.cfg.SelectBy, "error", rr.Err)
+				failed++
+			}
+			result.Records = append(result.Records, rr)
+			continue
+		}
+
+		for _, record := range records {
+			desired := contents[record.Type]
+			rr := u.syncOne(ctx, zoneIDs[key], zoneErrs[key], record, desired)
+			if retryStale(key, rr.Err) {
+				rr = u.syncOne(ctx, zoneIDs[key], zoneErrs[key], record, desired)
+			}
+			if rr.Err != nil {
+				u.log.Error("Record sync failed", "record", record.Name, "error", rr.Err)
+				failed++
+			}
+			if rr.Reason == reasonOutsideWindow && result.DeferredUntil.IsZero() {
+				result.DeferredUntil = u.cfg.UpdateWindow.NextOpen(time.Now())
+			}
+			result.Records = append(result.Records, rr)
+		}
+	}
+	if failed > 0 {
+		return result, fmt.Errorf("%d of %d records failed to update", failed, len(result.Records))
+	}
+	return result, nil
+}
+
+// syncOne syncs record with the desired content in the zone zoneID, or
+// reports why it cannot. When its DNS host is down, the record is synced
+// with Config.FallbackProvider instead.
+func (u *Updater) syncOne(ctx context.Context, zoneID string, zoneErr error, record Record, desired desiredContent) RecordResult {
+	rr := u.syncPrimary(ctx, zoneID, zoneErr, record, desired)
+	fallback := u.cfg.FallbackProvider
+	if fallback == nil || desired.err != nil || rr.Action != ActionFailed || !isOutage(rr.Err) {
+		return rr
+	}
+	u.log.Warn("DNS host failed, syncing the record with the fallback provider", "record", record.Name, "type", record.Type, "fallback", fallback.Name(), "error", rr.Err)
+	frr := u.syncProviderRecord(ctx, fallback, record, desired.value)
+	frr.Fallback = fallback.Name()
+	if frr.Err != nil {
+		frr.Err = errors.Join(rr.Err, frr.Err)
+	}
+	return frr
+}
+
+func (u *Updater) syncPrimary(ctx context.Context, zoneID string, zoneErr error, record Record, desired desiredContent) RecordResult {
+	switch {
+	case !u.cfg.Stack.manages(record.Type):
+		u.log.Debug("Skipping record outside the managed stack", "record", record.Name, "type", record.Type, "stack", u.cfg.Stack)
+		return RecordResult{Name: record.Name, Type: record.Type, Action: ActionSkipped, Reason: fmt.Sprintf("%s records are not managed with stack %s", record.Type, u.cfg.Stack)}
+	case zoneErr != nil:
+		return failedRecord(record, zoneErr)
+	case desired.err != nil:
+		return failedRecord(record, desired.err)
+	case desired.skip != "":
+		u.log.Warn("Skipping record", "record", record.Name, "reason", desired.skip)
+		return RecordResult{Name: record.Name, Type: record.Type, Action: ActionSkipped, Reason: desired.skip}
+	case u.cfg.providerFor(record) != nil:
+		return u.syncProviderRecord(ctx, u.cfg.providerFor(record), record, desired.value)
+	case u.cfg.SaaS:
+		return u.syncCustomHostname(ctx, zoneID, record, desired.value)
+	case u.cfg.MultiRecordStrategy == MultiRecordAll:
+		return u.syncRecordSet(ctx, zoneID, record, desired.value)
+	default:
+		return u.syncRecord(ctx, zoneID, record, desired.value)
+	}
+}
+
+// zoneID returns the ID of key's zone, from the cache when an earlier run
+// resolved it.
+func (u *Updater) zoneID(ctx context.Context, key zoneKey) (id string, cached bool, err error) {
+	u.zonesMu.Lock()
+	id, ok := u.zones[key]
+	u.zonesMu.Unlock()
+	if ok {
+		return id, true, nil
+	}
+
+	id, err = u.cf[key.credential].getZoneID(ctx, key.zone)
+	if err != nil {
+		return "", false, err
+	}
+	u.zonesMu.Lock()
+	u.zones[key] = id
+	u.zonesMu.Unlock()
+	return id, false, nil
+}
+
+func (u *Updater) forgetZoneID(key zoneKey) {
+	u.zonesMu.Lock()
+	delete(u.zones, key)
+	u.zonesMu.Unlock()
+}
+
+// desiredContent is what records of one type should hold.
+type desiredContent struct {
+	value string
+	// skip, when set, explains why records of this type are left alone.
+	skip string
+	err  error
+}
+
+// resolveContents returns the desired content keyed by record type. Public
+// IP detection runs once per address family in use; it only fails the run
+// when detection failed for every family, otherwise records of the missing
+// family fail individually. detected reports whether the contents came from
+// that detection rather than configured, override or failover addresses.
+func (u *Updater) resolveContents(ctx context.Context, result *Result) (contents map[string]desiredContent, detected bool, err error) {
+	if len(u.cfg.OverrideIPs) > 0 {
+		return u.overrideContents(result), false, nil
+	}
+	if u.cfg.Failover != nil && !u.primaryHealthy(ctx) {
+		return u.failoverContents(result), false, nil
+	}
+	contents = make(map[string]desiredContent)
+
+	if u.cfg.Content != "" {
+		u.log.Info("Using configured record content", "content", u.cfg.Content)
+		result.Content = u.cfg.Content
+		for _, record := range u.cfg.Records {
+			contents[record.Type] = desiredContent{value: u.cfg.Content}
+		}
+		return contents, false, nil
+	}
+
+	var errs []error
+	for _, family := range u.families() {
+		recordType := "A"
+		if family == ipv6 {
+			recordType = "AAAA"
+		}
+		if !u.cfg.Stack.manages(recordType) {
+			continue
+		}
+
+		providers := u.providersFor(family)
+		if u.cfg.ShuffleProviders {
+			providers = shuffleProviders(providers)
+		}
+		ip, err := u.getPublicIP(ctx, family, providers)
+		if err != nil {
+			errs = append(errs, err)
+			contents[recordType] = desiredContent{err: err}
+			continue
+		}
+
+		if family == ipv4 {
+			result.IPv4 = ip
+			if u.cfg.CGNATCheck {
+				u.checkCGNAT(ip)
+			}
+		} else {
+			result.IPv6 = ip
+		}
+		if u.cfg.EdgeCheck {
+			u.checkEdge(ctx, ip)
+		}
+		contents[recordType] = u.checkDetectedIP(ip)
+	}
+	if len(errs) == len(contents) {
+		return nil, true, errors.Join(errs...)
+	}
+	return contents, true, nil
+}
+
+// overrideContents is resolveContents with Config.OverrideIPs set: their
+// address of each family in use, without any detection or sanity check.
+func (u *Updater) overrideContents(result *Result) map[string]desiredContent {
+	u.log.Warn("Public IP detection is overridden, records get the override addresses", "override_ips", strings.Join(u.cfg.OverrideIPs, ","))
+	contents := make(map[string]desiredContent)
+	for _, family := range u.families() {
+		recordType := "A"
+		if family == ipv6 {
+			recordType = "AAAA"
+		}
+		if !u.cfg.Stack.manages(recordType) {
+			continue
+		}
+		ip := familyAddress(u.cfg.OverrideIPs, family)
+		if family == ipv4 {
+			result.IPv4 = ip
+		} else {
+			result.IPv6 = ip
+		}
+		contents[recordType] = desiredContent{value: ip}
+	}
+	return contents
+}
+
+// reasonNotAllowed is the skip reason of records whose detected address
+// Config.AllowedIPRanges rejected.
+const reasonNotAllowed = "detected IP is outside the allowed ranges"
+
+// checkDetectedIP applies the sanity checks a detected address must pass
+// before it is written to DNS.
+func (u *Updater) checkDetectedIP(ip string) desiredContent {
+	if len(u.cfg.AllowedIPRanges) > 0 {
+		addr := net.ParseIP(ip)
+		if !slices.ContainsFunc(u.cfg.AllowedIPRanges, func(n *net.IPNet) bool { return n.Contains(addr) }) {
+			u.log.Warn("Detected IP is outside the allowed ranges, treating it as a provider error", "ip", ip)
+			return desiredContent{skip: reasonNotAllowed}
+		}
+	}
+	return desiredContent{value: ip}
+}
+
+// providersFor returns the family-specific provider list, falling back to
+// Config.IPProviders when it is unset. Config.IPCommand comes first unless
+// the list places it.
+func (u *Updater) providersFor(family ipFamily) []string {
+	providers := u.cfg.IPv4Providers
+	if family == ipv6 {
+		providers = u.cfg.IPv6Providers
+	}
+	if len(providers) == 0 {
+		providers = u.cfg.IPProviders
+	}
+	if u.cfg.IPCommand != "" && !slices.Contains(providers, commandProvider) {
+		if len(providers) == 0 {
+			providers = []string{"ipify"}
+		}
+		providers = append([]string{commandProvider}, providers...)
+	}
+	return providers
+}
+
+func (u *Updater) families() []ipFamily {
+	var families []ipFamily
+	seen := make(map[ipFamily]bool)
+	for _, record := range u.cfg.Records {
+		family := familyForType(record.Type)
+		if !seen[family] {
+			seen[family] = true
+			families = append(families, family)
+		}
+	}
+	return families
+}
+
+func failedRecord(record Record, err error) RecordResult {
+	return RecordResult{Name: record.Name, Type: record.Type, Action: ActionFailed, Err: err}
+}
+
+// syncRecord syncs a single record, reading it again once when it was
+// modified between the read and a conditional update.
+func (u *Updater) syncRecord(ctx context.Context, zoneID string, record Record, content string) RecordResult {
+	rr := u.syncRecordOnce(ctx, zoneID, record, content)
+	if isConcurrentUpdate(rr.Err) {
+		u.log.Warn("Record was modified concurrently, reading it again", "record", record.Name, "type", record.Type)
+		rr = u.syncRecordOnce(ctx, zoneID, record, content)
+	}
+	return rr
+}
+
+func (u *Updater) syncRecordOnce(ctx context.Context, zoneID string, record Record, content string) RecordResult {
+	cf := u.cf[record.Credential]
+	rr := RecordResult{Name: record.Name, Type: record.Type, Content: content}
+	fail := func(err error) RecordResult {
+		rr.Action = ActionFailed
+		rr.Err = err
+		return rr
+	}
+
+	recordData, err := cf.getRecordData(ctx, zoneID, record.Name, record.Type)
+	if err != nil {
+		return fail(err)
+	}
+	if recordData != nil && u.cfg.CommentTimestamp {
+		recordData.Comment = stripCommentStamp(recordData.Comment)
+	}
+	if u.cfg.TXTStrategy != TXTReplace {
+		if recordData == nil && u.cfg.TXTStrategy == TXTRemove {
+			u.log.Info("Record does not exist, nothing to remove", "record", record.Name)
+			rr.Action = ActionSkipped
+			rr.Reason = "record does not exist, nothing to remove"
+			return rr
+		}
+		if recordData != nil {
+			merged, err := mergeTXT(u.cfg.TXTStrategy, recordData.Content, content)
+			if err != nil {
+				return fail(err)
+			}
+			content, rr.Content = merged, merged
+		}
+	}
+	if u.cfg.DryRun {
+		rr.Diff = recordDiff(record, recordData, content)
+	}
+
+	if recordData == nil {
+		if u.cfg.OnMissing != MissingCreate {
+			return u.missingRecord(rr)
+		}
+		if u.cfg.DryRun {
+			u.log.Info("Dry run, record would be created", "record", record.Name, "type", record.Type)
+			rr.Action = ActionCreated
+			return rr
+		}
+		if u.deferChange(&rr) {
+			return rr
+		}
+		u.log.Info("Record does not exist, creating", "record", record.Name, "type", record.Type)
+		err := timeChange(ctx, &rr, func(ctx context.Context) error {
+			return cf.createDNSRecord(ctx, zoneID, buildPayload(u.stampRecord(record), content))
+		})
+		u.audit(AuditEntry{Actor: cf.actor, Op: "create", Record: record.Name, Type: record.Type, New: content}, err)
+		if err != nil {
+			return fail(err)
+		}
+		rr.Action = ActionCreated
+		u.saveState(record, content)
+		u.checkPropagation(ctx, record, &rr)
+		return rr
+	}
+	if recordData.Content == "" && record.Type != "SRV" {
+		if last, ok := u.state.get(record); ok {
+			u.log.Info("Record content is unreadable, comparing with the last written content", "record", record.Name, "content", last)
+			recordData.Content = last
+		} else {
+			u.log.Warn("Record content is unreadable, forcing update", "record", record.Name, "proxied", recordData.Proxied)
+		}
+	}
+	rr.Previous = recordData.Content
+
+	changes := diffRecord(record, recordData, content, u.cfg.UpdateFields)
+	if len(changes) == 0 {
+		changes = u.refreshChanges(recordData)
+	}
+	if len(changes) == 0 {
+		u.log.Info("Record not changed", "record", record.Name, "content", content)
+		rr.Action = ActionUnchanged
+		return rr
+	}
+	rr.Changes = changes
+
+	if isIPType(record.Type) && isPlaceholder(recordData) && !sameContent(recordData.Content, content) {
+		if u.cfg.OnPlaceholder == PlaceholderSkip {
+			u.log.Warn("Record holds a Cloudflare placeholder address, not an origin, skipping", "record", record.Name, "content", recordData.Content)
+			rr.Action = ActionSkipped
+			rr.Reason = "record holds a Cloudflare placeholder address"
+			return rr
+		}
+		u.log.Warn("Record holds a Cloudflare placeholder address, replacing it", "record", record.Name, "content", recordData.Content)
+	}
+
+	if !sameContent(recordData.Content, content) && !u.confirmStable(ctx, record, content) {
+		rr.Action = ActionSkipped
+		rr.Reason = reasonUnstable
+		return rr
+	}
+
+	if recordData.Locked {
+		if u.cfg.OnLocked == LockedError {
+			return fail(fmt.Errorf("record is locked on Cloudflare and cannot be updated (%s)", strings.Join(changes, ", ")))
+		}
+		u.log.Warn("Skipping locked record", "record", record.Name, "changes", strings.Join(changes, ", "))
+		rr.Action = ActionSkipped
+		rr.Reason = "record is locked on Cloudflare"
+		return rr
+	}
+
+	if u.cfg.DryRun {
+		u.log.Info("Dry run, record would be updated", "record", record.Name, "changes", strings.Join(changes, ", "))
+		rr.Action = ActionUpdated
+		return rr
+	}
+	if u.deferChange(&rr) {
+		return rr
+	}
+
+	if record.Tags == nil {
+		record.Tags = recordData.Tags
+	}
+	if record.Comment == "" {
+		record.Comment = recordData.Comment
+	}
+	u.log.Info("Record changed, updating", "record", record.Name, "changes", strings.Join(changes, ", "))
+	err = timeChange(ctx, &rr, func(ctx context.Context) error {
+		return cf.updateDNSRecord(ctx, zoneID, recordData.ID, recordData.etag, buildPayload(u.stampRecord(record), content))
+	})
+	u.audit(AuditEntry{Actor: cf.actor, Op: "update", Record: record.Name, Type: record.Type, Old: recordData.Content, New: content}, err)
+	if err != nil {
+		return fail(err)
+	}
+	rr.Action = ActionUpdated
+	u.saveState(record, content)
+	u.checkPropagation(ctx, record, &rr)
+	return rr
+}
+
+// saveState records content as last written to record in Config.StateFile.
+// A failure only costs the comparison of unreadable content on later runs.
+func (u *Updater) saveState(record Record, content string) {
+	if err := u.state.set(record, content); err != nil {
+		u.log.Warn("Failed to write state file", "path", u.cfg.StateFile, "error", err)
+	}
+}
+
+// refreshChanges returns the change describing a rewrite of an unchanged
+// record last modified longer ago than Config.RefreshAfter, or nil when the
+// record is fresh or Cloudflare did not report when it was modified.
+func (u *Updater) refreshChanges(recordData *DNSRecord) []string {
+	if u.cfg.RefreshAfter <= 0 || recordData.ModifiedOn.IsZero() {
+		return nil
+	}
+	age := time.Since(recordData.ModifiedOn)
+	if age < u.cfg.RefreshAfter {
+		return nil
+	}
+	u.log.Info("Record is stale, refreshing", "record", recordData.Name, "modified_on", recordData.ModifiedOn, "refresh_after", u.cfg.RefreshAfter)
+	return []string{fmt.Sprintf("refresh, last modified %s ago", age.Round(time.Second))}
+}
+
+// reasonUnstable is the skip reason of changes to an address that moved
+// again within Config.ConfirmStable.
+const reasonUnstable = "detected address is not stable"
+
+// confirmStable reports whether the detected address of record's family is
+// still ip after Config.ConfirmStable. The address is re-detected at most
+// once per run; contents that were not detected, such as configured,
+// override or failover addresses, and dry runs are never delayed.
+func (u *Updater) confirmStable(ctx context.Context, record Record, ip string) bool {
+	if u.cfg.ConfirmStable <= 0 || u.cfg.DryRun {
+		return true
+	}
+	family := familyForType(record.Type)
+
+	u.stableMu.Lock()
+	defer u.stableMu.Unlock()
+	if !u.detected {
+		return true
+	}
+	again, ok := u.stable[family]
+	if !ok {
+		u.log.Info("Address changed, confirming it is stable", "family", family, "ip", ip, "wait", u.cfg.ConfirmStable)
+		if err := sleep(ctx, u.cfg.ConfirmStable); err != nil {
+			return false
+		}
+		var err error
+		again, err = u.getPublicIP(ctx, family, u.providersFor(family))
+		if err != nil {
+			u.log.Warn("Could not confirm the address is stable", "family", family, "error", err)
+		}
+		u.stable[family] = again
+	}
+	if !sameContent(again, ip) {
+		u.log.Warn("Address changed again while confirming, skipping update", "record", record.Name, "ip", ip, "now", again)
+		return false
+	}
+	return true
+}
+
+// reasonOutsideWindow is the skip reason of changes held back by
+// Config.UpdateWindow.
+const reasonOutsideWindow = "outside the update window"
+
+// deferChange reports whether a create or update must wait for the update
+// window, marking rr as skipped if so.
+func (u *Updater) deferChange(rr *RecordResult) bool {
+	w := u.cfg.UpdateWindow
+	if w == nil || w.Contains(time.Now()) {
+		return false
+	}
+	u.log.Info("Change deferred until the update window opens", "record", rr.Name, "window", w.String(), "changes", strings.Join(rr.Changes, ", "))
+	rr.Action = ActionSkipped
+	rr.Reason = reasonOutsideWindow
+	return true
+}
+
+// missingRecord applies a skip or error OnMissing policy to a record that
+// does not exist.
+func (u *Updater) missingRecord(rr RecordResult) RecordResult {
+	if u.cfg.OnMissing == MissingError {
+		rr.Action = ActionFailed
+		rr.Err = errors.New("record does not exist")
+		return rr
+	}
+	u.log.Warn("Record does not exist, skipping", "record", rr.Name, "type", rr.Type)
+	rr.Action = ActionSkipped
+	rr.Reason = "record does not exist"
+	return rr
+}
+
+func (u *Updater) checkPropagation(ctx context.Context, record Record, rr *RecordResult) {
+	if u.cfg.Propagation == nil {
+		return
+	}
+	propagated := u.waitForPropagation(ctx, record, rr.Content)
+	rr.Propagated = &propagated
+}
+
+// diffRecord describes how the existing record differs from the desired one.
+// An empty result means no update is needed. Content is compared against
+// the record's stored content, the origin address, which Cloudflare reports
+// unchanged for proxied records; the edge addresses clients resolve are
+// never consulted. Content that could not be read compares as changed.
+// Other fields are only compared when listed in fields. A proxied record's
+// TTL is left out, since Cloudflare always reports it as automatic, and an
+// empty desired comment means "keep the existing one".
+func diffRecord(record Record, existing *DNSRecord, content string, fields []string) []string {
+	var changes []string
+
+	if record.Type == "SRV" {
+		desired := buildSRVData(record, content)
+		if existing.Data == nil || *existing.Data != desired {
+			changes = append(changes, "data")
+		}
+	} else {
+		if !sameContent(existing.Content, content) {
+			changes = append(changes, fmt.Sprintf("content %s -> %s", existing.Content, content))
+		}
+		if record.Type == "MX" && existing.Priority != record.Priority {
+			changes = append(changes, fmt.Sprintf("priority %d -> %d", existing.Priority, record.Priority))
+		}
+		if slices.Contains(fields, "proxied") && existing.Proxied != record.Proxied {
+			changes = append(changes, fmt.Sprintf("proxied %t -> %t", existing.Proxied, record.Proxied))
+		}
+	}
+	if slices.Contains(fields, "ttl") && !record.Proxied && existing.TTL != record.TTL {
+		changes = append(changes, fmt.Sprintf("ttl %s -> %s", formatTTL(existing.TTL), formatTTL(record.TTL)))
+	}
+	if slices.Contains(fields, "comment") && record.Comment != "" && existing.Comment != record.Comment {
+		changes = append(changes, fmt.Sprintf("comment %q -> %q", existing.Comment, record.Comment))
+	}
+	return changes
+}
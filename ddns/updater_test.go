@@ -0,0 +1,633 @@
+package ddns
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunMixedProxiedRecords(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "vpn.example.com", Type: "A", Content: "192.0.2.1", Proxied: true, TTL: AutoTTL})
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Records: []Record{
+			{Name: "home.example.com", Proxied: true},
+			{Name: "vpn.example.com", TTL: 300},
+			{Name: "mail.example.com", TTL: 3600},
+		},
+	}, f)
+
+	result := run(t, u)
+	actions := make(map[string]Action)
+	for _, rr := range result.Records {
+		actions[rr.Name] = rr.Action
+	}
+	want := map[string]Action{"home.example.com": ActionCreated, "vpn.example.com": ActionUpdated, "mail.example.com": ActionCreated}
+	for name, action := range want {
+		if actions[name] != action {
+			t.Errorf("%s: action %q, want %q", name, actions[name], action)
+		}
+	}
+
+	bodies := make(map[string]map[string]any)
+	for _, req := range append(f.requestsFor("POST"), f.requestsFor("PUT")...) {
+		body := decodeBody(t, req)
+		bodies[body["name"].(string)] = body
+	}
+	for _, c := range []struct {
+		name    string
+		proxied bool
+		ttl     float64
+	}{
+		{"home.example.com", true, AutoTTL},
+		{"vpn.example.com", false, 300},
+		{"mail.example.com", false, 3600},
+	} {
+		body, ok := bodies[c.name]
+		if !ok {
+			t.Errorf("%s: no create or update sent", c.name)
+			continue
+		}
+		if body["proxied"] != c.proxied || body["ttl"] != c.ttl || body["content"] != testIP {
+			t.Errorf("%s: sent proxied=%v ttl=%v content=%v, want proxied=%v ttl=%v content=%s", c.name, body["proxied"], body["ttl"], body["content"], c.proxied, c.ttl, testIP)
+		}
+	}
+}
+
+func TestRunPerRecordCredentials(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com", "example.org")
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token-com",
+		Credentials: map[string]Credential{
+			"org": {APIToken: "token-org"},
+		},
+		Records: []Record{
+			{Name: "home.example.com"},
+			{Name: "home.example.org", Zone: "example.org", Credential: "org"},
+		},
+	}, f)
+	run(t, u)
+
+	wantToken := map[string]string{"zone-example.com": "Bearer token-com", "zone-example.org": "Bearer token-org"}
+	zoneToken := map[string]string{"example.com": "Bearer token-com", "example.org": "Bearer token-org"}
+	seen := make(map[string]bool)
+	for _, req := range f.requestsFor("") {
+		if req.Path == "/ip" {
+			continue
+		}
+		want, ok := "", false
+		if req.Path == "/zones" {
+			want, ok = zoneToken[req.Query.Get("name")]
+		} else {
+			for zoneID, token := range wantToken {
+				if strings.HasPrefix(req.Path, "/zones/"+zoneID+"/") {
+					want, ok = token, true
+				}
+			}
+		}
+		if !ok {
+			t.Errorf("unexpected request %s %s", req.Method, req.Path)
+			continue
+		}
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s %s?%s: Authorization %q, want %q", req.Method, req.Path, req.Query.Encode(), got, want)
+		}
+		seen[want] = true
+	}
+	if len(seen) != 2 {
+		t.Errorf("requests used tokens %v, want both", seen)
+	}
+	for zoneID := range wantToken {
+		if records := f.recordsOf(zoneID); len(records) != 1 || records[0].Content != testIP {
+			t.Errorf("%s records = %+v, want one with %s", zoneID, records, testIP)
+		}
+	}
+}
+
+func TestDiffRecordProxied(t *testing.T) {
+	all := slices.Clone(updateFields)
+	proxied := Record{Name: "home.example.com", Type: "A", Proxied: true, TTL: AutoTTL}
+	for _, c := range []struct {
+		name     string
+		record   Record
+		existing DNSRecord
+		fields   []string
+		want     []string
+	}{
+		{"same origin", proxied, DNSRecord{Content: "203.0.113.10", Proxied: true, TTL: AutoTTL}, all, nil},
+		{"same origin other text form", Record{Name: "home.example.com", Type: "AAAA", Proxied: true, TTL: AutoTTL}, DNSRecord{Content: "2001:db8:0::1", Proxied: true, TTL: AutoTTL}, all, nil},
+		{"other origin", proxied, DNSRecord{Content: "203.0.113.9", Proxied: true, TTL: AutoTTL}, all, []string{"content 203.0.113.9 -> 203.0.113.10"}},
+		{"ttl ignored while proxied", Record{Name: "home.example.com", Type: "A", Proxied: true, TTL: 300}, DNSRecord{Content: "203.0.113.10", Proxied: true, TTL: AutoTTL}, all, nil},
+		{"unreadable content", proxied, DNSRecord{Proxied: true, TTL: AutoTTL}, all, []string{"content  -> 203.0.113.10"}},
+		{"proxied turned on", proxied, DNSRecord{Content: "203.0.113.10", TTL: AutoTTL}, all, []string{"proxied false -> true"}},
+		{"proxied not compared", proxied, DNSRecord{Content: "203.0.113.10", TTL: AutoTTL}, []string{"content", "ttl"}, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			content := testIP
+			if c.record.Type == "AAAA" {
+				content = "2001:db8::1"
+			}
+			existing := c.existing
+			if got := diffRecord(c.record, &existing, content, c.fields); !slices.Equal(got, c.want) {
+				t.Errorf("diffRecord = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRunMixedCaseZone(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+	u := newTestUpdater(t, Config{
+		ZoneName: "Example.COM",
+		APIToken: "token",
+		Records:  []Record{{Name: "Home.Example.com"}},
+	}, f)
+
+	if result := run(t, u); result.Records[0].Action != ActionUpdated {
+		t.Fatalf("action %q, want %q", result.Records[0].Action, ActionUpdated)
+	}
+	for _, req := range f.requestsFor("GET") {
+		if name := req.Query.Get("name"); name != "" && name != strings.ToLower(name) {
+			t.Errorf("%s queried name %q, want it lowercased", req.Path, name)
+		}
+	}
+	if records := f.recordsOf("zone-example.com"); len(records) != 1 || records[0].Content != testIP {
+		t.Errorf("records = %+v, want home.example.com updated in place", records)
+	}
+}
+
+func TestRunFieldOnlyChanges(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		existing DNSRecord
+		record   Record
+		fields   []string
+		action   Action
+		changes  []string
+	}{
+		{"ttl only", DNSRecord{TTL: 300}, Record{TTL: 600}, nil, ActionUpdated, []string{"ttl 300 -> 600"}},
+		{"proxied only", DNSRecord{TTL: AutoTTL}, Record{Proxied: true, TTL: AutoTTL}, nil, ActionUpdated, []string{"proxied false -> true"}},
+		{"ttl not compared", DNSRecord{TTL: 300}, Record{TTL: 600}, []string{"content", "proxied"}, ActionUnchanged, nil},
+		{"proxied not compared", DNSRecord{TTL: AutoTTL}, Record{Proxied: true, TTL: AutoTTL}, []string{"content", "ttl"}, ActionUnchanged, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			existing := c.existing
+			existing.Name, existing.Type, existing.Content = "home.example.com", "A", testIP
+			f.addRecord("zone-example.com", existing)
+			record := c.record
+			record.Name = "home.example.com"
+			u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", UpdateFields: c.fields, Records: []Record{record}}, f)
+
+			rr := run(t, u).Records[0]
+			if rr.Action != c.action || !slices.Equal(rr.Changes, c.changes) {
+				t.Fatalf("action %q changes %q, want %q %q", rr.Action, rr.Changes, c.action, c.changes)
+			}
+			puts := f.requestsFor("PUT")
+			if c.action == ActionUnchanged {
+				if len(puts) != 0 {
+					t.Errorf("sent %d updates, want none", len(puts))
+				}
+				return
+			}
+			if len(puts) != 1 {
+				t.Fatalf("sent %d updates, want 1", len(puts))
+			}
+			body := decodeBody(t, puts[0])
+			if body["proxied"] != record.Proxied || body["ttl"] != float64(record.TTL) || body["content"] != testIP {
+				t.Errorf("update body = %s", puts[0].Body)
+			}
+		})
+	}
+}
+
+func TestRunOnMissing(t *testing.T) {
+	for _, c := range []struct {
+		policy  MissingPolicy
+		action  Action
+		wantErr bool
+		creates int
+	}{
+		{MissingCreate, ActionCreated, false, 1},
+		{MissingSkip, ActionSkipped, false, 0},
+		{MissingError, ActionFailed, true, 0},
+	} {
+		t.Run(string(c.policy), func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", OnMissing: c.policy, Records: []Record{{Name: "home.example.com"}}}, f)
+
+			result, err := u.Run(context.Background())
+			if (err != nil) != c.wantErr {
+				t.Fatalf("Run error %v, want error %t", err, c.wantErr)
+			}
+			rr := result.Records[0]
+			if rr.Action != c.action {
+				t.Errorf("action %q, want %q", rr.Action, c.action)
+			}
+			if c.policy == MissingSkip && rr.Reason != "record does not exist" {
+				t.Errorf("reason %q, want record does not exist", rr.Reason)
+			}
+			if got := len(f.requestsFor("POST")); got != c.creates {
+				t.Errorf("sent %d creates, want %d", got, c.creates)
+			}
+		})
+	}
+}
+
+func TestProxiedTTLWarning(t *testing.T) {
+	var logs bytes.Buffer
+	f := newFakeCloudflare(t, "example.com")
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Logger:   slog.New(slog.NewTextHandler(&logs, nil)),
+		Records: []Record{
+			{Name: "home.example.com", Proxied: true, TTL: 300},
+			{Name: "vpn.example.com", Proxied: true, TTL: AutoTTL},
+		},
+	}, f)
+
+	if got := strings.Count(logs.String(), "TTL is ignored for proxied records"); got != 1 {
+		t.Errorf("logged %d proxied TTL warnings, want 1 for home.example.com:\n%s", got, logs.String())
+	}
+	if !strings.Contains(logs.String(), "record=home.example.com ttl=300") {
+		t.Errorf("warning does not name the record and TTL:\n%s", logs.String())
+	}
+	run(t, u)
+	for _, req := range f.requestsFor("POST") {
+		if body := decodeBody(t, req); body["ttl"] != float64(AutoTTL) || body["proxied"] != true {
+			t.Errorf("create body = %s, want proxied with ttl %d", req.Body, AutoTTL)
+		}
+	}
+}
+
+func TestRunPlaceholderContent(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		existing DNSRecord
+		policy   PlaceholderPolicy
+		action   Action
+	}{
+		{"proxied CGNAT placeholder skipped", DNSRecord{Type: "A", Content: "100.64.0.1", Proxied: true}, PlaceholderSkip, ActionSkipped},
+		{"proxied discard prefix skipped", DNSRecord{Type: "AAAA", Content: "100::", Proxied: true}, PlaceholderSkip, ActionSkipped},
+		{"proxied placeholder replaced", DNSRecord{Type: "A", Content: "100.64.0.1", Proxied: true}, PlaceholderUpdate, ActionUpdated},
+		{"unproxied CGNAT origin updated", DNSRecord{Type: "A", Content: "100.64.0.1"}, PlaceholderSkip, ActionUpdated},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			existing := c.existing
+			existing.Name, existing.TTL = "home.example.com", AutoTTL
+			f.addRecord("zone-example.com", existing)
+			content := "198.51.100.1"
+			if existing.Type == "AAAA" {
+				content = "2001:db8::1"
+			}
+			u := newTestUpdater(t, Config{
+				ZoneName:      "example.com",
+				APIToken:      "token",
+				Content:       content,
+				OnPlaceholder: c.policy,
+				Records:       []Record{{Name: "home.example.com", Type: existing.Type, Proxied: existing.Proxied}},
+			}, f)
+
+			rr := run(t, u).Records[0]
+			if rr.Action != c.action {
+				t.Fatalf("action %q (%s), want %q", rr.Action, rr.Reason, c.action)
+			}
+			wantPuts := 1
+			if c.action == ActionSkipped {
+				wantPuts = 0
+				if rr.Reason != "record holds a Cloudflare placeholder address" {
+					t.Errorf("reason %q", rr.Reason)
+				}
+			}
+			if got := len(f.requestsFor("PUT")); got != wantPuts {
+				t.Errorf("sent %d updates, want %d", got, wantPuts)
+			}
+		})
+	}
+}
+
+func TestRunStaleCachedZoneID(t *testing.T) {
+	var logs bytes.Buffer
+	f := newFakeCloudflare(t, "example.com")
+	u := newTestUpdater(t, Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Logger:   slog.New(slog.NewTextHandler(&logs, nil)),
+		Records:  []Record{{Name: "home.example.com"}},
+	}, f)
+	run(t, u)
+
+	// The zone is deleted and added again, under a new ID.
+	f.mu.Lock()
+	f.zones[0].ID = "zone-readded"
+	f.records["zone-readded"] = f.records["zone-example.com"]
+	delete(f.records, "zone-example.com")
+	f.mu.Unlock()
+	logs.Reset()
+
+	result, err := u.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run after the zone was re-added: %v", err)
+	}
+	if rr := result.Records[0]; rr.Action != ActionUnchanged {
+		t.Errorf("action %q, want %q from the re-resolved zone", rr.Action, ActionUnchanged)
+	}
+	out := logs.String()
+	if !strings.Contains(out, "Cached zone ID is no longer valid") || !strings.Contains(out, "old_zone_id=zone-example.com") || !strings.Contains(out, "zone_id=zone-readded") {
+		t.Errorf("missing the stale zone warning:\n%s", out)
+	}
+	var lookups int
+	for _, req := range f.requestsFor("GET") {
+		if req.Path == "/zones" {
+			lookups++
+		}
+	}
+	if lookups != 2 {
+		t.Errorf("looked the zone up %d times, want once per run that needed it", lookups)
+	}
+}
+
+func TestRunConfirmStable(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		seen   []string
+		action Action
+	}{
+		{"reverts within the window", []string{"198.51.100.2", "198.51.100.1"}, ActionSkipped},
+		{"persists", []string{"198.51.100.2", "198.51.100.2"}, ActionUpdated},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var calls atomic.Int32
+			provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				i := int(calls.Add(1)) - 1
+				fmt.Fprint(w, c.seen[min(i, len(c.seen)-1)])
+			}))
+			defer provider.Close()
+			f := newFakeCloudflare(t, "example.com")
+			f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "198.51.100.1", TTL: AutoTTL})
+			u := newTestUpdater(t, Config{
+				ZoneName:      "example.com",
+				APIToken:      "token",
+				IPProviders:   []string{provider.URL},
+				ConfirmStable: 10 * time.Millisecond,
+				Records:       []Record{{Name: "home.example.com"}},
+			}, f)
+
+			rr := run(t, u).Records[0]
+			if rr.Action != c.action {
+				t.Fatalf("action %q, want %q", rr.Action, c.action)
+			}
+			if calls.Load() != 2 {
+				t.Errorf("asked the provider %d times, want a detection and one confirmation", calls.Load())
+			}
+			puts := f.requestsFor("PUT")
+			if c.action == ActionSkipped {
+				if len(puts) != 0 || rr.Reason != reasonUnstable {
+					t.Errorf("sent %d updates with reason %q, want none and %q", len(puts), rr.Reason, reasonUnstable)
+				}
+				return
+			}
+			if len(puts) != 1 || decodeBody(t, puts[0])["content"] != "198.51.100.2" {
+				t.Errorf("updates = %+v, want one to 198.51.100.2", puts)
+			}
+		})
+	}
+}
+
+func TestRunConfirmStableOnlyDetected(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	for _, c := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"override", Config{OverrideIPs: []string{"203.0.113.99"}}},
+		{"failover", Config{Failover: &FailoverCheck{HealthURL: down.URL, FailoverIPs: []string{"203.0.113.99"}}}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Errorf("asked the IP provider, want %s contents used as they are", c.name)
+				fmt.Fprint(w, "198.51.100.2")
+			}))
+			defer provider.Close()
+			f := newFakeCloudflare(t, "example.com")
+			f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "198.51.100.1", TTL: AutoTTL})
+			cfg := c.cfg
+			cfg.ZoneName, cfg.APIToken = "example.com", "token"
+			cfg.IPProviders = []string{provider.URL}
+			cfg.ConfirmStable = 10 * time.Millisecond
+			cfg.Records = []Record{{Name: "home.example.com"}}
+			u := newTestUpdater(t, cfg, f)
+
+			rr := run(t, u).Records[0]
+			if rr.Action != ActionUpdated {
+				t.Fatalf("action %q (%s), want %q", rr.Action, rr.Reason, ActionUpdated)
+			}
+			puts := f.requestsFor("PUT")
+			if len(puts) != 1 || decodeBody(t, puts[0])["content"] != "203.0.113.99" {
+				t.Errorf("updates = %+v, want one to 203.0.113.99", puts)
+			}
+		})
+	}
+}
+
+func TestRunOverrideIPs(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("asked the IP provider, want override addresses used instead")
+		fmt.Fprint(w, "192.0.2.99")
+	}))
+	defer provider.Close()
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+	u := newTestUpdater(t, Config{
+		ZoneName:    "example.com",
+		APIToken:    "token",
+		IPProviders: []string{provider.URL},
+		OverrideIPs: []string{"2001:db8::10", "203.0.113.99"},
+		Records:     []Record{{Name: "home.example.com", Type: "A"}, {Name: "home.example.com", Type: "AAAA"}},
+	}, f)
+
+	result := run(t, u)
+	if result.IPv4 != "203.0.113.99" || result.IPv6 != "2001:db8::10" {
+		t.Errorf("result addresses %s and %s, want the override ones", result.IPv4, result.IPv6)
+	}
+	for _, record := range f.recordsOf("zone-example.com") {
+		if want := map[string]string{"A": "203.0.113.99", "AAAA": "2001:db8::10"}[record.Type]; record.Content != want {
+			t.Errorf("%s record content %q, want %q", record.Type, record.Content, want)
+		}
+	}
+}
+
+func TestRunRefreshStaleRecord(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		modified time.Duration
+		action   Action
+	}{
+		{"stale", 48 * time.Hour, ActionUpdated},
+		{"fresh", time.Hour, ActionUnchanged},
+		{"modification time unknown", 0, ActionUnchanged},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			existing := DNSRecord{Name: "home.example.com", Type: "A", Content: testIP, TTL: AutoTTL}
+			if c.modified > 0 {
+				existing.ModifiedOn = time.Now().Add(-c.modified)
+			}
+			f.addRecord("zone-example.com", existing)
+			u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", RefreshAfter: 24 * time.Hour, Records: []Record{{Name: "home.example.com"}}}, f)
+
+			rr := run(t, u).Records[0]
+			if rr.Action != c.action {
+				t.Fatalf("action %q, want %q", rr.Action, c.action)
+			}
+			puts := f.requestsFor("PUT")
+			if c.action == ActionUnchanged {
+				if len(puts) != 0 {
+					t.Errorf("sent %d updates, want none", len(puts))
+				}
+				return
+			}
+			if len(rr.Changes) != 1 || !strings.HasPrefix(rr.Changes[0], "refresh, last modified 48h") {
+				t.Errorf("changes %q, want a refresh", rr.Changes)
+			}
+			if len(puts) != 1 || decodeBody(t, puts[0])["content"] != testIP {
+				t.Errorf("updates = %+v, want the same content rewritten", puts)
+			}
+		})
+	}
+}
+
+func TestRunUnreadableProxiedContent(t *testing.T) {
+	f := newFakeCloudflare(t, "example.com")
+	f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Proxied: true, TTL: AutoTTL})
+	// blank drops the content Cloudflare hides from a token unable to read
+	// proxied records.
+	blank := func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for i := range f.records["zone-example.com"] {
+			f.records["zone-example.com"][i].Content = ""
+		}
+	}
+	writes := func() int { return len(f.requestsFor("PATCH")) + len(f.requestsFor("PUT")) }
+	cfg := Config{
+		ZoneName: "example.com",
+		APIToken: "token",
+		Records:  []Record{{Name: "home.example.com", Proxied: true}},
+	}
+
+	// Without a state file every run has to force the update.
+	u := newTestUpdater(t, cfg, f)
+	for i := range 2 {
+		if result := run(t, u); result.Records[0].Action != ActionUpdated {
+			t.Errorf("run %d without state: action %q, want %q", i+1, result.Records[0].Action, ActionUpdated)
+		}
+		blank()
+	}
+	if got := writes(); got != 2 {
+		t.Fatalf("%d writes without state, want 2", got)
+	}
+
+	// With one, the forced update is written once and later runs compare
+	// against it.
+	cfg.StateFile = filepath.Join(t.TempDir(), "state.json")
+	u = newTestUpdater(t, cfg, f)
+	wantAction := []Action{ActionUpdated, ActionUnchanged, ActionUnchanged}
+	for i, want := range wantAction {
+		if result := run(t, u); result.Records[0].Action != want {
+			t.Errorf("run %d with state: action %q, want %q", i+1, result.Records[0].Action, want)
+		}
+		blank()
+	}
+	if got := writes(); got != 3 {
+		t.Errorf("%d writes in total, want 3", got)
+	}
+}
+
+func TestRunConcurrentUpdateRereads(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		// conflicts is how many updates are refused with 412.
+		conflicts int
+		wantPuts  int
+		action    Action
+	}{
+		{"conflict once", 1, 2, ActionUpdated},
+		{"conflict every time", 5, 2, ActionFailed},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			f.addRecord("zone-example.com", DNSRecord{Name: "home.example.com", Type: "A", Content: "192.0.2.1", TTL: AutoTTL})
+			var version, refused atomic.Int32
+			version.Store(1)
+			f.handle = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+				etag := fmt.Sprintf(`"v%d"`, version.Load())
+				switch {
+				case r.Method == "GET" && r.URL.Query().Get("name") != "":
+					w.Header().Set("ETag", etag)
+				case r.Method == "PUT" && (r.Header.Get("If-Match") != etag || refused.Load() < int32(c.conflicts)):
+					// Another client changed the record since it was read.
+					refused.Add(1)
+					version.Add(1)
+					f.mu.Lock()
+					f.records["zone-example.com"][0].Content = "192.0.2.50"
+					f.mu.Unlock()
+					writeError(w, http.StatusPreconditionFailed, 1000, "Precondition failed")
+					return true
+				}
+				return false
+			}
+			u := newTestUpdater(t, Config{
+				ZoneName: "example.com",
+				APIToken: "token",
+				Content:  "198.51.100.1",
+				Records:  []Record{{Name: "home.example.com"}},
+			}, f)
+
+			result, _ := u.Run(context.Background())
+			rr := result.Records[0]
+			if rr.Action != c.action {
+				t.Fatalf("action %q (%v), want %q", rr.Action, rr.Err, c.action)
+			}
+			puts := f.requestsFor("PUT")
+			if len(puts) != c.wantPuts {
+				t.Fatalf("%d updates, want %d", len(puts), c.wantPuts)
+			}
+			for i, put := range puts {
+				if want := fmt.Sprintf(`"v%d"`, i+1); put.Header.Get("If-Match") != want {
+					t.Errorf("update %d sent If-Match %q, want the ETag of the read before it, %s", i+1, put.Header.Get("If-Match"), want)
+				}
+			}
+			if c.action == ActionFailed {
+				if !isConcurrentUpdate(rr.Err) || !strings.Contains(rr.Err.Error(), "modified since it was read") {
+					t.Errorf("error %v, want the concurrent update", rr.Err)
+				}
+				return
+			}
+			if rr.Previous != "192.0.2.50" {
+				t.Errorf("previous %q, want the content read again, 192.0.2.50", rr.Previous)
+			}
+			if got := f.recordsOf("zone-example.com")[0].Content; got != "198.51.100.1" {
+				t.Errorf("content %q, want 198.51.100.1", got)
+			}
+		})
+	}
+}
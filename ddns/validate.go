@@ -0,0 +1,83 @@
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// Validate checks what a run needs short of changing anything: the API
+// token of every credential in use unless skipToken is set, every zone and
+// every record, which must exist unless OnMissing creates it. Records
+// selected by comment or tag are only checked for a match. Unlike Lookup
+// it keeps going after a problem and returns all of them.
+func (u *Updater) Validate(ctx context.Context, skipToken bool) []error {
+	var problems []error
+
+	var zones []zoneKey
+	for _, record := range u.cfg.Records {
+		key := zoneKey{record.Credential, record.Zone}
+		if u.cfg.providerFor(record) == nil && u.cfg.Stack.manages(record.Type) && !slices.Contains(zones, key) {
+			zones = append(zones, key)
+		}
+	}
+
+	if !skipToken {
+		var verified []string
+		for _, key := range zones {
+			if slices.Contains(verified, key.credential) {
+				continue
+			}
+			verified = append(verified, key.credential)
+			if err := u.cf[key.credential].verifyToken(ctx); err != nil {
+				if key.credential != "" {
+					err = fmt.Errorf("credential %s: %w", key.credential, err)
+				}
+				problems = append(problems, err)
+			}
+		}
+	}
+
+	zoneIDs := make(map[zoneKey]string)
+	zoneErrs := make(map[zoneKey]error)
+	for _, key := range zones {
+		id, err := u.cf[key.credential].getZoneID(ctx, key.zone)
+		if err != nil {
+			zoneErrs[key] = err
+			problems = append(problems, fmt.Errorf("zone %s: %w", key.zone, err))
+			continue
+		}
+		zoneIDs[key] = id
+	}
+
+	for _, record := range u.cfg.Records {
+		key := zoneKey{record.Credential, record.Zone}
+		if zoneErrs[key] != nil || !u.cfg.Stack.manages(record.Type) {
+			continue
+		}
+		if u.cfg.SelectBy != SelectByName {
+			if _, rr, ok := u.selectRecords(ctx, zoneIDs[key], nil, record); !ok {
+				problems = append(problems, fmt.Errorf("record %s: %s", u.selector(record), rrProblem(rr)))
+			}
+			continue
+		}
+		state, err := u.lookupRecord(ctx, zoneIDs, record)
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Errorf("record %s %s: %w", record.Name, record.Type, err))
+		case !state.Exists && u.cfg.OnMissing != MissingCreate:
+			problems = append(problems, fmt.Errorf("record %s %s does not exist and would not be created", record.Name, record.Type))
+		case !state.Exists:
+			u.log.Info("Record does not exist and would be created", "record", record.Name, "type", record.Type)
+		}
+	}
+	return problems
+}
+
+// rrProblem describes why a record result holds no usable record.
+func rrProblem(rr RecordResult) string {
+	if rr.Err != nil {
+		return rr.Err.Error()
+	}
+	return rr.Reason
+}
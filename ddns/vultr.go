@@ -0,0 +1,133 @@
+package ddns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	vultrBaseURL = "https://api.vultr.com/v2"
+
+	// VultrMinTTL is the lowest TTL Vultr DNS accepts. Lower TTLs are raised
+	// to it; AutoTTL leaves the TTL to Vultr's default.
+	VultrMinTTL = 60
+)
+
+type vultrProvider struct {
+	api restClient
+}
+
+type vultrRecord struct {
+	ID   string `json:"id,omitempty"`
+	Type string `json:"type,omitempty"`
+	Name string `json:"name"`
+	Data string `json:"data"`
+	TTL  int    `json:"ttl,omitempty"`
+}
+
+type vultrRecordList struct {
+	Records []vultrRecord `json:"records"`
+	Meta    struct {
+		Links struct {
+			Next string `json:"next"`
+		} `json:"links"`
+	} `json:"meta"`
+}
+
+// NewVultrProvider manages records on Vultr DNS with an API key.
+func NewVultrProvider(apiKey string) (Provider, error) {
+	if apiKey == "" {
+		return nil, errors.New("Vultr API key is required")
+	}
+	return &vultrProvider{api: restClient{
+		client:  &http.Client{Timeout: DefaultHTTPTimeout},
+		baseURL: vultrBaseURL,
+		auth:    "Bearer " + apiKey,
+	}}, nil
+}
+
+func (p *vultrProvider) Name() string {
+	return "vultr"
+}
+
+// records returns the domain's records of record's name and type. Vultr
+// names records relative to the domain, "" being the apex, and pages the
+// list with a cursor.
+func (p *vultrProvider) records(ctx context.Context, record Record) ([]vultrRecord, error) {
+	name, err := relativeName(record.Name, record.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []vultrRecord
+	cursor := ""
+	for {
+		path := fmt.Sprintf("/domains/%s/records?per_page=500", record.Zone)
+		if cursor != "" {
+			path += "&cursor=" + url.QueryEscape(cursor)
+		}
+		var list vultrRecordList
+		err := p.api.do(ctx, "GET", path, nil, &list)
+		if errors.Is(err, errNotFound) {
+			return nil, fmt.Errorf("domain %s not found", record.Zone)
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range list.Records {
+			if r.Type == record.Type && sameName(r.Name, name) {
+				matches = append(matches, r)
+			}
+		}
+		if cursor = list.Meta.Links.Next; cursor == "" {
+			return matches, nil
+		}
+	}
+}
+
+func (p *vultrProvider) Get(ctx context.Context, record Record) (*RRset, error) {
+	records, err := p.records(ctx, record)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	rrset := &RRset{TTL: records[0].TTL}
+	for _, r := range records {
+		rrset.Values = append(rrset.Values, r.Data)
+	}
+	return rrset, nil
+}
+
+// Set creates the record, or updates the first existing one with PATCH and
+// deletes any others, since Vultr keeps one record per value.
+func (p *vultrProvider) Set(ctx context.Context, record Record, content string) error {
+	records, err := p.records(ctx, record)
+	if err != nil {
+		return err
+	}
+	name, err := relativeName(record.Name, record.Zone)
+	if err != nil {
+		return err
+	}
+	payload := vultrRecord{Name: name, Data: content}
+	if record.TTL != AutoTTL {
+		payload.TTL = max(record.TTL, VultrMinTTL)
+	}
+
+	base := fmt.Sprintf("/domains/%s/records", record.Zone)
+	if len(records) == 0 {
+		payload.Type = record.Type
+		return p.api.do(ctx, "POST", base, payload, nil)
+	}
+	if err := p.api.do(ctx, "PATCH", base+"/"+records[0].ID, payload, nil); err != nil {
+		return err
+	}
+	for _, r := range records[1:] {
+		if err := p.api.do(ctx, "DELETE", base+"/"+r.ID, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
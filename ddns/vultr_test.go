@@ -0,0 +1,98 @@
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// serveVultr answers the records listing of example.com with records,
+// split over two cursor pages, and accepts every write.
+func serveVultr(records []vultrRecord) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if r.URL.Path != "/domains/example.com/records" {
+			http.NotFound(w, r)
+			return
+		}
+		half := len(records) / 2
+		var list vultrRecordList
+		if r.URL.Query().Get("cursor") == "" {
+			list.Records = records[:half]
+			list.Meta.Links.Next = "page2"
+		} else {
+			list.Records = records[half:]
+		}
+		writeJSON(w, list)
+	}
+}
+
+func newTestVultr(t *testing.T, records []vultrRecord) (*vultrProvider, *providerServer) {
+	t.Helper()
+	srv := newProviderServer(t, serveVultr(records))
+	p, err := NewVultrProvider("key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.(*vultrProvider).api.baseURL = srv.URL
+	return p.(*vultrProvider), srv
+}
+
+func TestVultrSetCreates(t *testing.T) {
+	p, srv := newTestVultr(t, []vultrRecord{
+		{ID: "1", Type: "A", Name: "www", Data: "192.0.2.1"},
+		{ID: "2", Type: "AAAA", Name: "home", Data: "2001:db8::1"},
+	})
+	record := Record{Name: "home.example.com", Zone: "example.com", Type: "A", TTL: 30}
+	if rrset, err := p.Get(context.Background(), record); err != nil || rrset != nil {
+		t.Fatalf("Get = %+v, %v; want nil for a name with only another type", rrset, err)
+	}
+	if err := p.Set(context.Background(), record, "198.51.100.7"); err != nil {
+		t.Fatal(err)
+	}
+	writes := srv.writes()
+	if len(writes) != 1 || writes[0].Method != "POST" || writes[0].Path != "/domains/example.com/records" {
+		t.Fatalf("writes = %+v, want one POST", writes)
+	}
+	var got vultrRecord
+	json.Unmarshal(writes[0].Body, &got)
+	if want := (vultrRecord{Type: "A", Name: "home", Data: "198.51.100.7", TTL: VultrMinTTL}); got != want {
+		t.Errorf("created %+v, want %+v", got, want)
+	}
+}
+
+func TestVultrSetUpdates(t *testing.T) {
+	p, srv := newTestVultr(t, []vultrRecord{
+		{ID: "1", Type: "A", Name: "www", Data: "192.0.2.1"},
+		{ID: "2", Type: "A", Name: "home", Data: "192.0.2.2", TTL: 300},
+		{ID: "3", Type: "A", Name: "HOME", Data: "192.0.2.3", TTL: 300},
+	})
+	record := Record{Name: "home.example.com", Zone: "example.com", Type: "A", TTL: AutoTTL}
+	rrset, err := p.Get(context.Background(), record)
+	if err != nil || rrset == nil || len(rrset.Values) != 2 || rrset.TTL != 300 {
+		t.Fatalf("Get = %+v, %v; want both home records across the pages", rrset, err)
+	}
+	if err := p.Set(context.Background(), record, "198.51.100.7"); err != nil {
+		t.Fatal(err)
+	}
+	writes := srv.writes()
+	if len(writes) != 2 || writes[0].Method != "PATCH" || writes[0].Path != "/domains/example.com/records/2" || writes[1].Method != "DELETE" || writes[1].Path != "/domains/example.com/records/3" {
+		t.Fatalf("writes = %+v, want a PATCH of the first record and a DELETE of the other", writes)
+	}
+	var got map[string]any
+	json.Unmarshal(writes[0].Body, &got)
+	if got["data"] != "198.51.100.7" || got["name"] != "home" || got["ttl"] != nil || got["type"] != nil {
+		t.Errorf("patched with %s", writes[0].Body)
+	}
+}
+
+func TestVultrMissingDomain(t *testing.T) {
+	p, _ := newTestVultr(t, nil)
+	if _, err := p.Get(context.Background(), Record{Name: "home.example.org", Zone: "example.org", Type: "A"}); err == nil {
+		t.Error("Get on an unknown domain succeeded, want an error")
+	}
+}
@@ -0,0 +1,95 @@
+package ddns
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a daily span of wall-clock time, such as 02:00-04:00. A
+// window whose end is before its start wraps past midnight.
+type TimeWindow struct {
+	// Start and End are offsets from midnight.
+	Start, End time.Duration
+	// Location defaults to the local time zone.
+	Location *time.Location
+}
+
+// ParseTimeWindow parses "HH:MM-HH:MM", optionally followed by a space and
+// an IANA time zone name, e.g. "02:00-04:00 Europe/Madrid".
+func ParseTimeWindow(s string) (*TimeWindow, error) {
+	span, zone, _ := strings.Cut(strings.TrimSpace(s), " ")
+	startStr, endStr, ok := strings.Cut(span, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid time window %q: expected HH:MM-HH:MM", s)
+	}
+
+	w := &TimeWindow{Location: time.Local}
+	var err error
+	if w.Start, err = parseClock(startStr); err != nil {
+		return nil, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	if w.End, err = parseClock(endStr); err != nil {
+		return nil, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	if w.Start == w.End {
+		return nil, fmt.Errorf("invalid time window %q: start and end are equal", s)
+	}
+	if zone = strings.TrimSpace(zone); zone != "" {
+		if w.Location, err = time.LoadLocation(zone); err != nil {
+			return nil, fmt.Errorf("invalid time window %q: %w", s, err)
+		}
+	}
+	return w, nil
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a HH:MM time", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w *TimeWindow) String() string {
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%s-%s %s", clock(w.Start), clock(w.End), w.location())
+}
+
+func (w *TimeWindow) location() *time.Location {
+	if w.Location == nil {
+		return time.Local
+	}
+	return w.Location
+}
+
+// Contains reports whether t falls inside the window.
+func (w *TimeWindow) Contains(t time.Time) bool {
+	t = t.In(w.location())
+	offset := t.Sub(midnight(t))
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// NextOpen returns t when it is inside the window, otherwise the next time
+// the window opens.
+func (w *TimeWindow) NextOpen(t time.Time) time.Time {
+	if w.Contains(t) {
+		return t
+	}
+	t = t.In(w.location())
+	open := midnight(t).Add(w.Start)
+	if !open.After(t) {
+		day := midnight(t)
+		open = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location()).Add(w.Start)
+	}
+	return open
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,101 @@
+package ddns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeWindowContains(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2026, 3, 10, hour, min, 0, 0, time.UTC) }
+	for _, c := range []struct {
+		window string
+		t      time.Time
+		want   bool
+	}{
+		{"02:00-04:00 UTC", at(1, 59), false},
+		{"02:00-04:00 UTC", at(2, 0), true},
+		{"02:00-04:00 UTC", at(3, 30), true},
+		{"02:00-04:00 UTC", at(4, 0), false},
+		{"23:00-01:30 UTC", at(22, 59), false},
+		{"23:00-01:30 UTC", at(23, 0), true},
+		{"23:00-01:30 UTC", at(0, 15), true},
+		{"23:00-01:30 UTC", at(1, 30), false},
+		{"23:00-01:30 UTC", at(12, 0), false},
+		// 01:00 UTC is 02:00 in Madrid in winter.
+		{"02:00-04:00 Europe/Madrid", at(1, 0), true},
+		{"02:00-04:00 Europe/Madrid", at(3, 0), false},
+	} {
+		w, err := ParseTimeWindow(c.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Contains(c.t); got != c.want {
+			t.Errorf("%s contains %s = %t, want %t", c.window, c.t.Format("15:04 MST"), got, c.want)
+		}
+	}
+}
+
+func TestTimeWindowNextOpen(t *testing.T) {
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 3, day, hour, min, 0, 0, time.UTC) }
+	for _, c := range []struct {
+		window string
+		t      time.Time
+		want   time.Time
+	}{
+		{"02:00-04:00 UTC", at(10, 3, 0), at(10, 3, 0)},
+		{"02:00-04:00 UTC", at(10, 1, 0), at(10, 2, 0)},
+		{"02:00-04:00 UTC", at(10, 5, 0), at(11, 2, 0)},
+		{"23:00-01:30 UTC", at(10, 0, 30), at(10, 0, 30)},
+		{"23:00-01:30 UTC", at(10, 12, 0), at(10, 23, 0)},
+	} {
+		w, err := ParseTimeWindow(c.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.NextOpen(c.t); !got.Equal(c.want) {
+			t.Errorf("%s next open after %s = %s, want %s", c.window, c.t, got, c.want)
+		}
+	}
+}
+
+func TestParseTimeWindowErrors(t *testing.T) {
+	for _, s := range []string{"", "02:00", "02:00-02:00", "2am-4am", "02:00-25:00", "02:00-04:00 Nowhere/City"} {
+		if _, err := ParseTimeWindow(s); err == nil {
+			t.Errorf("ParseTimeWindow(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestRunUpdateWindow(t *testing.T) {
+	now := time.Now().UTC()
+	offset := now.Sub(midnight(now))
+	around := func(from, to time.Duration) *TimeWindow {
+		day := 24 * time.Hour
+		return &TimeWindow{Start: (offset + from + day) % day, End: (offset + to + day) % day, Location: time.UTC}
+	}
+	for _, c := range []struct {
+		name   string
+		window *TimeWindow
+		action Action
+	}{
+		{"in window", around(-time.Hour, time.Hour), ActionCreated},
+		{"out of window", around(time.Hour, 2*time.Hour), ActionSkipped},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := newFakeCloudflare(t, "example.com")
+			u := newTestUpdater(t, Config{ZoneName: "example.com", APIToken: "token", UpdateWindow: c.window, Records: []Record{{Name: "home.example.com"}}}, f)
+			result := run(t, u)
+			if got := result.Records[0].Action; got != c.action {
+				t.Fatalf("action %q, want %q", got, c.action)
+			}
+			posts := len(f.requestsFor("POST"))
+			if c.action == ActionSkipped {
+				if posts != 0 || result.Records[0].Reason != reasonOutsideWindow || result.DeferredUntil.IsZero() {
+					t.Errorf("sent %d creates, reason %q, deferred until %s; want the change held back", posts, result.Records[0].Reason, result.DeferredUntil)
+				}
+			} else if posts != 1 {
+				t.Errorf("sent %d creates, want 1", posts)
+			}
+		})
+	}
+}
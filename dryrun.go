@@ -0,0 +1,38 @@
+package main
+
+import "fmt"
+
+// printDryRun renders the changes of a dry run. In text form changed fields
+// are marked with "~"; records that would be created show only their
+// desired values.
+func printDryRun(report cycleReport, output string) {
+	if output != "text" {
+		printReport(report, output)
+		return
+	}
+
+	for _, r := range report.Records {
+		switch r.Action {
+		case "created":
+			fmt.Printf("+ %s %s (would be created)\n", r.Name, r.Type)
+			for _, d := range r.Diff {
+				fmt.Printf("    %-10s %s\n", d.Field, d.Desired)
+			}
+		case "updated":
+			fmt.Printf("~ %s %s (would be updated)\n", r.Name, r.Type)
+			for _, d := range r.Diff {
+				if d.Changed {
+					fmt.Printf("  ~ %-10s %s -> %s\n", d.Field, d.Current, d.Desired)
+				} else {
+					fmt.Printf("    %-10s %s\n", d.Field, d.Current)
+				}
+			}
+		case "skipped":
+			fmt.Printf("! %s %s (skipped: %s)\n", r.Name, r.Type, r.Reason)
+		case "failed":
+			fmt.Printf("! %s %s (failed: %s)\n", r.Name, r.Type, r.Error)
+		default:
+			fmt.Printf("= %s %s (unchanged)\n", r.Name, r.Type)
+		}
+	}
+}
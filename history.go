@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+	"github.com/casantosmu/ddns-updater/internal/atomicfile"
+)
+
+const defaultHistoryMax = 1000
+
+// historyEntry is one line of HISTORY_FILE.
+type historyEntry struct {
+	Time   time.Time `json:"time"`
+	Record string    `json:"record"`
+	Old    string    `json:"old"`
+	New    string    `json:"new"`
+}
+
+// historyEntries returns the content changes made by a run. Created records
+// have an empty Old value.
+func historyEntries(result *ddns.Result, now time.Time) []historyEntry {
+	if result == nil {
+		return nil
+	}
+	var entries []historyEntry
+	for _, r := range result.Records {
+		if r.Action != ddns.ActionCreated && r.Action != ddns.ActionUpdated {
+			continue
+		}
+		if r.Previous == r.Content {
+			continue
+		}
+		entries = append(entries, historyEntry{Time: now, Record: r.Name, Old: r.Previous, New: r.Content})
+	}
+	return entries
+}
+
+// appendHistory adds entries to the JSON lines file at path, dropping the
+// oldest so that at most max remain.
+func appendHistory(path string, entries []historyEntry, max int) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	lines, err := readHistoryLines(path)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > max {
+		lines = lines[len(lines)-max:]
+	}
+
+	var b bytes.Buffer
+	for _, line := range lines {
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	return atomicfile.Write(path, b.Bytes(), 0o644)
+}
+
+// readHistory returns up to the last n entries of the file at path. Lines
+// that do not decode are skipped.
+func readHistory(path string, n int) ([]historyEntry, error) {
+	lines, err := readHistoryLines(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	entries := []historyEntry{}
+	for _, line := range lines {
+		var e historyEntry
+		if json.Unmarshal(line, &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func readHistoryLines(path string) ([][]byte, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			lines = append(lines, bytes.Clone(line))
+		}
+	}
+	return lines, scanner.Err()
+}
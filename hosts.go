@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+	"github.com/casantosmu/ddns-updater/internal/atomicfile"
+)
+
+// updateHostsFile points each A and AAAA record of result that is in sync
+// with its address at that address in the hosts-style file at path, so a
+// local override follows the public record. Other lines are kept as they
+// are, and the file is only rewritten when an entry changes.
+func updateHostsFile(path string, result *ddns.Result) error {
+	if result == nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	for _, r := range result.Records {
+		switch r.Action {
+		case ddns.ActionCreated, ddns.ActionUpdated, ddns.ActionUnchanged:
+		default:
+			continue
+		}
+		if r.Type != "A" && r.Type != "AAAA" || net.ParseIP(r.Content) == nil {
+			continue
+		}
+		lines = setHostsEntry(lines, strings.ToLower(r.Name), r.Content)
+	}
+
+	var b bytes.Buffer
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if bytes.Equal(b.Bytes(), data) {
+		return nil
+	}
+	return atomicfile.Write(path, b.Bytes(), 0o644)
+}
+
+// setHostsEntry makes name resolve to ip in lines. A line already mapping
+// ip to name is kept; name is removed from the other lines of ip's family,
+// dropping lines left without names, and a line is added when none mapped
+// it to ip.
+func setHostsEntry(lines []string, name, ip string) []string {
+	isV4 := net.ParseIP(ip).To4() != nil
+	found := false
+	var out []string
+	for _, line := range lines {
+		entry, comment, _ := strings.Cut(line, "#")
+		fields := strings.Fields(entry)
+		if len(fields) < 2 || !slices.Contains(fields[1:], name) {
+			out = append(out, line)
+			continue
+		}
+		addr := net.ParseIP(fields[0])
+		if addr == nil || (addr.To4() != nil) != isV4 {
+			out = append(out, line)
+			continue
+		}
+		if addr.Equal(net.ParseIP(ip)) && !found {
+			found = true
+			out = append(out, line)
+			continue
+		}
+
+		hosts := slices.DeleteFunc(fields[1:], func(h string) bool { return h == name })
+		if len(hosts) == 0 {
+			continue
+		}
+		rewritten := fields[0] + "\t" + strings.Join(hosts, " ")
+		if comment != "" {
+			rewritten += " #" + comment
+		}
+		out = append(out, rewritten)
+	}
+	if !found {
+		out = append(out, ip+"\t"+name)
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+func TestUpdateHostsFileIdempotent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	initial := strings.Join([]string{
+		"# local overrides",
+		"127.0.0.1\tlocalhost",
+		"192.0.2.1\thome.example.com nas.example.com # old address",
+		"192.0.2.9\tvpn.example.com",
+		"::1\tlocalhost home.example.com",
+		"",
+	}, "\n")
+	if err := os.WriteFile(path, []byte(initial), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	result := &ddns.Result{Records: []ddns.RecordResult{
+		{Name: "Home.Example.com", Type: "A", Action: ddns.ActionUpdated, Content: "198.51.100.1"},
+		{Name: "vpn.example.com", Type: "A", Action: ddns.ActionUnchanged, Content: "192.0.2.9"},
+		{Name: "new.example.com", Type: "AAAA", Action: ddns.ActionCreated, Content: "2001:db8::1"},
+		{Name: "failed.example.com", Type: "A", Action: ddns.ActionFailed, Content: "198.51.100.2"},
+		{Name: "txt.example.com", Type: "TXT", Action: ddns.ActionUpdated, Content: "hello"},
+	}}
+
+	if err := updateHostsFile(path, result); err != nil {
+		t.Fatal(err)
+	}
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"# local overrides",
+		"127.0.0.1\tlocalhost",
+		"192.0.2.1\tnas.example.com # old address",
+		"192.0.2.9\tvpn.example.com",
+		"::1\tlocalhost home.example.com",
+		"198.51.100.1\thome.example.com",
+		"2001:db8::1\tnew.example.com",
+		"",
+	}, "\n")
+	if string(first) != want {
+		t.Fatalf("hosts file after the first run:\n%s\nwant:\n%s", first, want)
+	}
+
+	before, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := updateHostsFile(path, result); err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(second) != string(first) {
+		t.Errorf("hosts file changed on the second run:\n%s\nwant:\n%s", second, first)
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("second run rewrote the hosts file, want it left alone")
+	}
+}
+
+func TestUpdateHostsFileMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	result := &ddns.Result{Records: []ddns.RecordResult{
+		{Name: "home.example.com", Type: "A", Action: ddns.ActionUpdated, Content: "198.51.100.1"},
+	}}
+	if err := updateHostsFile(path, result); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "198.51.100.1\thome.example.com\n"; string(data) != want {
+		t.Errorf("hosts file %q, want %q", data, want)
+	}
+}
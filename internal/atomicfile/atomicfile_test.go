@@ -0,0 +1,71 @@
+package atomicfile
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ip")
+	if err := Write(path, []byte("198.51.100.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A reader that opened the old file keeps seeing all of it.
+	old, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer old.Close()
+	if err := Write(path, []byte("2001:db8::1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(old); string(data) != "198.51.100.1\n" {
+		t.Errorf("old reader saw %q, want the previous content whole", data)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "2001:db8::1\n" {
+		t.Errorf("content = %q, %v; want the new address", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode %v, want 0600", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want only the file and no temporary ones", len(entries))
+	}
+}
+
+func TestWriteMissingDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "ip")
+	if err := Write(path, []byte("198.51.100.1\n"), 0o644); err == nil {
+		t.Error("Write into a missing directory succeeded, want an error")
+	}
+}
+
+func TestWriteFailureRemovesTemporary(t *testing.T) {
+	dir := t.TempDir()
+	// Renaming over a directory fails after the temporary file is written.
+	target := filepath.Join(dir, "sub")
+	if err := os.Mkdir(target, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "keep"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(target, []byte("x"), 0o644); err == nil {
+		t.Fatal("Write over a non-empty directory succeeded, want an error")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want the temporary file removed", len(entries))
+	}
+}
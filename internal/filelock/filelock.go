@@ -0,0 +1,8 @@
+// Package filelock takes exclusive advisory locks on files, so overlapping
+// invocations can detect each other.
+package filelock
+
+import "errors"
+
+// ErrLocked is returned by Lock when another process holds the lock.
+var ErrLocked = errors.New("lock is held by another process")
@@ -0,0 +1,13 @@
+//go:build !unix
+
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+// Lock is not supported on this platform.
+func Lock(path string) (*os.File, error) {
+	return nil, errors.New("lock files are not supported on this platform")
+}
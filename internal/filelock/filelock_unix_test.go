@@ -0,0 +1,42 @@
+//go:build unix
+
+package filelock
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockHeld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ddns.lock")
+	first, err := Lock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// flock locks belong to the open file, so a second open in the same
+	// process conflicts just as another process would.
+	if second, err := Lock(path); !errors.Is(err, ErrLocked) {
+		if second != nil {
+			second.Close()
+		}
+		t.Fatalf("second Lock = %v, want ErrLocked while the first is held", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Lock after release = %v, want success", err)
+	}
+	again.Close()
+}
+
+func TestLockMissingDirectory(t *testing.T) {
+	_, err := Lock(filepath.Join(t.TempDir(), "missing", "ddns.lock"))
+	if err == nil || errors.Is(err, ErrLocked) {
+		t.Errorf("Lock in a missing directory = %v, want an open error", err)
+	}
+}
@@ -0,0 +1,401 @@
+// Package toml decodes the subset of TOML that config files use: tables,
+// arrays of tables, dotted and quoted keys, basic and literal strings,
+// integers, floats, booleans, arrays and inline tables. Multi-line strings
+// and date-times are rejected.
+package toml
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Unmarshal decodes data into nested maps and slices, with tables as
+// map[string]any, arrays as []any, integers as int64 and floats as
+// float64, ready to be re-encoded as JSON.
+func Unmarshal(data []byte) (map[string]any, error) {
+	p := &parser{src: string(data)}
+	root := make(map[string]any)
+	current := root
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return root, nil
+		}
+		var err error
+		if p.peek() == '[' {
+			current, err = p.parseHeader(root)
+		} else {
+			err = p.parsePair(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line := 1 + strings.Count(p.src[:min(p.pos, len(p.src))], "\n")
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipBlank skips spaces, tabs and comments, and newlines too when
+// newlines is set.
+func (p *parser) skipBlank(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t':
+			p.pos++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case newlines && (c == '\n' || c == '\r'):
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) endOfLine() error {
+	p.skipBlank(false)
+	if p.eof() {
+		return nil
+	}
+	if strings.HasPrefix(p.src[p.pos:], "\r\n") || p.peek() == '\n' {
+		return nil
+	}
+	return p.errorf("unexpected %q after value", p.peek())
+}
+
+// parseHeader parses a [table] or [[array]] header and returns the table
+// that the following pairs belong to.
+func (p *parser) parseHeader(root map[string]any) (map[string]any, error) {
+	array := strings.HasPrefix(p.src[p.pos:], "[[")
+	if array {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	p.skipBlank(false)
+	path, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return nil, p.errorf("expected %q to close the table header", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.descend(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	if !array {
+		return p.descend(parent, []string{last})
+	}
+	var tables []any
+	if v, ok := parent[last]; ok {
+		if tables, ok = v.([]any); !ok {
+			return nil, p.errorf("key %s is already defined", last)
+		}
+	}
+	table := make(map[string]any)
+	parent[last] = append(tables, table)
+	return table, nil
+}
+
+// descend walks path from table, creating missing tables and entering the
+// last element of arrays of tables.
+func (p *parser) descend(table map[string]any, path []string) (map[string]any, error) {
+	for _, key := range path {
+		switch v := table[key].(type) {
+		case nil:
+			next := make(map[string]any)
+			table[key] = next
+			table = next
+		case map[string]any:
+			table = v
+		case []any:
+			if len(v) == 0 {
+				return nil, p.errorf("key %s is not a table", key)
+			}
+			last, ok := v[len(v)-1].(map[string]any)
+			if !ok {
+				return nil, p.errorf("key %s is not a table", key)
+			}
+			table = last
+		default:
+			return nil, p.errorf("key %s is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+func (p *parser) parsePair(table map[string]any) error {
+	path, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.errorf("expected = after key %s", strings.Join(path, "."))
+	}
+	p.pos++
+	p.skipBlank(false)
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	parent, err := p.descend(table, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	last := path[len(path)-1]
+	if _, ok := parent[last]; ok {
+		return p.errorf("key %s is already defined", strings.Join(path, "."))
+	}
+	parent[last] = value
+	return nil
+}
+
+// parseKey parses a possibly dotted key and the blanks after it.
+func (p *parser) parseKey() ([]string, error) {
+	var path []string
+	for {
+		var part string
+		switch p.peek() {
+		case '"':
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		case '\'':
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected a key")
+			}
+			part = p.src[start:p.pos]
+		}
+		path = append(path, part)
+		p.skipBlank(false)
+		if p.peek() != '.' {
+			return path, nil
+		}
+		p.pos++
+		p.skipBlank(false)
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *parser) parseValue() (any, error) {
+	switch c := p.peek(); {
+	case c == '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.parseBasicString()
+	case c == '\'':
+		if strings.HasPrefix(p.src[p.pos:], "'''") {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.parseLiteralString()
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case strings.HasPrefix(p.src[p.pos:], "true"):
+		p.pos += len("true")
+		return true, nil
+	case strings.HasPrefix(p.src[p.pos:], "false"):
+		p.pos += len("false")
+		return false, nil
+	default:
+		return p.parseNumber()
+	}
+}
+
+func (p *parser) parseBasicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated string")
+			}
+			esc := p.src[p.pos]
+			p.pos++
+			switch esc {
+			case 'b':
+				b.WriteByte('\b')
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'f':
+				b.WriteByte('\f')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(esc)
+			case 'u', 'U':
+				n := 4
+				if esc == 'U' {
+					n = 8
+				}
+				if p.pos+n > len(p.src) {
+					return "", p.errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+				if err != nil {
+					return "", p.errorf("invalid unicode escape %q", p.src[p.pos:p.pos+n])
+				}
+				p.pos += n
+				b.WriteRune(rune(code))
+			default:
+				return "", p.errorf("invalid escape \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (p *parser) parseLiteralString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end < 0 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+func (p *parser) parseArray() ([]any, error) {
+	p.pos++
+	values := []any{}
+	for {
+		p.skipBlank(true)
+		if p.peek() == ']' {
+			p.pos++
+			return values, nil
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		p.skipBlank(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
+
+func (p *parser) parseInlineTable() (map[string]any, error) {
+	p.pos++
+	table := make(map[string]any)
+	p.skipBlank(false)
+	if p.peek() == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		p.skipBlank(false)
+		if err := p.parsePair(table); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("expected , or } in inline table")
+		}
+	}
+}
+
+func (p *parser) parseNumber() (any, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-_.:0123456789abcdefinoxABCDEFINTZ", p.peek()) >= 0 {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+	if token == "" {
+		return nil, p.errorf("expected a value")
+	}
+	if strings.Contains(token, ":") || len(token) >= 10 && token[4] == '-' && token[7] == '-' {
+		return nil, p.errorf("date-times are not supported")
+	}
+	digits := strings.ReplaceAll(token, "_", "")
+	if isLeadingZero(digits) {
+		return nil, p.errorf("invalid value %q: leading zeros are not allowed", token)
+	}
+	if n, err := strconv.ParseInt(digits, 0, 64); err == nil {
+		return n, nil
+	}
+	// JSON cannot carry inf or nan, so they are rejected too.
+	if f, err := strconv.ParseFloat(digits, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f, nil
+	}
+	return nil, p.errorf("invalid value %q", token)
+}
+
+// isLeadingZero reports a decimal number with a leading zero, which TOML
+// forbids and strconv would read as octal.
+func isLeadingZero(s string) bool {
+	s = strings.TrimLeft(s, "+-")
+	return len(s) > 1 && s[0] == '0' && s[1] >= '0' && s[1] <= '9'
+}
@@ -0,0 +1,117 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	data := `# a config file
+title = "ddns" # trailing comment
+literal = 'C:\path'
+escapes = "tab\tquote\" \u00e9"
+count = 1_000
+hex = 0xff
+ratio = -1.5e3
+enabled = true
+disabled = false
+list = [1, 2,
+  3, ] # trailing comma
+empty = []
+inline = { a = 1, "b c" = "d" }
+dotted.key = "x"
+"quoted.key" = "y"
+
+[server]
+addr = ":8080"
+
+[server.tls]
+cert = "cert.pem"
+
+[[records]]
+name = "home.example.com"
+proxied = true
+
+[[records]]
+name = "vpn.example.com"
+tags = ["a", "b"]
+[records.extra]
+ttl = 300
+`
+	want := map[string]any{
+		"title":      "ddns",
+		"literal":    `C:\path`,
+		"escapes":    "tab\tquote\" é",
+		"count":      int64(1000),
+		"hex":        int64(255),
+		"ratio":      -1500.0,
+		"enabled":    true,
+		"disabled":   false,
+		"list":       []any{int64(1), int64(2), int64(3)},
+		"empty":      []any{},
+		"inline":     map[string]any{"a": int64(1), "b c": "d"},
+		"dotted":     map[string]any{"key": "x"},
+		"quoted.key": "y",
+		"server": map[string]any{
+			"addr": ":8080",
+			"tls":  map[string]any{"cert": "cert.pem"},
+		},
+		"records": []any{
+			map[string]any{"name": "home.example.com", "proxied": true},
+			map[string]any{"name": "vpn.example.com", "tags": []any{"a", "b"}, "extra": map[string]any{"ttl": int64(300)}},
+		},
+	}
+	got, err := Unmarshal([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestUnmarshalCRLF(t *testing.T) {
+	got, err := Unmarshal([]byte("a = 1\r\n[t]\r\nb = 'x'\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"a": int64(1), "t": map[string]any{"b": "x"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal = %#v, want %#v", got, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, c := range []struct {
+		name, data, want string
+	}{
+		{"duplicate key", "a = 1\na = 2", "line 2: key a is already defined"},
+		{"table over value", "a = 1\n[a]", "key a is not a table"},
+		{"array of tables over table", "[a]\n[[a]]", "key a is already defined"},
+		{"missing equals", "a 1", "expected = after key a"},
+		{"missing value", "a =", "expected a value"},
+		{"unterminated string", `a = "abc`, "unterminated string"},
+		{"unterminated literal", "a = 'abc\nb = 1", "line 1: unterminated string"},
+		{"invalid escape", `a = "\q"`, `invalid escape \q`},
+		{"invalid unicode escape", `a = "\u12"`, "invalid unicode escape"},
+		{"multi-line string", `a = """x"""`, "multi-line strings are not supported"},
+		{"multi-line literal", "a = '''x'''", "multi-line strings are not supported"},
+		{"date-time", "a = 2026-01-02T15:04:05Z", "date-times are not supported"},
+		{"date", "a = 2026-01-02", "date-times are not supported"},
+		{"leading zero", "a = 012", "leading zeros are not allowed"},
+		{"inf", "a = inf", `invalid value "inf"`},
+		{"garbage after value", "a = 1 2", `unexpected '2' after value`},
+		{"unclosed header", "[a\nb = 1", `expected "]" to close the table header`},
+		{"unclosed array", "a = [1, 2", "expected , or ] in array"},
+		{"unclosed inline table", "a = { b = 1", "expected , or } in inline table"},
+		{"empty key", "= 1", "expected a key"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Unmarshal([]byte(c.data))
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("Unmarshal(%q) = %v, want an error containing %q", c.data, err, c.want)
+			}
+		})
+	}
+}
@@ -1,250 +1,275 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+	"github.com/casantosmu/ddns-updater/internal/filelock"
 )
 
-type Config struct {
-	ZoneName   string
-	RecordName string
-	APIToken   string
-}
+const usage = `Usage: ddns-updater [command] [flags]
 
-type CloudflareResponse[T any] struct {
-	Result  []T   `json:"result"`
-	Success bool  `json:"success"`
-	Errors  []any `json:"errors"`
-}
+Commands:
+  run       Update the configured records (default)
+  config    Print the effective configuration without contacting any API
+  prune     List, or with -confirm delete, matching records that are no longer configured
+  exists    Print the current content of the records; exit 2 if any is missing
+  set       Write the address given with -ip to the records, skipping detection
+  diagnose  Compare the public address seen by several sources; changes nothing
+  drift     Report the records that differ from the desired state; exit 2 if any does
 
-type Zone struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-}
+With ENV_PREFIX=NAME set, every variable is read as NAME_<VAR> first and
+falls back to the unprefixed <VAR>.
+`
+
+func main() {
+	envPrefix = strings.TrimSuffix(strings.ToUpper(os.Getenv("ENV_PREFIX")), "_")
+
+	args := os.Args[1:]
+	command := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
 
-type DNSRecord struct {
-	ID      string `json:"id"`
-	Name    string `json:"name"`
-	Content string `json:"content"`
-	Type    string `json:"type"`
+	switch command {
+	case "run":
+		runCommand(args)
+	case "config":
+		configCommand(args)
+	case "prune":
+		pruneCommand(args)
+	case "exists":
+		existsCommand(args)
+	case "set":
+		setCommand(args)
+	case "diagnose":
+		diagnoseCommand(args)
+	case "drift":
+		driftCommand(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		fatal(fmt.Errorf("unknown command %q", command))
+	}
 }
 
-type DNSRecordPayload struct {
-	Type    string `json:"type"`
-	Name    string `json:"name"`
-	Content string `json:"content"`
-	Proxied bool   `json:"proxied"`
+// exitLocked is the status of a command that found LOCK_FILE held by
+// another run, EX_TEMPFAIL from sysexits.h.
+const exitLocked = 75
+
+// exitTimeout is the status of a one-shot run that exceeded RUN_TIMEOUT,
+// matching timeout(1).
+const exitTimeout = 124
+
+// options holds the flags shared by every command.
+type options struct {
+	configFile string
 }
 
-var httpClient = &http.Client{
-	Timeout: 10 * time.Second,
+func newFlagSet(command string) (*flag.FlagSet, *options) {
+	opts := &options{}
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	fs.StringVar(&opts.configFile, "config", getenv("CONFIG_FILE"), "path to a JSON or TOML config file (overrides CONFIG_FILE)")
+	return fs, opts
 }
 
-const cloudflareBaseURL = "https://api.cloudflare.com/client/v4"
+// outputFlag registers -output with the given formats, the first of which
+// is the default.
+func outputFlag(fs *flag.FlagSet, formats ...string) *string {
+	return fs.String("output", formats[0], "output format: "+joinFormats(formats))
+}
 
-func getEnvVars() (*Config, error) {
-	cfg := &Config{
-		ZoneName:   os.Getenv("ZONE_NAME"),
-		RecordName: os.Getenv("RECORD_NAME"),
-		APIToken:   os.Getenv("API_TOKEN"),
+func checkOutput(output string, formats ...string) {
+	if !slices.Contains(formats, output) {
+		fatal(fmt.Errorf("invalid -output value %q: must be %s", output, joinFormats(formats)))
 	}
+}
 
-	var missingVars []string
-	if cfg.ZoneName == "" {
-		missingVars = append(missingVars, "ZONE_NAME")
+func joinFormats(formats []string) string {
+	if len(formats) == 1 {
+		return formats[0]
 	}
-	if cfg.RecordName == "" {
-		missingVars = append(missingVars, "RECORD_NAME")
+	return strings.Join(formats[:len(formats)-1], ", ") + " or " + formats[len(formats)-1]
+}
+
+// runFormats are the -output formats of the run command. json and env
+// print the outcome of a single cycle on stdout; logs stay on stderr.
+var runFormats = []string{"text", "json", "env"}
+
+// runCommand updates the records once, or every INTERVAL when it is set.
+// -once (or RUN_ONCE) takes precedence over INTERVAL and forces a single
+// cycle. -dry-run (or DRY_RUN) implies -once and prints what would change.
+func runCommand(args []string) {
+	fs, opts := newFlagSet("run")
+	var once, dryRun, quiet bool
+	if err := boolEnv("RUN_ONCE", &once); err != nil {
+		fatal(err)
 	}
-	if cfg.APIToken == "" {
-		missingVars = append(missingVars, "API_TOKEN")
+	if err := boolEnv("DRY_RUN", &dryRun); err != nil {
+		fatal(err)
 	}
-
-	if len(missingVars) > 0 {
-		return nil, fmt.Errorf("missing environment variables: %s", strings.Join(missingVars, ", "))
+	if err := boolEnv("QUIET", &quiet); err != nil {
+		fatal(err)
 	}
-
-	return cfg, nil
-}
-
-func getPublicIP() (string, error) {
-	resp, err := httpClient.Get("https://api.ipify.org")
+	fs.BoolVar(&once, "once", once, "run a single cycle and exit, even when INTERVAL is set (overrides RUN_ONCE)")
+	fs.BoolVar(&dryRun, "dry-run", dryRun, "print the changes a single cycle would make without making them (overrides DRY_RUN)")
+	fs.BoolVar(&quiet, "quiet", quiet, "only log record changes, warnings and errors (overrides QUIET)")
+	validate := fs.Bool("validate", false, "check the configuration, API token, zones and records, then exit without changing anything")
+	skipToken := fs.Bool("skip-token-check", false, "with -validate, do not verify the API tokens")
+	output := outputFlag(fs, runFormats...)
+	fs.Parse(args)
+	checkOutput(*output, runFormats...)
+
+	cfg, err := getEnvVars(opts.configFile)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch public IP: %w", err)
+		fatal(err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to fetch public IP (status %d): %s", resp.StatusCode, string(body))
+	cfg.DryRun = dryRun
+	cfg.Quiet = quiet
+	if quiet {
+		cfg.Logger = quietLogger()
 	}
-
-	body, err := io.ReadAll(resp.Body)
+	if *validate {
+		validateCommand(cfg, *skipToken)
+		return
+	}
+	acquireLock(cfg)
+	openAuditLog(cfg)
+	updater, err := ddns.New(cfg.Config)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		fatal(err)
 	}
+	d := &daemon{updater: updater, cfg: cfg}
+	mode := "loop"
+	switch {
+	case dryRun:
+		mode = "dry-run"
+	case once || cfg.Interval == 0:
+		mode = "once"
+	}
+	logStartup(updater.Config(), mode, cfg.Interval)
 
-	ip := strings.TrimSpace(string(body))
-
-	log.Printf("[INFO] Public IP address: %s", ip)
-	return ip, nil
-}
-
-func cfRequest(method, endpoint string, token string, bodyData interface{}) (*http.Response, error) {
-	var bodyReader io.Reader
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if bodyData != nil {
-		jsonData, err := json.Marshal(bodyData)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	if cfg.WaitForNetwork > 0 {
+		if err := updater.WaitForNetwork(ctx, cfg.WaitForNetwork); err != nil {
+			slog.Warn("Starting without network", "error", err)
 		}
-		bodyReader = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, cloudflareBaseURL+endpoint, bodyReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if cfg.Preflight {
+		if err := updater.Preflight(ctx); err != nil {
+			fatal(err)
+		}
 	}
 
-	req.Header.Set("Authorization", "Bearer "+token)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+	if cfg.ClockSkewMax > 0 {
+		checkClock(ctx, updater, cfg.ClockSkewMax)
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		respBody, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		return nil, fmt.Errorf("cloudflare API error (status %d): %s", resp.StatusCode, string(respBody))
+	if dryRun {
+		result, err := d.cycle(ctx)
+		printDryRun(newCycleReport(result, err, time.Now()), *output)
+		if err != nil {
+			fatal(err)
+		}
+		return
 	}
-
-	return resp, nil
+	if once || cfg.Interval == 0 {
+		result, err := d.cycle(ctx)
+		if *output != "text" {
+			printReport(newCycleReport(result, err, time.Now()), *output)
+		}
+		if err != nil {
+			if errors.Is(err, errRunTimeout) {
+				slog.Error(err.Error())
+				os.Exit(exitTimeout)
+			}
+			fatal(err)
+		}
+		return
+	}
+	d.loop(ctx)
 }
 
-func getZoneID(zoneName, token string) (string, error) {
-	resp, err := cfRequest("GET", "/zones?name="+zoneName, token, nil)
+// validateCommand runs Updater.Validate for -validate, logging every
+// problem found and exiting 1 when there is any.
+func validateCommand(cfg *cliConfig, skipToken bool) {
+	updater, err := ddns.New(cfg.Config)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch zone ID: %w", err)
+		fatal(err)
 	}
-	defer resp.Body.Close()
-
-	var cfResp CloudflareResponse[Zone]
-	if err := json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
-		return "", fmt.Errorf("failed to decode zone response: %w", err)
+	problems := updater.Validate(context.Background(), skipToken)
+	for _, err := range problems {
+		slog.Error("Validation failed", "error", err)
 	}
-
-	if len(cfResp.Result) == 0 {
-		return "", fmt.Errorf("zone not found")
+	if len(problems) > 0 {
+		fatal(fmt.Errorf("validation found %d problem(s)", len(problems)))
 	}
-
-	id := cfResp.Result[0].ID
-	log.Printf("[INFO] Zone ID: %s", id)
-	return id, nil
+	slog.Info("Configuration is valid", "records", len(updater.Config().Records))
 }
 
-func getRecordData(zoneID, recordName, token string) (*DNSRecord, error) {
-	endpoint := fmt.Sprintf("/zones/%s/dns_records?name=%s", zoneID, recordName)
-	resp, err := cfRequest("GET", endpoint, token, nil)
+// checkClock warns when the local clock is more than max away from the DNS
+// API's. It never fails the run.
+func checkClock(ctx context.Context, updater *ddns.Updater, max time.Duration) {
+	skew, err := updater.ClockSkew(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch record data: %w", err)
-	}
-	defer resp.Body.Close()
-
-	var cfResp CloudflareResponse[DNSRecord]
-	if err := json.NewDecoder(resp.Body).Decode(&cfResp); err != nil {
-		return nil, fmt.Errorf("failed to decode record response: %w", err)
+		slog.Warn("Could not check the clock", "error", err)
+		return
 	}
-
-	if len(cfResp.Result) == 0 {
-		return nil, nil
+	if skew > max || skew < -max {
+		slog.Warn("Local clock is off, API authentication may fail", "skew", skew, "max", max)
+		return
 	}
-
-	record := cfResp.Result[0]
-	log.Printf("[INFO] Record found. ID: %s - Current IP: %s", record.ID, record.Content)
-	return &record, nil
+	slog.Debug("Clock checked", "skew", skew)
 }
 
-func createDNSRecord(zoneID, recordName, ip, token string) error {
-	payload := DNSRecordPayload{
-		Type:    "A",
-		Name:    recordName,
-		Content: ip,
-		Proxied: false,
-	}
-
-	endpoint := fmt.Sprintf("/zones/%s/dns_records", zoneID)
-	resp, err := cfRequest("POST", endpoint, token, payload)
-	if err != nil {
-		return fmt.Errorf("failed to create DNS record: %w", err)
+// acquireLock takes LOCK_FILE, exiting with exitLocked when another run
+// holds it. The lock is released when the process exits.
+func acquireLock(cfg *cliConfig) {
+	if cfg.LockFile == "" {
+		return
 	}
-	defer resp.Body.Close()
-
-	log.Println("[INFO] DNS record created successfully.")
-	return nil
-}
-
-func updateDNSRecord(zoneID, recordName, recordID, ip, token string) error {
-	payload := DNSRecordPayload{
-		Type:    "A",
-		Name:    recordName,
-		Content: ip,
-		Proxied: false,
+	f, err := filelock.Lock(cfg.LockFile)
+	if errors.Is(err, filelock.ErrLocked) {
+		slog.Error("Another run holds the lock file, exiting", "path", cfg.LockFile)
+		os.Exit(exitLocked)
 	}
-
-	endpoint := fmt.Sprintf("/zones/%s/dns_records/%s", zoneID, recordID)
-	resp, err := cfRequest("PUT", endpoint, token, payload)
 	if err != nil {
-		return fmt.Errorf("failed to update DNS record: %w", err)
+		fatal(fmt.Errorf("failed to lock %s: %w", cfg.LockFile, err))
 	}
-	defer resp.Body.Close()
-
-	log.Println("[INFO] DNS record updated successfully.")
-	return nil
+	lockFile = f
 }
 
-func main() {
-	cfg, err := getEnvVars()
-	if err != nil {
-		log.Fatalf("[FATAL] %v", err)
-	}
+// lockFile keeps the lock file open, and locked, until the process exits.
+var lockFile *os.File
 
-	publicIP, err := getPublicIP()
-	if err != nil {
-		log.Fatalf("[FATAL] %v", err)
-	}
-
-	zoneID, err := getZoneID(cfg.ZoneName, cfg.APIToken)
-	if err != nil {
-		log.Fatalf("[FATAL] %v", err)
+// openAuditLog opens AUDIT_LOG for appending as cfg's audit log. The file
+// stays open until the process exits.
+func openAuditLog(cfg *cliConfig) {
+	if cfg.AuditLogFile == "" {
+		return
 	}
-
-	recordData, err := getRecordData(zoneID, cfg.RecordName, cfg.APIToken)
+	f, err := os.OpenFile(cfg.AuditLogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
 	if err != nil {
-		log.Fatalf("[FATAL] %v", err)
+		fatal(fmt.Errorf("failed to open audit log: %w", err))
 	}
+	cfg.AuditLog = f
+}
 
-	if recordData == nil {
-		log.Println("[INFO] Record does not exist. Creating...")
-		if err := createDNSRecord(zoneID, cfg.RecordName, publicIP, cfg.APIToken); err != nil {
-			log.Fatalf("[FATAL] %v", err)
-		}
-		return
-	}
-	if recordData.Content == publicIP {
-		log.Printf("[INFO] IP not changed (%s).", publicIP)
-		return
-	}
-	log.Printf("[INFO] IP changed (%s -> %s). Updating...", recordData.Content, publicIP)
-	if err := updateDNSRecord(zoneID, cfg.RecordName, recordData.ID, publicIP, cfg.APIToken); err != nil {
-		log.Fatalf("[FATAL] %v", err)
-	}
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+	"github.com/casantosmu/ddns-updater/internal/atomicfile"
+)
+
+// Metrics written to METRICS_TEXTFILE for node_exporter's textfile
+// collector. Counters and timestamps are carried over from the previous file
+// so they survive one-shot runs:
+//
+//	ddns_updater_last_run_timestamp_seconds     time of the last run
+//	ddns_updater_last_run_success               1 if the last run succeeded
+//	ddns_updater_last_success_timestamp_seconds time of the last successful run
+//	ddns_updater_last_change_timestamp_seconds  time a record was last created or updated
+//	ddns_updater_runs_total                     runs since the file was created
+//	ddns_updater_failures_total                 failed runs since the file was created
+//	ddns_updater_record_changes_total           records created or updated
+//	ddns_updater_update_duration_seconds        histogram of the Cloudflare calls changing a record, retries' backoff excluded
+//	ddns_updater_update_total_duration_seconds  the same histogram with the backoff included
+var textfileMetrics = []struct {
+	name, kind, help string
+}{
+	{"ddns_updater_last_run_timestamp_seconds", "gauge", "Unix time of the last run."},
+	{"ddns_updater_last_run_success", "gauge", "Whether the last run succeeded."},
+	{"ddns_updater_last_success_timestamp_seconds", "gauge", "Unix time of the last successful run."},
+	{"ddns_updater_last_change_timestamp_seconds", "gauge", "Unix time a record was last created or updated."},
+	{"ddns_updater_runs_total", "counter", "Total number of runs."},
+	{"ddns_updater_failures_total", "counter", "Total number of failed runs."},
+	{"ddns_updater_record_changes_total", "counter", "Total number of records created or updated."},
+}
+
+var textfileHistograms = []struct {
+	name, help string
+	value      func(ddns.RecordResult) time.Duration
+}{
+	{"ddns_updater_update_duration_seconds", "Time the Cloudflare calls changing a record spent in flight.", func(r ddns.RecordResult) time.Duration { return r.Latency }},
+	{"ddns_updater_update_total_duration_seconds", "Time the Cloudflare calls changing a record took, retries and backoff included.", func(r ddns.RecordResult) time.Duration { return r.LatencyTotal }},
+}
+
+// latencyBuckets are the upper bounds, in seconds, of the update duration
+// histograms.
+var latencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+func writeMetricsTextfile(path string, result *ddns.Result, runErr error, now time.Time) error {
+	values := readMetricsTextfile(path)
+
+	ts := float64(now.Unix())
+	values["ddns_updater_last_run_timestamp_seconds"] = ts
+	values["ddns_updater_runs_total"]++
+	if runErr == nil {
+		values["ddns_updater_last_run_success"] = 1
+		values["ddns_updater_last_success_timestamp_seconds"] = ts
+	} else {
+		values["ddns_updater_last_run_success"] = 0
+		values["ddns_updater_failures_total"]++
+	}
+	if changes := countChanges(result); changes > 0 {
+		values["ddns_updater_last_change_timestamp_seconds"] = ts
+		values["ddns_updater_record_changes_total"] += float64(changes)
+	}
+
+	var b bytes.Buffer
+	for _, m := range textfileMetrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", m.name, m.kind)
+		fmt.Fprintf(&b, "%s %s\n", m.name, strconv.FormatFloat(values[m.name], 'f', -1, 64))
+	}
+	for _, h := range textfileHistograms {
+		observeHistogram(values, h.name, result, h.value)
+		fmt.Fprintf(&b, "# HELP %s %s\n", h.name, h.help)
+		fmt.Fprintf(&b, "# TYPE %s histogram\n", h.name)
+		for _, le := range histogramBounds() {
+			key := fmt.Sprintf("%s_bucket{le=%q}", h.name, le)
+			fmt.Fprintf(&b, "%s %s\n", key, strconv.FormatFloat(values[key], 'f', -1, 64))
+		}
+		fmt.Fprintf(&b, "%s_sum %s\n", h.name, strconv.FormatFloat(values[h.name+"_sum"], 'f', -1, 64))
+		fmt.Fprintf(&b, "%s_count %s\n", h.name, strconv.FormatFloat(values[h.name+"_count"], 'f', -1, 64))
+	}
+	return atomicfile.Write(path, b.Bytes(), 0o644)
+}
+
+// observeHistogram adds the value of every record that was written to the
+// histogram name in values, whose buckets are cumulative.
+func observeHistogram(values map[string]float64, name string, result *ddns.Result, value func(ddns.RecordResult) time.Duration) {
+	if result == nil {
+		return
+	}
+	for _, r := range result.Records {
+		if r.LatencyTotal == 0 {
+			continue
+		}
+		seconds := value(r).Seconds()
+		for i, le := range histogramBounds() {
+			if i == len(latencyBuckets) || seconds <= latencyBuckets[i] {
+				values[fmt.Sprintf("%s_bucket{le=%q}", name, le)]++
+			}
+		}
+		values[name+"_sum"] += seconds
+		values[name+"_count"]++
+	}
+}
+
+// histogramBounds returns the le labels of latencyBuckets, +Inf last.
+func histogramBounds() []string {
+	bounds := make([]string, 0, len(latencyBuckets)+1)
+	for _, le := range latencyBuckets {
+		bounds = append(bounds, strconv.FormatFloat(le, 'f', -1, 64))
+	}
+	return append(bounds, "+Inf")
+}
+
+// readMetricsTextfile returns the samples of a previously written file. A
+// missing or unreadable file starts every metric from zero.
+func readMetricsTextfile(path string) map[string]float64 {
+	values := make(map[string]float64)
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			values[name] = v
+		}
+	}
+	return values
+}
+
+func countChanges(result *ddns.Result) int {
+	if result == nil {
+		return 0
+	}
+	n := 0
+	for _, r := range result.Records {
+		if r.Action == ddns.ActionCreated || r.Action == ddns.ActionUpdated {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// parseTextfile reads a written metrics file into its samples and the HELP
+// and TYPE lines of each metric.
+func parseTextfile(t *testing.T, path string) (samples map[string]string, help, types map[string]string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples, help, types = make(map[string]string), make(map[string]string), make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if rest, ok := strings.CutPrefix(line, "# HELP "); ok {
+			name, text, _ := strings.Cut(rest, " ")
+			help[name] = text
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			name, kind, _ := strings.Cut(rest, " ")
+			types[name] = kind
+			continue
+		}
+		name, value, ok := strings.Cut(line, " ")
+		if !ok {
+			t.Fatalf("malformed sample %q", line)
+		}
+		samples[name] = value
+	}
+	return samples, help, types
+}
+
+func TestMetricsTextfileCarriesCountersOver(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ddns.prom")
+	first := time.Unix(1700000000, 0)
+	changed := &ddns.Result{Records: []ddns.RecordResult{
+		{Name: "home.example.com", Action: ddns.ActionUpdated, Latency: 200 * time.Millisecond, LatencyTotal: 200 * time.Millisecond},
+		{Name: "vpn.example.com", Action: ddns.ActionUnchanged},
+	}}
+	if err := writeMetricsTextfile(path, changed, nil, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMetricsTextfile(path, &ddns.Result{}, errors.New("boom"), first.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, help, types := parseTextfile(t, path)
+	for _, m := range textfileMetrics {
+		if help[m.name] != m.help || types[m.name] != m.kind {
+			t.Errorf("%s: HELP %q TYPE %q, want %q %q", m.name, help[m.name], types[m.name], m.help, m.kind)
+		}
+	}
+	for _, h := range textfileHistograms {
+		if help[h.name] != h.help || types[h.name] != "histogram" {
+			t.Errorf("%s: HELP %q TYPE %q, want %q histogram", h.name, help[h.name], types[h.name], h.help)
+		}
+	}
+	for name, want := range map[string]string{
+		"ddns_updater_runs_total":                     "2",
+		"ddns_updater_failures_total":                 "1",
+		"ddns_updater_record_changes_total":           "1",
+		"ddns_updater_last_run_success":               "0",
+		"ddns_updater_last_run_timestamp_seconds":     "1700000060",
+		"ddns_updater_last_success_timestamp_seconds": "1700000000",
+		"ddns_updater_last_change_timestamp_seconds":  "1700000000",
+		"ddns_updater_update_duration_seconds_count":  "1",
+	} {
+		if samples[name] != want {
+			t.Errorf("%s = %q, want %q", name, samples[name], want)
+		}
+	}
+}
+
+func TestObserveHistogram(t *testing.T) {
+	const name = "test_seconds"
+	result := &ddns.Result{Records: []ddns.RecordResult{
+		{Name: "a.example.com", Latency: 50 * time.Millisecond, LatencyTotal: 50 * time.Millisecond},
+		// A value on a bound falls in that bucket.
+		{Name: "b.example.com", Latency: 250 * time.Millisecond, LatencyTotal: time.Second},
+		{Name: "c.example.com", Latency: 3 * time.Second, LatencyTotal: 3 * time.Second},
+		{Name: "d.example.com", Latency: time.Minute, LatencyTotal: time.Minute},
+		// Records that were not written are left out.
+		{Name: "e.example.com"},
+	}}
+	values := make(map[string]float64)
+	observeHistogram(values, name, result, func(r ddns.RecordResult) time.Duration { return r.Latency })
+
+	want := map[string]float64{
+		"0.1": 1, "0.25": 2, "0.5": 2, "1": 2, "2.5": 2, "5": 3, "10": 3, "30": 3, "+Inf": 4,
+	}
+	for _, le := range histogramBounds() {
+		if got := values[name+`_bucket{le="`+le+`"}`]; got != want[le] {
+			t.Errorf("bucket le=%s = %v, want %v", le, got, want[le])
+		}
+	}
+	if got := values[name+"_count"]; got != 4 {
+		t.Errorf("count = %v, want 4", got)
+	}
+	if got, want := values[name+"_sum"], 0.05+0.25+3+60; got < want-1e-9 || got > want+1e-9 {
+		t.Errorf("sum = %v, want %v", got, want)
+	}
+
+	// Observations add up across runs, and a nil result adds nothing.
+	observeHistogram(values, name, result, func(r ddns.RecordResult) time.Duration { return r.Latency })
+	observeHistogram(values, name, nil, func(r ddns.RecordResult) time.Duration { return r.Latency })
+	if got := values[name+`_bucket{le="+Inf"}`]; got != 8 || values[name+"_count"] != 8 {
+		t.Errorf("after two runs +Inf = %v, count = %v; want 8 and 8", got, values[name+"_count"])
+	}
+}
+
+func TestHistogramBounds(t *testing.T) {
+	got := strings.Join(histogramBounds(), " ")
+	if want := "0.1 0.25 0.5 1 2.5 5 10 30 +Inf"; got != want {
+		t.Errorf("histogramBounds = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+var notifierEnvVars = []struct {
+	name string
+	new  func(string) (ddns.Notifier, error)
+}{
+	{"DISCORD_WEBHOOK_URL", ddns.NewDiscordNotifier},
+	{"SLACK_WEBHOOK_URL", ddns.NewSlackNotifier},
+	{"WEBHOOK_URL", ddns.NewWebhookNotifier},
+}
+
+// notifiersFromEnv builds a notifier for every configured variable. A
+// misconfigured notifier is logged and disabled rather than failing startup,
+// unless NOTIFY_REQUIRED is set and no notifier is left.
+func notifiersFromEnv() ([]ddns.Notifier, error) {
+	var required bool
+	if err := boolEnv("NOTIFY_REQUIRED", &required); err != nil {
+		return nil, err
+	}
+
+	var notifiers []ddns.Notifier
+	for _, env := range notifierEnvVars {
+		v := getenv(env.name)
+		if v == "" {
+			continue
+		}
+		n, err := env.new(v)
+		if err != nil {
+			slog.Warn("Disabling misconfigured notifier", "env", env.name, "error", err)
+			continue
+		}
+		notifiers = append(notifiers, n)
+	}
+
+	if required && len(notifiers) == 0 {
+		return nil, fmt.Errorf("%s is set but no valid notifier is configured", envName("NOTIFY_REQUIRED"))
+	}
+	return notifiers, nil
+}
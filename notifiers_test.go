@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// captureLogs sends the default logger to the returned buffer for the
+// duration of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+func notifierNames(notifiers []ddns.Notifier) string {
+	names := make([]string, 0, len(notifiers))
+	for _, n := range notifiers {
+		names = append(names, n.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func TestNotifiersFromEnvPartialMisconfiguration(t *testing.T) {
+	var received atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		name     string
+		env      []string
+		want     string
+		warnings []string
+		wantErr  bool
+	}{
+		{
+			name:     "bad discord",
+			env:      []string{"DISCORD_WEBHOOK_URL", "http://discord.com/api/webhooks/1/x", "SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T/B/X", "WEBHOOK_URL", srv.URL},
+			want:     "slack,webhook",
+			warnings: []string{"env=DISCORD_WEBHOOK_URL"},
+		},
+		{
+			name:     "bad slack and webhook",
+			env:      []string{"DISCORD_WEBHOOK_URL", "https://discord.com/api/webhooks/1/x", "SLACK_WEBHOOK_URL", "https://example.com/hook", "WEBHOOK_URL", "ftp://example.com"},
+			want:     "discord",
+			warnings: []string{"env=SLACK_WEBHOOK_URL", "env=WEBHOOK_URL"},
+		},
+		{
+			name:     "all bad, not required",
+			env:      []string{"SLACK_WEBHOOK_URL", "not a url", "WEBHOOK_URL", "://"},
+			want:     "",
+			warnings: []string{"env=SLACK_WEBHOOK_URL", "env=WEBHOOK_URL"},
+		},
+		{
+			name:    "all bad, required",
+			env:     []string{"NOTIFY_REQUIRED", "true", "WEBHOOK_URL", "://"},
+			wantErr: true,
+		},
+		{
+			name: "one good, required",
+			env:  []string{"NOTIFY_REQUIRED", "true", "DISCORD_WEBHOOK_URL", "https://example.com/x", "WEBHOOK_URL", srv.URL},
+			want: "webhook",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			for _, env := range notifierEnvVars {
+				t.Setenv(env.name, "")
+			}
+			t.Setenv("NOTIFY_REQUIRED", "")
+			setenv(t, c.env...)
+			logs := captureLogs(t)
+
+			notifiers, err := notifiersFromEnv()
+			if (err != nil) != c.wantErr {
+				t.Fatalf("notifiersFromEnv error = %v, want error %t", err, c.wantErr)
+			}
+			if got := notifierNames(notifiers); got != c.want {
+				t.Errorf("notifiers = %q, want %q", got, c.want)
+			}
+			for _, w := range c.warnings {
+				if !strings.Contains(logs.String(), "Disabling misconfigured notifier") || !strings.Contains(logs.String(), w) {
+					t.Errorf("no warning for %s in logs:\n%s", w, logs)
+				}
+			}
+			// Webhook URLs embed credentials, so the warning must not
+			// repeat them.
+			for i := 1; i < len(c.env); i += 2 {
+				if strings.HasSuffix(c.env[i-1], "_URL") && strings.Contains(logs.String(), c.env[i]) {
+					t.Errorf("warning leaks %s:\n%s", c.env[i-1], logs)
+				}
+			}
+
+			// The notifiers left still fire.
+			for _, n := range notifiers {
+				if n.Name() != "webhook" {
+					continue
+				}
+				before := received.Load()
+				if err := n.Notify(context.Background(), ddns.Event{Summary: "test"}); err != nil {
+					t.Errorf("webhook notifier: %v", err)
+				}
+				if received.Load() != before+1 {
+					t.Error("webhook notifier did not reach its endpoint")
+				}
+			}
+		})
+	}
+}
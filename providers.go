@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// providerEnvVars maps PROVIDER values other than cloudflare to the
+// variable holding their token.
+var providerEnvVars = map[string]struct {
+	tokenEnv string
+	new      func(string) (ddns.Provider, error)
+}{
+	"desec":  {"DESEC_TOKEN", ddns.NewDeSECProvider},
+	"gandi":  {"GANDI_TOKEN", ddns.NewGandiProvider},
+	"linode": {"LINODE_TOKEN", ddns.NewLinodeProvider},
+	"vultr":  {"VULTR_API_KEY", ddns.NewVultrProvider},
+}
+
+// providerFromEnv builds the DNS provider selected by PROVIDER. It returns
+// nil for Cloudflare, the default.
+func providerFromEnv() (ddns.Provider, error) {
+	name := strings.ToLower(getenv("PROVIDER"))
+	if name == "" || name == "cloudflare" {
+		return nil, nil
+	}
+	env, ok := providerEnvVars[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid PROVIDER value %q: must be one of %s", name, providerNames())
+	}
+	token := getenv(env.tokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("missing environment variables: %s", envName(env.tokenEnv))
+	}
+	return env.new(token)
+}
+
+// fallbackProviderFromEnv builds the provider selected by FALLBACK_PROVIDER,
+// with the same token variable as when it is PROVIDER. It returns nil when
+// the variable is unset. Cloudflare cannot be the fallback.
+func fallbackProviderFromEnv() (ddns.Provider, error) {
+	name := strings.ToLower(getenv("FALLBACK_PROVIDER"))
+	if name == "" {
+		return nil, nil
+	}
+	env, ok := providerEnvVars[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid %s value %q: must be one of %s", envName("FALLBACK_PROVIDER"), name, strings.Join(slices.Sorted(maps.Keys(providerEnvVars)), ", "))
+	}
+	if strings.EqualFold(getenv("PROVIDER"), name) {
+		return nil, fmt.Errorf("%s must differ from %s", envName("FALLBACK_PROVIDER"), envName("PROVIDER"))
+	}
+	token := getenv(env.tokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("missing environment variables: %s", envName(env.tokenEnv))
+	}
+	return env.new(token)
+}
+
+// newProvider builds the provider registered as name, authenticated with
+// token. It returns nil for Cloudflare.
+func newProvider(name, token string) (ddns.Provider, error) {
+	name = strings.ToLower(name)
+	if name == "" || name == "cloudflare" {
+		return nil, nil
+	}
+	env, ok := providerEnvVars[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid provider %q: must be one of %s", name, providerNames())
+	}
+	return env.new(token)
+}
+
+func providerNames() string {
+	return strings.Join(append(slices.Sorted(maps.Keys(providerEnvVars)), "cloudflare"), ", ")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// minLevelHandler drops records below level.
+type minLevelHandler struct {
+	slog.Handler
+	level slog.Level
+}
+
+func (h minLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.Handler.Enabled(ctx, level)
+}
+
+func (h minLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return minLevelHandler{h.Handler.WithAttrs(attrs), h.level}
+}
+
+func (h minLevelHandler) WithGroup(name string) slog.Handler {
+	return minLevelHandler{h.Handler.WithGroup(name), h.level}
+}
+
+// quietLogger is handed to the updater in quiet mode: only warnings and
+// errors get through, and logChanges reports what changed instead.
+func quietLogger() *slog.Logger {
+	return slog.New(minLevelHandler{slog.Default().Handler(), slog.LevelWarn})
+}
+
+// logChanges logs one line per record a cycle created or updated.
+func logChanges(result *ddns.Result) {
+	if result == nil {
+		return
+	}
+	for _, r := range result.Records {
+		switch r.Action {
+		case ddns.ActionCreated:
+			slog.Info("Record created", "record", r.Name, "type", r.Type, "content", r.Content)
+		case ddns.ActionUpdated:
+			slog.Info("Record updated", "record", r.Name, "type", r.Type, "previous", r.Previous, "content", r.Content)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// printReport writes report to stdout as indented JSON, or with output
+// "env" as shell assignments a wrapper script can eval.
+func printReport(report cycleReport, output string) {
+	if output == "env" {
+		printEnv(report)
+		return
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fatal(err)
+	}
+}
+
+// printEnv prints the overall DDNS_ACTION (failed, updated or unchanged),
+// the addresses involved and, for each record n starting at 1, its
+// DDNS_RECORD_<n>_* fields. DDNS_OLD_IP is the first record's previous
+// content.
+func printEnv(report cycleReport) {
+	newIP := report.address()
+	oldIP := ""
+	if len(report.Records) > 0 {
+		oldIP = report.Records[0].Previous
+	}
+
+	printVar("DDNS_ACTION", report.action())
+	printVar("DDNS_NEW_IP", newIP)
+	printVar("DDNS_OLD_IP", oldIP)
+	printVar("DDNS_IPV4", report.IPv4)
+	printVar("DDNS_IPV6", report.IPv6)
+	printVar("DDNS_DRY_RUN", fmt.Sprint(report.DryRun))
+	printVar("DDNS_ERROR", report.Error)
+	printVar("DDNS_RECORD_COUNT", fmt.Sprint(len(report.Records)))
+	for i, r := range report.Records {
+		prefix := fmt.Sprintf("DDNS_RECORD_%d_", i+1)
+		printVar(prefix+"NAME", r.Name)
+		printVar(prefix+"TYPE", r.Type)
+		printVar(prefix+"ACTION", r.Action)
+		printVar(prefix+"OLD", r.Previous)
+		printVar(prefix+"NEW", r.Content)
+		printVar(prefix+"ERROR", r.Error)
+	}
+}
+
+// action summarizes the run as failed, updated or unchanged.
+func (r cycleReport) action() string {
+	action := "unchanged"
+	for _, rr := range r.Records {
+		switch rr.Action {
+		case "failed":
+			return "failed"
+		case "created", "updated":
+			action = "updated"
+		}
+	}
+	if r.Error != "" {
+		return "failed"
+	}
+	return action
+}
+
+// address returns the configured content, or else the detected IPv4 or
+// IPv6 address.
+func (r cycleReport) address() string {
+	switch {
+	case r.Content != "":
+		return r.Content
+	case r.IPv4 != "":
+		return r.IPv4
+	default:
+		return r.IPv6
+	}
+}
+
+// printVar prints name=value with value single-quoted, so any character,
+// including newlines, survives eval.
+func printVar(name, value string) {
+	fmt.Printf("%s='%s'\n", name, strings.ReplaceAll(value, "'", `'\''`))
+}
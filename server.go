@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// cycleReport is the JSON form of a cycle's outcome.
+type cycleReport struct {
+	Time    time.Time      `json:"time"`
+	IPv4    string         `json:"ipv4,omitempty"`
+	IPv6    string         `json:"ipv6,omitempty"`
+	Content string         `json:"content,omitempty"`
+	DryRun  bool           `json:"dry_run,omitempty"`
+	Records []recordReport `json:"records"`
+	Error   string         `json:"error,omitempty"`
+}
+
+type recordReport struct {
+	Name       string      `json:"name"`
+	Type       string      `json:"type"`
+	Action     string      `json:"action"`
+	Previous   string      `json:"previous,omitempty"`
+	Content    string      `json:"content,omitempty"`
+	Changes    []string    `json:"changes,omitempty"`
+	Reason     string      `json:"reason,omitempty"`
+	Propagated *bool       `json:"propagated,omitempty"`
+	Diff       []fieldDiff `json:"diff,omitempty"`
+	Fallback   string      `json:"fallback,omitempty"`
+	// Latency and LatencyTotal are ddns.RecordResult's, in seconds.
+	Latency      float64 `json:"latency_seconds,omitempty"`
+	LatencyTotal float64 `json:"latency_total_seconds,omitempty"`
+	Error        string  `json:"error,omitempty"`
+}
+
+type fieldDiff struct {
+	Field   string `json:"field"`
+	Current string `json:"current"`
+	Desired string `json:"desired"`
+	Changed bool   `json:"changed"`
+}
+
+func newCycleReport(result *ddns.Result, err error, now time.Time) cycleReport {
+	report := cycleReport{Time: now, Records: []recordReport{}}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	if result == nil {
+		return report
+	}
+	report.IPv4 = result.IPv4
+	report.IPv6 = result.IPv6
+	report.Content = result.Content
+	report.DryRun = result.DryRun
+	for _, r := range result.Records {
+		rr := recordReport{
+			Name:         r.Name,
+			Type:         r.Type,
+			Action:       string(r.Action),
+			Previous:     r.Previous,
+			Content:      r.Content,
+			Changes:      r.Changes,
+			Reason:       r.Reason,
+			Propagated:   r.Propagated,
+			Fallback:     r.Fallback,
+			Latency:      r.Latency.Seconds(),
+			LatencyTotal: r.LatencyTotal.Seconds(),
+		}
+		for _, d := range r.Diff {
+			rr.Diff = append(rr.Diff, fieldDiff{Field: d.Field, Current: d.Current, Desired: d.Desired, Changed: d.Changed()})
+		}
+		if r.Err != nil {
+			rr.Error = r.Err.Error()
+		}
+		report.Records = append(report.Records, rr)
+	}
+	return report
+}
+
+// statusHistory is how many history entries GET /status returns.
+const statusHistory = 50
+
+// serve runs the health server until ctx is done. GET /healthz reports the
+// last cycle and GET /status adds the recent change history, which GET /
+// shows as a page; both require the status token when one is configured.
+// POST /update runs a cycle on demand and is only registered when an update
+// token is configured.
+func (d *daemon) serve(ctx context.Context) {
+	srv := &http.Server{Addr: d.cfg.HealthAddr, Handler: d.routes(ctx), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("Starting health server", "addr", d.cfg.HealthAddr, "update_endpoint", d.cfg.UpdateToken != "")
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Health server failed", "error", err)
+	}
+}
+
+// routes returns the health server's handler; ctx is passed on to the
+// cycles POST /update runs.
+func (d *daemon) routes(ctx context.Context) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", d.handleHealth)
+	mux.HandleFunc("GET /status", d.requireStatusToken(d.handleStatus))
+	mux.HandleFunc("GET /{$}", d.requireStatusToken(d.handleIndex))
+	if d.cfg.UpdateToken != "" {
+		mux.HandleFunc("POST /update", func(w http.ResponseWriter, r *http.Request) {
+			d.handleUpdate(ctx, w, r)
+		})
+	}
+	return mux
+}
+
+func (d *daemon) handleHealth(w http.ResponseWriter, r *http.Request) {
+	last, ok := d.lastReport()
+	status := http.StatusOK
+	switch {
+	case !ok:
+		status = http.StatusServiceUnavailable
+		last = cycleReport{Records: []recordReport{}, Error: "no cycle has completed yet"}
+	case last.Error != "":
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, last)
+}
+
+// statusReport is the body of GET /status.
+type statusReport struct {
+	Last    *cycleReport   `json:"last"`
+	History []historyEntry `json:"history,omitempty"`
+}
+
+func (d *daemon) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, d.status())
+}
+
+func (d *daemon) status() statusReport {
+	var status statusReport
+	if last, ok := d.lastReport(); ok {
+		status.Last = &last
+	}
+	if d.cfg.HistoryFile != "" {
+		history, err := readHistory(d.cfg.HistoryFile, statusHistory)
+		if err != nil {
+			slog.Warn("Failed to read history file", "path", d.cfg.HistoryFile, "error", err)
+		}
+		status.History = history
+	}
+	return status
+}
+
+// requireStatusToken wraps next so it answers only requests that carry the
+// status token, as a bearer token or as the password of basic
+// authentication so a browser can prompt for it. Without a status token,
+// next is returned as is.
+func (d *daemon) requireStatusToken(next http.HandlerFunc) http.HandlerFunc {
+	if d.cfg.StatusToken == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			_, token, ok = r.BasicAuth()
+		}
+		if !ok || !tokenMatches(token, d.cfg.StatusToken) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ddns-updater"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func tokenMatches(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// handleUpdate runs a cycle out of band from the ticker. ctx is the daemon's
+// context, so a client disconnecting does not abort a half-done update.
+// The token comes as a bearer token, or as the token field of the status
+// page's form, which is then redirected back to the page.
+func (d *daemon) handleUpdate(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	fromPage := !ok && strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+	if fromPage {
+		token, ok = r.PostFormValue("token"), true
+	}
+	if !ok || !tokenMatches(token, d.cfg.UpdateToken) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if !d.acceptTrigger(ctx) {
+		slog.Info("Coalescing HTTP trigger", "remote", r.RemoteAddr)
+		if fromPage {
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "coalesced"})
+		return
+	}
+
+	slog.Info("Update triggered over HTTP", "remote", r.RemoteAddr)
+	result, err := d.cycle(ctx)
+	if fromPage {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, newCycleReport(result, err, time.Now()))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestServer serves the routes of a daemon with the given tokens whose
+// cycles fail, since its IP provider always answers 500.
+func newTestServer(t *testing.T, statusToken, updateToken string) *httptest.Server {
+	t.Helper()
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+	}))
+	t.Cleanup(provider.Close)
+	d := newTestDaemon(t, provider.URL, 0)
+	d.cfg.StatusToken, d.cfg.UpdateToken = statusToken, updateToken
+	srv := httptest.NewServer(d.routes(t.Context()))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// do sends req and returns the response with its body read.
+func do(t *testing.T, req *http.Request) (*http.Response, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func newRequest(t *testing.T, method, url string, body io.Reader) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestIndexPage(t *testing.T) {
+	srv := newTestServer(t, "", "")
+	resp, body := do(t, newRequest(t, "GET", srv.URL+"/", nil))
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET / status %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type %q, want text/html", ct)
+	}
+	if !strings.Contains(body, "No run has completed yet.") {
+		t.Errorf("page does not say no run completed:\n%s", body)
+	}
+	if strings.Contains(body, `action="/update"`) {
+		t.Error("page offers the update form without an update token")
+	}
+
+	// Only the root path is the page.
+	if resp, _ := do(t, newRequest(t, "GET", srv.URL+"/other", nil)); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET /other status %d, want 404", resp.StatusCode)
+	}
+	// Without an update token there is no update endpoint.
+	if resp, _ := do(t, newRequest(t, "POST", srv.URL+"/update", nil)); resp.StatusCode != http.StatusNotFound && resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /update status %d, want it unregistered", resp.StatusCode)
+	}
+}
+
+func TestIndexPageStatusToken(t *testing.T) {
+	srv := newTestServer(t, "status-secret", "update-secret")
+	for _, c := range []struct {
+		name string
+		auth func(*http.Request)
+		want int
+	}{
+		{"no credentials", func(*http.Request) {}, http.StatusUnauthorized},
+		{"wrong basic password", func(r *http.Request) { r.SetBasicAuth("admin", "wrong") }, http.StatusUnauthorized},
+		{"update token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer update-secret") }, http.StatusUnauthorized},
+		{"basic password", func(r *http.Request) { r.SetBasicAuth("admin", "status-secret") }, http.StatusOK},
+		{"bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer status-secret") }, http.StatusOK},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			req := newRequest(t, "GET", srv.URL+"/", nil)
+			c.auth(req)
+			resp, body := do(t, req)
+			if resp.StatusCode != c.want {
+				t.Fatalf("status %d, want %d", resp.StatusCode, c.want)
+			}
+			if c.want == http.StatusUnauthorized {
+				if got := resp.Header.Get("WWW-Authenticate"); got != `Basic realm="ddns-updater"` {
+					t.Errorf("WWW-Authenticate %q, want the basic realm", got)
+				}
+				return
+			}
+			if !strings.Contains(body, `action="/update"`) {
+				t.Error("page does not offer the update form")
+			}
+			if strings.Contains(body, "status-secret") || strings.Contains(body, "update-secret") {
+				t.Error("page shows a token")
+			}
+		})
+	}
+}
+
+func TestUpdateEndpointToken(t *testing.T) {
+	srv := newTestServer(t, "", "update-secret")
+	form := func(token string) *http.Request {
+		req := newRequest(t, "POST", srv.URL+"/update", strings.NewReader(url.Values{"token": {token}}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req
+	}
+	bearer := func(token string) *http.Request {
+		req := newRequest(t, "POST", srv.URL+"/update", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+
+	for _, c := range []struct {
+		name string
+		req  *http.Request
+	}{
+		{"no token", newRequest(t, "POST", srv.URL+"/update", nil)},
+		{"wrong bearer token", bearer("wrong")},
+		{"token prefix", bearer("update")},
+		{"wrong form token", form("wrong")},
+		{"empty form token", form("")},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			resp, _ := do(t, c.req)
+			if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("status %d, WWW-Authenticate %q; want 401 asking for a bearer token", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// A valid bearer token runs a cycle and reports it; this one fails.
+	resp, body := do(t, bearer("update-secret"))
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("status %d, want 500 for the failed cycle", resp.StatusCode)
+	}
+	var report cycleReport
+	if err := json.Unmarshal([]byte(body), &report); err != nil || report.Error == "" {
+		t.Errorf("body %s (%v), want a cycle report with the error", body, err)
+	}
+
+	// The page's form is sent back to the page.
+	resp, _ = do(t, form("update-secret"))
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != "/" {
+		t.Errorf("form status %d, Location %q; want a redirect to /", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if _, body := do(t, newRequest(t, "GET", srv.URL+"/", nil)); !strings.Contains(body, `<p class="error">`) {
+		t.Errorf("page does not show the failed cycle:\n%s", body)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// statsdTimeout bounds resolving and writing to STATSD_ADDR, so an
+// unreachable collector cannot hold up a cycle.
+const statsdTimeout = time.Second
+
+// sendStatsd reports a cycle to the statsd server at addr in one datagram.
+// Delivery is not confirmed; an error only means the datagram could not be
+// sent:
+//
+//	ddns_updater.runs                   counter, 1 per run
+//	ddns_updater.failures               counter, 1 per failed run
+//	ddns_updater.record_changes         counter, records created or updated
+//	ddns_updater.run_duration           timer, in milliseconds
+//	ddns_updater.last_run_success       gauge, 1 if the run succeeded
+//	ddns_updater.update_duration        timer, per record written, retries' backoff excluded
+//	ddns_updater.update_total_duration  timer, the same with the backoff included
+func sendStatsd(addr string, result *ddns.Result, runErr error, duration time.Duration) error {
+	success, failures := 1, 0
+	if runErr != nil {
+		success, failures = 0, 1
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "ddns_updater.runs:1|c\n")
+	fmt.Fprintf(&b, "ddns_updater.failures:%d|c\n", failures)
+	fmt.Fprintf(&b, "ddns_updater.record_changes:%d|c\n", countChanges(result))
+	fmt.Fprintf(&b, "ddns_updater.run_duration:%d|ms\n", duration.Milliseconds())
+	fmt.Fprintf(&b, "ddns_updater.last_run_success:%d|g", success)
+	if result != nil {
+		for _, r := range result.Records {
+			if r.LatencyTotal > 0 {
+				fmt.Fprintf(&b, "\nddns_updater.update_duration:%d|ms", r.Latency.Milliseconds())
+				fmt.Fprintf(&b, "\nddns_updater.update_total_duration:%d|ms", r.LatencyTotal.Milliseconds())
+			}
+		}
+	}
+
+	conn, err := net.DialTimeout("udp", addr, statsdTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(statsdTimeout))
+	_, err = conn.Write([]byte(b.String()))
+	return err
+}
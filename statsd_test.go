@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+// listenStatsd returns a UDP listener standing in for a statsd server.
+func listenStatsd(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readDatagram returns the lines of the next datagram conn receives.
+func readDatagram(t *testing.T, conn *net.UDPConn) []string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64<<10)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(string(buf[:n]), "\n")
+}
+
+func TestSendStatsd(t *testing.T) {
+	conn := listenStatsd(t)
+	result := &ddns.Result{Records: []ddns.RecordResult{
+		{Name: "home.example.com", Action: ddns.ActionUpdated, Latency: 120 * time.Millisecond, LatencyTotal: 1120 * time.Millisecond},
+		{Name: "vpn.example.com", Action: ddns.ActionUnchanged},
+		{Name: "www.example.com", Action: ddns.ActionCreated, Latency: 80 * time.Millisecond, LatencyTotal: 80 * time.Millisecond},
+	}}
+	if err := sendStatsd(conn.LocalAddr().String(), result, nil, 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"ddns_updater.runs:1|c",
+		"ddns_updater.failures:0|c",
+		"ddns_updater.record_changes:2|c",
+		"ddns_updater.run_duration:1500|ms",
+		"ddns_updater.last_run_success:1|g",
+		"ddns_updater.update_duration:120|ms",
+		"ddns_updater.update_total_duration:1120|ms",
+		"ddns_updater.update_duration:80|ms",
+		"ddns_updater.update_total_duration:80|ms",
+	}
+	if got := readDatagram(t, conn); !slices.Equal(got, want) {
+		t.Errorf("datagram lines:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSendStatsdFailedRun(t *testing.T) {
+	conn := listenStatsd(t)
+	if err := sendStatsd(conn.LocalAddr().String(), nil, errors.New("boom"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"ddns_updater.runs:1|c",
+		"ddns_updater.failures:1|c",
+		"ddns_updater.record_changes:0|c",
+		"ddns_updater.run_duration:20|ms",
+		"ddns_updater.last_run_success:0|g",
+	}
+	if got := readDatagram(t, conn); !slices.Equal(got, want) {
+		t.Errorf("datagram lines:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
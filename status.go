@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/internal/atomicfile"
+)
+
+// runStatus is the content of STATUS_FILE: the outcome of the last run, for
+// monitors that alert when Time goes stale or Error is set.
+type runStatus struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	IP     string    `json:"ip,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// writeStatusFile replaces the file at path with the status of report. It
+// is written after failed runs too.
+func writeStatusFile(path string, report cycleReport) error {
+	data, err := json.MarshalIndent(runStatus{
+		Time:   report.Time.UTC(),
+		Action: report.action(),
+		IP:     report.address(),
+		Error:  report.Error,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return atomicfile.Write(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/casantosmu/ddns-updater/ddns"
+)
+
+func TestWriteStatusFile(t *testing.T) {
+	now := time.Date(2026, 3, 10, 4, 5, 6, 0, time.FixedZone("CET", 3600))
+	for _, c := range []struct {
+		name   string
+		result *ddns.Result
+		err    error
+		want   map[string]any
+	}{
+		{
+			name:   "updated",
+			result: &ddns.Result{IPv4: "198.51.100.1", Records: []ddns.RecordResult{{Name: "home.example.com", Action: ddns.ActionUpdated}, {Name: "vpn.example.com", Action: ddns.ActionUnchanged}}},
+			want:   map[string]any{"time": "2026-03-10T03:05:06Z", "action": "updated", "ip": "198.51.100.1"},
+		},
+		{
+			name:   "unchanged",
+			result: &ddns.Result{IPv6: "2001:db8::1", Records: []ddns.RecordResult{{Name: "home.example.com", Action: ddns.ActionUnchanged}}},
+			want:   map[string]any{"time": "2026-03-10T03:05:06Z", "action": "unchanged", "ip": "2001:db8::1"},
+		},
+		{
+			name:   "record failed",
+			result: &ddns.Result{IPv4: "198.51.100.1", Records: []ddns.RecordResult{{Name: "home.example.com", Action: ddns.ActionFailed, Err: errors.New("boom")}}},
+			err:    errors.New("1 of 1 records failed"),
+			want:   map[string]any{"time": "2026-03-10T03:05:06Z", "action": "failed", "ip": "198.51.100.1", "error": "1 of 1 records failed"},
+		},
+		{
+			name: "detection failed",
+			err:  errors.New("failed to fetch public IPv4 address: all providers failed"),
+			want: map[string]any{"time": "2026-03-10T03:05:06Z", "action": "failed", "error": "failed to fetch public IPv4 address: all providers failed"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "status.json")
+			if err := writeStatusFile(path, newCycleReport(c.result, c.err, now)); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("invalid status file %q: %v", data, err)
+			}
+			if len(got) != len(c.want) {
+				t.Errorf("status = %s, want %v", data, c.want)
+			}
+			for key, value := range c.want {
+				if got[key] != value {
+					t.Errorf("%s = %v, want %v", key, got[key], value)
+				}
+			}
+		})
+	}
+}
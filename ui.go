@@ -0,0 +1,78 @@
+package main
+
+import (
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// indexPage renders the status page. It is kept to one inline template
+// with no scripts or external assets.
+var indexPage = template.Must(template.New("index").Funcs(template.FuncMap{
+	"ago": func(t time.Time) string { return time.Since(t).Round(time.Second).String() + " ago" },
+}).Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>ddns-updater</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .4rem .6rem; border-bottom: 1px solid #ddd; }
+.failed, .error { color: #b00020; }
+.updated, .created { color: #1b5e20; }
+</style>
+</head>
+<body>
+<h1>ddns-updater</h1>
+{{with .Last}}
+<p>
+{{if .IPv4}}IPv4 <strong>{{.IPv4}}</strong><br>{{end}}
+{{if .IPv6}}IPv6 <strong>{{.IPv6}}</strong><br>{{end}}
+{{if and .Content (not .IPv4) (not .IPv6)}}Content <strong>{{.Content}}</strong><br>{{end}}
+Last run {{.Time.Format "2006-01-02 15:04:05 MST"}} ({{ago .Time}}){{if .DryRun}}, dry run{{end}}
+</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<table>
+<tr><th>Record</th><th>Type</th><th>Status</th><th>Content</th><th>Detail</th></tr>
+{{range .Records}}
+<tr><td>{{.Name}}</td><td>{{.Type}}</td><td class="{{.Action}}">{{.Action}}</td><td>{{.Content}}</td><td>{{if .Error}}{{.Error}}{{else}}{{.Reason}}{{end}}</td></tr>
+{{end}}
+</table>
+{{else}}
+<p>No run has completed yet.</p>
+{{end}}
+{{with .LastChange}}
+<p>Last change {{.Time.Format "2006-01-02 15:04:05 MST"}}: {{.Record}} {{if .Old}}{{.Old}} &rarr; {{end}}{{.New}}</p>
+{{end}}
+{{if .CanUpdate}}
+<form method="post" action="/update">
+<input type="password" name="token" placeholder="Update token" required autocomplete="current-password">
+<button type="submit">Update now</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// indexData is what indexPage renders: the GET /status data plus what the
+// page needs on top of it.
+type indexData struct {
+	statusReport
+	LastChange *historyEntry
+	CanUpdate  bool
+}
+
+func (d *daemon) handleIndex(w http.ResponseWriter, r *http.Request) {
+	data := indexData{statusReport: d.status(), CanUpdate: d.cfg.UpdateToken != ""}
+	if len(data.History) > 0 {
+		data.LastChange = &data.History[len(data.History)-1]
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := indexPage.Execute(w, data); err != nil {
+		slog.Warn("Failed to render status page", "error", err)
+	}
+}